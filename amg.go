@@ -0,0 +1,369 @@
+package sparse
+
+import (
+	"context"
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+	_ Smoother       = (*AMG)(nil)
+	_ Preconditioner = (*AMG)(nil)
+)
+
+// AMGSettings holds the settings for constructing a smoothed aggregation algebraic
+// multigrid hierarchy.  The zero value (or a nil *AMGSettings) uses the default for
+// each setting.
+type AMGSettings struct {
+	// StrengthThreshold is the threshold (theta) for strength of connection.  Node j
+	// is strongly connected to node i if |a_ij| >= theta * sqrt(|a_ii * a_jj|).  If
+	// StrengthThreshold is zero, all (non-zero) connections are considered strong.
+	StrengthThreshold float64
+
+	// MaxLevels is the maximum number of levels in the hierarchy (including the
+	// finest level).  If MaxLevels is zero, a default of 10 is used.
+	MaxLevels int
+
+	// MaxCoarseSize is the size at or below which coarsening stops and the coarsest
+	// level is solved directly (by sparse LU factorisation).  If MaxCoarseSize is
+	// zero, a default of 100 is used.
+	MaxCoarseSize int
+
+	// ProlongationWeight is the weight (omega) of the damped Jacobi iteration used
+	// to smooth the tentative prolongators.  The weight is scaled by the inverse of
+	// an estimate of the spectral radius of D^-1 * A.  If ProlongationWeight is zero,
+	// a default of 4/3 is used.
+	ProlongationWeight float64
+
+	// Smoother creates the smoother for the operator at each level (except the
+	// coarsest).  If Smoother is nil, symmetric Gauss-Seidel (SSOR with a relaxation
+	// weight of 1) is used.  To use the hierarchy as a preconditioner for CG, the
+	// smoother must be symmetric (e.g. Jacobi or SSOR).
+	Smoother func(a *CSR) (Smoother, error)
+
+	// PreSmoothing and PostSmoothing are the number of smoothing iterations
+	// performed before and after the coarse grid correction at each level of the
+	// V-cycle.  If zero, a default of 1 is used.
+	PreSmoothing, PostSmoothing int
+}
+
+// defaults returns a copy of the settings (or the default settings if s is nil)
+// with default values filled in.
+func (s *AMGSettings) defaults() AMGSettings {
+	var settings AMGSettings
+	if s != nil {
+		settings = *s
+	}
+	if settings.MaxLevels == 0 {
+		settings.MaxLevels = 10
+	}
+	if settings.MaxCoarseSize == 0 {
+		settings.MaxCoarseSize = 100
+	}
+	if settings.ProlongationWeight == 0 {
+		settings.ProlongationWeight = 4.0 / 3.0
+	}
+	if settings.Smoother == nil {
+		settings.Smoother = func(a *CSR) (Smoother, error) {
+			return NewSSOR(a, 1)
+		}
+	}
+	if settings.PreSmoothing == 0 {
+		settings.PreSmoothing = 1
+	}
+	if settings.PostSmoothing == 0 {
+		settings.PostSmoothing = 1
+	}
+	return settings
+}
+
+// AMGStats holds statistics describing an AMG hierarchy.
+type AMGStats struct {
+	// Levels is the number of levels in the hierarchy.
+	Levels int
+
+	// Rows and NonZeros hold the number of rows and non-zero elements of the
+	// operator at each level (starting with the finest).
+	Rows, NonZeros []int
+
+	// OperatorComplexity is the total number of non-zero elements of the operators
+	// at all levels relative to the number in the finest level operator.
+	OperatorComplexity float64
+
+	// GridComplexity is the total number of rows of the operators at all levels
+	// relative to the number in the finest level operator.
+	GridComplexity float64
+}
+
+// amgLevel is a single level of an AMG hierarchy along with workspace for the
+// V-cycle.
+type amgLevel struct {
+	a         *CSR
+	p, r      *CSR
+	smoother  Smoother
+	x, b, res []float64
+}
+
+// AMG is a smoothed aggregation algebraic multigrid hierarchy for symmetric positive
+// definite (typically Poisson-type) matrices.  Each level of the hierarchy is built
+// from the one above by grouping strongly connected nodes into aggregates, forming
+// a tentative prolongator (interpolating the constant vector over each aggregate),
+// smoothing the prolongator with a damped Jacobi iteration and forming the Galerkin
+// coarse operator R * A * P where R = P^T.  AMG may be used as a standalone solver,
+// as a Smoother or (more commonly) as a Preconditioner for CG in which case a
+// single V-cycle is applied.
+type AMG struct {
+	settings AMGSettings
+	levels   []amgLevel
+	coarse   LU
+}
+
+// NewAMG builds the AMG hierarchy for the square matrix a.  settings may be nil in
+// which case the default settings are used.  A *SingularError is returned if an
+// operator in the hierarchy has a zero diagonal element or the coarsest operator is
+// singular.
+func NewAMG(a *CSR, settings *AMGSettings) (*AMG, error) {
+	r, c := a.Dims()
+	if r != c {
+		panic(mat.ErrShape)
+	}
+	amg := &AMG{settings: settings.defaults()}
+	s := &amg.settings
+
+	for {
+		n, _ := a.Dims()
+		level := amgLevel{
+			a:   a,
+			x:   make([]float64, n),
+			b:   make([]float64, n),
+			res: make([]float64, n),
+		}
+		if n <= s.MaxCoarseSize || len(amg.levels) == s.MaxLevels-1 {
+			amg.levels = append(amg.levels, level)
+			break
+		}
+
+		diag, err := diagonal(a)
+		if err != nil {
+			return nil, err
+		}
+		agg, nc := aggregate(strongConnections(a, diag, s.StrengthThreshold))
+		if nc == 0 || nc == n {
+			// coarsening has stagnated
+			amg.levels = append(amg.levels, level)
+			break
+		}
+		level.p = smoothProlongator(a, diag, tentativeProlongator(agg, nc), s.ProlongationWeight)
+		level.r = level.p.ToCSC().T().(*CSR)
+		if level.smoother, err = s.Smoother(a); err != nil {
+			return nil, err
+		}
+		amg.levels = append(amg.levels, level)
+
+		// Galerkin coarse operator R * A * P
+		var ap CSR
+		ap.Mul(a, level.p)
+		coarse := &CSR{}
+		coarse.Mul(level.r, &ap)
+		a = coarse
+	}
+
+	if err := amg.coarse.Factorize(amg.levels[len(amg.levels)-1].a); err != nil {
+		return nil, err
+	}
+	return amg, nil
+}
+
+// strongConnections returns the (strongly connected) neighbours of each node
+// (excluding itself) as a CSR sparsity pattern.
+func strongConnections(a *CSR, diag []float64, theta float64) (indptr, ind []int) {
+	m := &a.matrix
+	indptr = make([]int, m.I+1)
+	ind = make([]int, 0, len(m.Ind))
+	for i := 0; i < m.I; i++ {
+		for p := m.Indptr[i]; p < m.Indptr[i+1]; p++ {
+			j := m.Ind[p]
+			if j != i && m.Data[p] != 0 && math.Abs(m.Data[p]) >= theta*math.Sqrt(math.Abs(diag[i]*diag[j])) {
+				ind = append(ind, j)
+			}
+		}
+		indptr[i+1] = len(ind)
+	}
+	return indptr, ind
+}
+
+// aggregate groups the nodes of the graph (described by its adjacency structure)
+// into aggregates returning the aggregate of each node and the number of aggregates.
+// The standard 3 phase aggregation algorithm (Vanek, Mandel and Brezina 1996) is
+// used.
+func aggregate(indptr, ind []int) (agg []int, count int) {
+	n := len(indptr) - 1
+	agg = make([]int, n)
+	for i := range agg {
+		agg[i] = -1
+	}
+
+	// 1. form aggregates from nodes whose neighbours are all unaggregated
+	for i := 0; i < n; i++ {
+		if agg[i] >= 0 {
+			continue
+		}
+		free := true
+		for _, j := range ind[indptr[i]:indptr[i+1]] {
+			if agg[j] >= 0 {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		agg[i] = count
+		for _, j := range ind[indptr[i]:indptr[i+1]] {
+			agg[j] = count
+		}
+		count++
+	}
+
+	// 2. add remaining nodes to a neighbouring aggregate formed in phase 1
+	phase1 := make([]int, n)
+	copy(phase1, agg)
+	for i := 0; i < n; i++ {
+		if agg[i] >= 0 {
+			continue
+		}
+		for _, j := range ind[indptr[i]:indptr[i+1]] {
+			if phase1[j] >= 0 {
+				agg[i] = phase1[j]
+				break
+			}
+		}
+	}
+
+	// 3. form new aggregates from any remaining nodes and their unaggregated neighbours
+	for i := 0; i < n; i++ {
+		if agg[i] >= 0 {
+			continue
+		}
+		agg[i] = count
+		for _, j := range ind[indptr[i]:indptr[i+1]] {
+			if agg[j] < 0 {
+				agg[j] = count
+			}
+		}
+		count++
+	}
+	return agg, count
+}
+
+// tentativeProlongator returns the tentative prolongator interpolating the constant
+// vector (normalised) over each of the count aggregates.
+func tentativeProlongator(agg []int, count int) *CSR {
+	n := len(agg)
+	size := make([]int, count)
+	for _, k := range agg {
+		size[k]++
+	}
+	indptr := make([]int, n+1)
+	ind := make([]int, n)
+	data := make([]float64, n)
+	for i, k := range agg {
+		indptr[i+1] = i + 1
+		ind[i] = k
+		data[i] = 1 / math.Sqrt(float64(size[k]))
+	}
+	return NewCSR(n, count, indptr, ind, data)
+}
+
+// smoothProlongator returns the prolongator P = (I - omega/rho * D^-1 * A) * T where
+// T is the tentative prolongator and rho is an (upper bound) estimate of the spectral
+// radius of D^-1 * A.
+func smoothProlongator(a *CSR, diag []float64, t *CSR, omega float64) *CSR {
+	m := &a.matrix
+	data := make([]float64, len(m.Data))
+	var rho float64
+	for i := 0; i < m.I; i++ {
+		var sum float64
+		for p := m.Indptr[i]; p < m.Indptr[i+1]; p++ {
+			data[p] = m.Data[p] / diag[i]
+			sum += math.Abs(data[p])
+		}
+		rho = math.Max(rho, sum)
+	}
+	floats.Scale(omega/rho, data)
+	dinvA := NewCSR(m.I, m.J, m.Indptr, m.Ind, data)
+
+	var s CSR
+	s.Mul(dinvA, t)
+	p := &CSR{}
+	p.Sub(t, &s)
+	return p
+}
+
+// Stats returns statistics describing the hierarchy.
+func (amg *AMG) Stats() AMGStats {
+	stats := AMGStats{Levels: len(amg.levels)}
+	for _, level := range amg.levels {
+		n, _ := level.a.Dims()
+		stats.Rows = append(stats.Rows, n)
+		stats.NonZeros = append(stats.NonZeros, level.a.NNZ())
+		stats.GridComplexity += float64(n)
+		stats.OperatorComplexity += float64(level.a.NNZ())
+	}
+	stats.GridComplexity /= float64(stats.Rows[0])
+	stats.OperatorComplexity /= float64(stats.NonZeros[0])
+	return stats
+}
+
+// vcycle performs a V-cycle starting at level l improving the approximate solution
+// x of A_l * x = b in place.
+func (amg *AMG) vcycle(l int, x, b []float64) {
+	level := &amg.levels[l]
+	if l == len(amg.levels)-1 {
+		xv := mat.NewVecDense(len(x), x)
+		amg.coarse.SolveVecTo(xv, false, mat.NewVecDense(len(b), b))
+		return
+	}
+
+	level.smoother.Smooth(x, b, amg.settings.PreSmoothing)
+
+	// restrict the residual to the next level and recursively solve for the error
+	copy(level.res, b)
+	floats.Scale(-1, level.res)
+	level.a.MulVecTo(level.res, false, x)
+	floats.Scale(-1, level.res)
+	next := &amg.levels[l+1]
+	zero(next.b)
+	level.r.MulVecTo(next.b, false, level.res)
+	zero(next.x)
+	amg.vcycle(l+1, next.x, next.b)
+
+	// correct x with the prolongated error
+	level.p.MulVecTo(x, false, next.x)
+
+	level.smoother.Smooth(x, b, amg.settings.PostSmoothing)
+}
+
+// Apply applies a single V-cycle (from a zero initial guess) to approximately solve
+// A * dst = src.
+func (amg *AMG) Apply(dst, src []float64) {
+	zero(dst)
+	amg.vcycle(0, dst, src)
+}
+
+// Smooth performs the specified number of V-cycles updating x in place.
+func (amg *AMG) Smooth(x, b []float64, iterations int) {
+	for k := 0; k < iterations; k++ {
+		amg.vcycle(0, x, b)
+	}
+}
+
+// Solve solves the system of linear equations A * x = b for x using repeated
+// V-cycles.  The Preconditioner in settings (which may be nil) is ignored.  See CG
+// for details of the result and errors.  Additionally, a *BreakdownError is returned
+// if the iteration diverges.
+func (amg *AMG) Solve(ctx context.Context, b []float64, settings *SolverSettings) (*SolverResult, error) {
+	return stationary(ctx, amg.levels[0].a, b, settings, func(x, b []float64) { amg.vcycle(0, x, b) })
+}
@@ -0,0 +1,106 @@
+package sparse
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestAMG(t *testing.T) {
+	var iterations []int
+	for _, test := range []struct {
+		name string
+		a    *CSR
+	}{
+		{name: "Poisson2D 16", a: laplacian2D(16, 16)},
+		{name: "Poisson2D 32", a: laplacian2D(32, 32)},
+		{name: "Poisson2D 64", a: laplacian2D(64, 64)},
+		{name: "Poisson3D 16", a: laplacian3D(16, 16, 16)},
+	} {
+		n, _ := test.a.Dims()
+		b := randomData(n, 1, 1)
+
+		amg, err := NewAMG(test.a, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		stats := amg.Stats()
+		if stats.Levels < 2 || stats.Rows[0] != n || stats.NonZeros[0] != test.a.NNZ() {
+			t.Errorf("%s: unexpected hierarchy %+v", test.name, stats)
+		}
+		if stats.Rows[stats.Levels-1] > 100 && stats.Levels < 10 {
+			t.Errorf("%s: expected coarsest level of at most 100 rows but was %d", test.name, stats.Rows[stats.Levels-1])
+		}
+		for l := 1; l < stats.Levels; l++ {
+			if stats.Rows[l] >= stats.Rows[l-1] {
+				t.Errorf("%s: expected level %d to be coarser than level %d but had %d rows", test.name, l, l-1, stats.Rows[l])
+			}
+		}
+		if stats.OperatorComplexity < 1 || stats.OperatorComplexity > 2 || stats.GridComplexity < 1 || stats.GridComplexity > 2 {
+			t.Errorf("%s: unexpected complexity %+v", test.name, stats)
+		}
+
+		result, err := PCG(context.Background(), test.a, b, amg, &SolverSettings{Tolerance: 1e-10})
+		if err != nil {
+			t.Fatalf("%s: unexpected error from PCG %v", test.name, err)
+		}
+		if res := residual(test.a, b, result.X); res > 1e-9 {
+			t.Errorf("%s: expected relative residual <= 1e-9 but was %g", test.name, res)
+		}
+		if result.Iterations > 25 {
+			t.Errorf("%s: expected AMG preconditioned CG to converge within 25 iterations but took %d", test.name, result.Iterations)
+		}
+		iterations = append(iterations, result.Iterations)
+
+		standalone, err := amg.Solve(context.Background(), b, &SolverSettings{Tolerance: 1e-8, MaxIterations: 100})
+		if err != nil {
+			t.Errorf("%s: unexpected error from standalone solve %v", test.name, err)
+		}
+		if res := residual(test.a, b, standalone.X); res > 1e-8 {
+			t.Errorf("%s: expected relative residual <= 1e-8 but was %g", test.name, res)
+		}
+	}
+
+	// convergence should be (roughly) independent of the mesh size
+	if iterations[2] > 2*iterations[0] {
+		t.Errorf("expected mesh independent convergence but took %v iterations", iterations)
+	}
+}
+
+func TestAMGSettings(t *testing.T) {
+	a := laplacian2D(20, 20)
+	n, _ := a.Dims()
+	b := randomData(n, 1, 1)
+
+	// a single level hierarchy is a direct solve
+	amg, err := NewAMG(a, &AMGSettings{MaxLevels: 1})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if levels := amg.Stats().Levels; levels != 1 {
+		t.Errorf("expected 1 level but had %d", levels)
+	}
+	x := make([]float64, n)
+	amg.Apply(x, b)
+	if res := residual(a, b, x); res > 1e-12 {
+		t.Errorf("expected exact solution from single level but relative residual was %g", res)
+	}
+
+	for _, settings := range []*AMGSettings{
+		{MaxLevels: 2, MaxCoarseSize: 10},
+		{StrengthThreshold: 0.25, PreSmoothing: 2, PostSmoothing: 2},
+		{Smoother: func(a *CSR) (Smoother, error) { return NewJacobi(a, 2.0/3.0) }},
+	} {
+		desc := fmt.Sprintf("%+v", *settings)
+		amg, err := NewAMG(a, settings)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", desc, err)
+		}
+		if settings.MaxLevels != 0 && amg.Stats().Levels != settings.MaxLevels {
+			t.Errorf("%s: expected %d levels but had %d", desc, settings.MaxLevels, amg.Stats().Levels)
+		}
+		if _, err := PCG(context.Background(), a, b, amg, nil); err != nil {
+			t.Errorf("%s: unexpected error from PCG %v", desc, err)
+		}
+	}
+}
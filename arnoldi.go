@@ -0,0 +1,245 @@
+package sparse
+
+import (
+	"context"
+	"math"
+	"math/cmplx"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/lapack"
+	lapackgonum "gonum.org/v1/gonum/lapack/gonum"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Eigen computes k eigenvalues (and corresponding eigenvectors) of the n x n general
+// (unsymmetric) matrix (or operator) a using the Krylov-Schur restarted Arnoldi
+// method (which is mathematically equivalent to the implicitly restarted Arnoldi
+// method).  Only the MulVecTo method of a is used so a may be a matrix free operator
+// (except in shift-invert mode).  target specifies which eigenvalues are computed,
+// typically LargestMagnitude for the dominant spectrum.  The (complex) eigenvalues
+// are returned in order of preference for the target (nearest to the shift first in
+// shift-invert mode) along with the corresponding eigenvectors (normalised to unit
+// 2-norm) as the columns of an n x k complex dense matrix.  settings may be nil in
+// which case the default settings are used.  Eigen panics if k is not in the range
+// [1, n).
+//
+// As the eigenvalues of a real matrix occur in complex conjugate pairs, the
+// subspace size should be at least k+2.  If the eigenpairs do not converge within
+// the maximum number of restarts (or the context is cancelled), the current
+// approximations are returned along with a *NotConvergedError (or the error from
+// ctx).
+func Eigen(ctx context.Context, a MulVecToer, n, k int, target EigenTarget, settings *EigenSettings) ([]complex128, *mat.CDense, error) {
+	if k < 1 || k >= n {
+		panic("sparse: number of eigenvalues must be in the range [1, n)")
+	}
+	s := settings.defaults(n, k)
+	var op *shiftInvertOp
+	if s.ShiftInvert {
+		var err error
+		if op, err = newShiftInvertOp(a, s.Shift); err != nil {
+			return nil, nil, err
+		}
+		a, target = op, LargestMagnitude
+	}
+	m := s.SubspaceSize
+	basis := newKrylovBasis(n, m, s.InitVec)
+
+	hess := mat.NewDense(m, m, nil)
+	h := make([]float64, m)
+	w := make([]float64, n)
+	var eig mat.Eigen
+	var y mat.CDense
+	var scale float64
+	l := 0
+
+	for iter := 1; ; iter++ {
+		if err := ctx.Err(); err != nil {
+			values, vectors := arnoldiPairs(basis, &eig, &y, k, m, target, op)
+			return values, vectors, err
+		}
+
+		// extend the Arnoldi factorisation A * V_m = V_m * H_m + beta * v_m * e_m^T
+		var beta float64
+		for j := l; j < m; j++ {
+			zero(w)
+			a.MulVecTo(w, false, basis.v[j])
+			beta = basis.extend(j, w, h[:j+1], scale)
+			for i := 0; i <= j; i++ {
+				hess.Set(i, j, h[i])
+			}
+			if j+1 < m {
+				hess.Set(j+1, j, beta)
+			}
+		}
+
+		if ok := eig.Factorize(hess, mat.EigenRight); !ok {
+			panic("sparse: eigendecomposition of Hessenberg matrix failed")
+		}
+		eig.VectorsTo(&y)
+		values := eig.Values(nil)
+		scale = 0
+		for _, v := range values {
+			scale = math.Max(scale, cmplx.Abs(v))
+		}
+
+		// check convergence of the wanted Ritz pairs
+		order := wanted(values, target)
+		converged := 0
+		residual := 0.0
+		for _, c := range order[:k] {
+			r := beta * cmplx.Abs(y.At(m-1, c)) / cnorm(&y, c)
+			residual = math.Max(residual, r/scale)
+			if r <= s.Tolerance*scale {
+				converged++
+			}
+		}
+		if converged == k {
+			vals, vectors := arnoldiPairs(basis, &eig, &y, k, m, target, op)
+			return vals, vectors, nil
+		}
+		if iter == s.MaxIterations {
+			vals, vectors := arnoldiPairs(basis, &eig, &y, k, m, target, op)
+			return vals, vectors, &NotConvergedError{Iterations: iter, Residual: residual}
+		}
+
+		// Krylov-Schur restart keeping the Schur vectors of the wanted Ritz values
+		// (plus some extra to accelerate convergence)
+		l = k + converged
+		if extra := (m - k) / 2; l < k+extra {
+			l = k + extra
+		}
+		if l > m-1 {
+			l = m - 1
+		}
+		t, z := orderedSchur(hess, l, target)
+		if l < m && t.At(l, l-1) != 0 {
+			// avoid splitting a complex conjugate pair
+			if l+1 < m {
+				l++
+			} else {
+				l--
+			}
+		}
+		cols := make([]int, l)
+		for i := range cols {
+			cols[i] = i
+		}
+		basis.combine(z, cols, m)
+		hess.Zero()
+		for i := 0; i < l; i++ {
+			for j := 0; j < l; j++ {
+				hess.Set(i, j, t.At(i, j))
+			}
+			hess.Set(l, i, beta*z.At(m-1, i))
+		}
+	}
+}
+
+// cnorm returns the 2-norm of column j of the complex matrix y.
+func cnorm(y *mat.CDense, j int) float64 {
+	r, _ := y.Dims()
+	var norm float64
+	for i := 0; i < r; i++ {
+		norm = math.Hypot(norm, cmplx.Abs(y.At(i, j)))
+	}
+	return norm
+}
+
+// orderedSchur computes the real Schur decomposition H = Z * T * Z^T of the square
+// matrix h with the (at least) l most wanted eigenvalues (for the target) ordered
+// first along the diagonal of T.
+func orderedSchur(h *mat.Dense, l int, target EigenTarget) (t, z *mat.Dense) {
+	impl := lapackgonum.Implementation{}
+	n, _ := h.Dims()
+	t = mat.DenseCopyOf(h)
+	z = mat.NewDense(n, n, nil)
+	tr, zr := t.RawMatrix(), z.RawMatrix()
+	tau := make([]float64, n-1)
+	wr := make([]float64, n)
+	wi := make([]float64, n)
+
+	// reduce to Hessenberg form then compute the Schur form
+	work := make([]float64, 1)
+	impl.Dgehrd(n, 0, n-1, tr.Data, tr.Stride, tau, work, -1)
+	work = make([]float64, int(work[0]))
+	impl.Dgehrd(n, 0, n-1, tr.Data, tr.Stride, tau, work, len(work))
+	z.Copy(t)
+	work = make([]float64, 1)
+	impl.Dorghr(n, 0, n-1, zr.Data, zr.Stride, tau, work, -1)
+	work = make([]float64, int(work[0]))
+	impl.Dorghr(n, 0, n-1, zr.Data, zr.Stride, tau, work, len(work))
+	for i := 2; i < n; i++ {
+		for j := 0; j < i-1; j++ {
+			t.Set(i, j, 0)
+		}
+	}
+	work = make([]float64, 1)
+	impl.Dhseqr(lapack.EigenvaluesAndSchur, lapack.SchurOrig, n, 0, n-1, tr.Data, tr.Stride, wr, wi, zr.Data, zr.Stride, work, -1)
+	work = make([]float64, int(work[0]))
+	impl.Dhseqr(lapack.EigenvaluesAndSchur, lapack.SchurOrig, n, 0, n-1, tr.Data, tr.Stride, wr, wi, zr.Data, zr.Stride, work, len(work))
+
+	// reorder the diagonal blocks moving the most wanted remaining block into the
+	// next position in turn
+	work = make([]float64, n)
+	blockSize := func(i int) int {
+		if i+1 < n && t.At(i+1, i) != 0 {
+			return 2
+		}
+		return 1
+	}
+	for pos := 0; pos < l; pos += blockSize(pos) {
+		var values []complex128
+		var starts []int
+		for i := pos; i < n; i += blockSize(i) {
+			starts = append(starts, i)
+			v := complex(t.At(i, i), 0)
+			if blockSize(i) == 2 {
+				v = complex(t.At(i, i), math.Sqrt(math.Abs(t.At(i, i+1)*t.At(i+1, i))))
+			}
+			values = append(values, v)
+		}
+		best := starts[wanted(values, target)[0]]
+		if best == pos {
+			continue
+		}
+		if _, _, ok := impl.Dtrexc(lapack.UpdateSchur, n, tr.Data, tr.Stride, zr.Data, zr.Stride, best, pos, work); !ok {
+			break
+		}
+	}
+	return t, z
+}
+
+// arnoldiPairs returns the k wanted Ritz values and vectors from the current
+// decomposition (or nil if no decomposition has been computed).  If op is not nil,
+// the Ritz values are those of the shift-invert operator and are transformed back
+// into eigenvalues of A.
+func arnoldiPairs(basis *krylovBasis, eig *mat.Eigen, y *mat.CDense, k, m int, target EigenTarget, op *shiftInvertOp) ([]complex128, *mat.CDense) {
+	if y.IsEmpty() {
+		return nil, nil
+	}
+	values := eig.Values(nil)
+	order := wanted(values, target)[:k]
+	n := len(basis.v[0])
+	vals := make([]complex128, k)
+	vectors := mat.NewCDense(n, k, nil)
+	re := make([]float64, n)
+	im := make([]float64, n)
+	for c, col := range order {
+		vals[c] = values[col]
+		if op != nil {
+			vals[c] = op.eigenvalue(vals[c])
+		}
+		zero(re)
+		zero(im)
+		for i := 0; i < m; i++ {
+			v := y.At(i, col)
+			floats.AddScaled(re, real(v), basis.v[i])
+			floats.AddScaled(im, imag(v), basis.v[i])
+		}
+		norm := math.Hypot(floats.Norm(re, 2), floats.Norm(im, 2))
+		for i := 0; i < n; i++ {
+			vectors.Set(i, c, complex(re[i]/norm, im[i]/norm))
+		}
+	}
+	return vals, vectors
+}
@@ -0,0 +1,172 @@
+package sparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// checkComplexEigenpairs checks the eigenvalues match the expected eigenvalues and
+// the eigenvectors satisfy A * v = lambda * v.
+func checkComplexEigenpairs(t *testing.T, desc string, a *CSR, want, values []complex128, vectors *mat.CDense, tol float64) {
+	n, k := vectors.Dims()
+	if len(values) != k || len(want) != k {
+		t.Fatalf("%s: expected %d eigenvalues but received %d", desc, len(want), len(values))
+	}
+	for i, v := range values {
+		if cmplx.Abs(v-want[i]) > tol*math.Max(1, cmplx.Abs(want[i])) {
+			t.Errorf("%s: expected eigenvalue %d to be %v but was %v", desc, i, want[i], v)
+		}
+
+		re := make([]float64, n)
+		im := make([]float64, n)
+		for j := 0; j < n; j++ {
+			re[j], im[j] = real(vectors.At(j, i)), imag(vectors.At(j, i))
+		}
+		ar := make([]float64, n)
+		ai := make([]float64, n)
+		a.MulVecTo(ar, false, re)
+		a.MulVecTo(ai, false, im)
+		var r, norm float64
+		for j := 0; j < n; j++ {
+			d := complex(ar[j], ai[j]) - v*complex(re[j], im[j])
+			r = math.Hypot(r, cmplx.Abs(d))
+			norm = math.Hypot(norm, cmplx.Abs(vectors.At(j, i)))
+		}
+		if r > tol*math.Max(1, cmplx.Abs(v)) {
+			t.Errorf("%s: eigenpair %d (%v) has residual %g", desc, i, v, r)
+		}
+		if math.Abs(norm-1) > 1e-10 {
+			t.Errorf("%s: expected unit eigenvector %d but norm was %v", desc, i, norm)
+		}
+	}
+}
+
+// denseEigenvalues returns the k wanted eigenvalues of a computed densely.  If sigma
+// is not nil, the k eigenvalues nearest sigma are returned.
+func denseEigenvalues(a *CSR, k int, target EigenTarget, sigma *float64) []complex128 {
+	var eig mat.Eigen
+	if ok := eig.Factorize(a.ToDense(), mat.EigenNone); !ok {
+		panic("dense eigendecomposition failed")
+	}
+	values := eig.Values(nil)
+	key := values
+	if sigma != nil {
+		key = make([]complex128, len(values))
+		for i, v := range values {
+			key[i] = 1 / (v - complex(*sigma, 0))
+		}
+		target = LargestMagnitude
+	}
+	want := make([]complex128, k)
+	for i, c := range wanted(key, target)[:k] {
+		want[i] = values[c]
+	}
+	return want
+}
+
+// markovChain returns the (column stochastic) transition matrix of a random walk on a
+// random sparse graph.
+func markovChain(n int, density float64) *CSR {
+	dok := NewDOK(n, n)
+	for j := 0; j < n; j++ {
+		var targets []int
+		targets = append(targets, (j+1)%n)
+		for i := 0; i < n; i++ {
+			if i != (j+1)%n && rand.Float64() < density {
+				targets = append(targets, i)
+			}
+		}
+		for _, i := range targets {
+			dok.Set(i, j, 1/float64(len(targets)))
+		}
+	}
+	return dok.ToCSR()
+}
+
+func TestEigen(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		a      *CSR
+		k      int
+		target EigenTarget
+	}{
+		{name: "random unsymmetric", a: CreateCSR(80, 80, randomData(80, 80, 0.1)).(*CSR), k: 4, target: LargestMagnitude},
+		{name: "convection-diffusion", a: convectionDiffusion2D(12, 9, 2), k: 5, target: LargestMagnitude},
+		{name: "convection-diffusion smallest real", a: convectionDiffusion2D(12, 9, 2), k: 3, target: SmallestAlgebraic},
+		{name: "Markov chain", a: markovChain(150, 0.03), k: 3, target: LargestMagnitude},
+	} {
+		n, _ := test.a.Dims()
+		desc := fmt.Sprintf("%s (target %d)", test.name, test.target)
+		values, vectors, err := Eigen(context.Background(), test.a, n, test.k, test.target, &EigenSettings{SubspaceSize: 30})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", desc, err)
+		}
+		want := denseEigenvalues(test.a, test.k, test.target, nil)
+		checkComplexEigenpairs(t, desc, test.a, want, values, vectors, 1e-8)
+	}
+}
+
+func TestEigenMarkovStationary(t *testing.T) {
+	a := markovChain(200, 0.02)
+	values, vectors, err := Eigen(context.Background(), a, 200, 1, LargestMagnitude, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cmplx.Abs(values[0]-1) > 1e-10 {
+		t.Errorf("expected dominant eigenvalue of 1 but was %v", values[0])
+	}
+	// the stationary distribution has elements of the same sign
+	sign := real(vectors.At(0, 0)) > 0
+	for i := 0; i < 200; i++ {
+		if v := vectors.At(i, 0); math.Abs(imag(v)) > 1e-10 || (real(v) > 0) != sign {
+			t.Errorf("expected real eigenvector with elements of the same sign but element %d was %v", i, v)
+			break
+		}
+	}
+}
+
+func TestEigenShiftInvert(t *testing.T) {
+	a := convectionDiffusion2D(15, 12, 1)
+	n, _ := a.Dims()
+	for _, sigma := range []float64{0, 4.1} {
+		values, vectors, err := Eigen(context.Background(), a, n, 4, LargestMagnitude, &EigenSettings{ShiftInvert: true, Shift: sigma})
+		if err != nil {
+			t.Fatalf("sigma %v: unexpected error %v", sigma, err)
+		}
+		want := denseEigenvalues(a, 4, LargestMagnitude, &sigma)
+		checkComplexEigenpairs(t, fmt.Sprintf("shift-invert (sigma %v)", sigma), a, want, values, vectors, 1e-8)
+	}
+
+	// symmetric shift-invert finds interior eigenvalues
+	sym := laplacian2D(14, 9)
+	n, _ = sym.Dims()
+	sigma := 3.3
+	values, vectors, err := EigenSym(context.Background(), sym, n, 4, LargestAlgebraic, &EigenSettings{ShiftInvert: true, Shift: sigma})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := denseEigenvalues(sym, 4, LargestMagnitude, &sigma)
+	for i, v := range values {
+		if math.Abs(v-real(want[i])) > 1e-8 {
+			t.Errorf("expected eigenvalue %d nearest %v to be %v but was %v", i, sigma, real(want[i]), v)
+		}
+	}
+	checkEigenpairs(t, "symmetric shift-invert", sym, values, vectors, 1e-8)
+
+	singular := CreateCSR(3, 3, []float64{
+		1, 0, 0,
+		0, 2, 0,
+		0, 0, 3,
+	}).(*CSR)
+	_, _, err = Eigen(context.Background(), singular, 3, 1, LargestMagnitude, &EigenSettings{ShiftInvert: true, Shift: 2, SubspaceSize: 3})
+	if !errors.Is(err, mat.ErrSingular) {
+		t.Errorf("expected singular error for shift equal to an eigenvalue but received %v", err)
+	}
+}
@@ -475,3 +475,79 @@ func BenchmarkTraceDIA(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkCholesky(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		a    *CSR
+	}{
+		{name: "Poisson2D 32x32", a: laplacian2D(32, 32)},
+		{name: "Poisson2D 64x64", a: laplacian2D(64, 64)},
+		{name: "Poisson2D 128x128", a: laplacian2D(128, 128)},
+		{name: "Poisson3D 10x10x10", a: laplacian3D(10, 10, 10)},
+		{name: "Poisson3D 16x16x16", a: laplacian3D(16, 16, 16)},
+		{name: "Poisson3D 20x20x20", a: laplacian3D(20, 20, 20)},
+	}
+	orderings := []struct {
+		name string
+		o    Ordering
+	}{
+		{name: "Natural", o: NaturalOrdering{}},
+		{name: "AMD", o: AMDOrdering{}},
+		{name: "ND", o: NestedDissectionOrdering{}},
+	}
+
+	for _, bench := range benchmarks {
+		for _, ordering := range orderings {
+			b.Run(fmt.Sprintf("Factorize %s %s", bench.name, ordering.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					var chol Cholesky
+					chol.Factorize(bench.a, WithOrdering(ordering.o))
+				}
+			})
+		}
+		b.Run(fmt.Sprintf("Refactorize %s AMD", bench.name), func(b *testing.B) {
+			var chol Cholesky
+			chol.Factorize(bench.a, WithOrdering(AMDOrdering{}))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				chol.Refactorize(bench.a)
+			}
+		})
+	}
+}
+
+func BenchmarkSpMV(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		a    *CSR
+	}{
+		{name: "Poisson2D 300x300", a: laplacian2D(300, 300)},
+		{name: "Poisson3D 40x40x40", a: laplacian3D(40, 40, 40)},
+		{name: "Random 10000x10000", a: Random(CSRFormat, 10000, 10000, 0.001).(*CSR)},
+	}
+
+	for _, bench := range benchmarks {
+		r, c := bench.a.Dims()
+		x := make([]float64, c)
+		for i := range x {
+			x[i] = float64(i%7) - 3
+		}
+		dst := make([]float64, r)
+		formats := []struct {
+			name string
+			m    MulVecToer
+		}{
+			{name: "CSR", m: bench.a},
+			{name: "ELL", m: bench.a.ToELL()},
+			{name: "SELL-8-64", m: bench.a.ToSELL(8, 64)},
+		}
+		for _, format := range formats {
+			b.Run(fmt.Sprintf("%s %s", bench.name, format.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					format.m.MulVecTo(dst, false, x)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package sparse
+
+import (
+	"context"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+// BiCGStab solves the system of linear equations A * x = b for x using the
+// BiConjugate Gradient Stabilised method where A is a general (unsymmetric) square
+// matrix (or operator).  If a preconditioner is specified in the settings it is
+// applied on the right so the residual norms reported are those of the
+// unpreconditioned system.  settings may be nil in which case the default settings
+// are used.
+//
+// The result is returned along with a nil error if the solver converged to the
+// required tolerance.  Otherwise the result holds the best solution found, its
+// Status records the outcome and the error is a *NotConvergedError if the maximum
+// number of iterations was reached, a *BreakdownError if the method broke down
+// (encountered a zero inner product) or the error from ctx if the context was
+// cancelled.
+func BiCGStab(ctx context.Context, a MulVecToer, b []float64, settings *SolverSettings) (*SolverResult, error) {
+	n := len(b)
+	r := make([]float64, n)
+	state := newIterativeState(ctx, a, b, settings, r)
+	x := state.result.X
+	if state.converged(0, floats.Norm(r, 2)) {
+		return state.result, nil
+	}
+
+	rHat := make([]float64, n)
+	copy(rHat, r)
+	p := make([]float64, n)
+	pHat := make([]float64, n)
+	v := make([]float64, n)
+	s := make([]float64, n)
+	sHat := make([]float64, n)
+	t := make([]float64, n)
+	rho, alpha, omega := 1.0, 1.0, 1.0
+
+	for k := 1; ; k++ {
+		if err := state.next(k); err != nil {
+			return state.result, err
+		}
+
+		rhoNext := floats.Dot(rHat, r)
+		if rhoNext == 0 {
+			return state.result, state.breakdown(k, "r_0^T * r = 0")
+		}
+		if k == 1 {
+			copy(p, r)
+		} else {
+			// p = r + beta * (p - omega * v)
+			beta := (rhoNext / rho) * (alpha / omega)
+			floats.AddScaled(p, -omega, v)
+			floats.AddScaledTo(p, r, beta, p)
+		}
+		rho = rhoNext
+
+		state.precondition(pHat, p)
+		zero(v)
+		a.MulVecTo(v, false, pHat)
+		rv := floats.Dot(rHat, v)
+		if rv == 0 {
+			return state.result, state.breakdown(k, "r_0^T * A * p = 0")
+		}
+		alpha = rho / rv
+
+		floats.AddScaledTo(s, r, -alpha, v)
+		if sNorm := floats.Norm(s, 2); state.relative(sNorm) <= state.settings.Tolerance {
+			floats.AddScaled(x, alpha, pHat)
+			state.converged(k, sNorm)
+			return state.result, nil
+		}
+
+		state.precondition(sHat, s)
+		zero(t)
+		a.MulVecTo(t, false, sHat)
+		tt := floats.Dot(t, t)
+		if tt == 0 {
+			floats.AddScaled(x, alpha, pHat)
+			return state.result, state.breakdown(k, "A * s = 0")
+		}
+		omega = floats.Dot(t, s) / tt
+
+		floats.AddScaled(x, alpha, pHat)
+		floats.AddScaled(x, omega, sHat)
+		floats.AddScaledTo(r, s, -omega, t)
+		if state.converged(k, floats.Norm(r, 2)) {
+			return state.result, nil
+		}
+		if omega == 0 {
+			return state.result, state.breakdown(k, "omega = 0")
+		}
+	}
+}
@@ -25,8 +25,122 @@ func Dusmv(transA bool, alpha float64, a *SparseMatrix, x []float64, incx int, y
 	}
 }
 
-// Dussv (sparse triangular solve (x <- alpha * T^-1 * x Or x <- alpha * T^T * x)) solves
+// Dussv (sparse triangular solve (x <- alpha * T^-1 * x Or x <- alpha * T^-T * x)) solves
 // a system of equations where x is a dense vector and T is a triangular sparse matrix.
-//func Dussv(transT bool, alpha float64, t *SparseMatrix, x []float64, incx int) {
+// transT is a boolean indicating whether to transpose (true) t and lower indicates
+// whether t is lower (true) or upper (false) triangular.  The diagonal elements of t
+// must be stored and non-zero.  alpha is used to scale the solution and incx represents
+// the span to be used for indexing into vector x.
+func Dussv(transT bool, lower bool, alpha float64, t *SparseMatrix, x []float64, incx int) {
+	n := t.I
 
-//}
+	if alpha != 1 {
+		for i := 0; i < n; i++ {
+			x[i*incx] *= alpha
+		}
+	}
+
+	if !transT {
+		// substitution row by row where each x[i] depends on the previously
+		// computed elements of x
+		solveRow := func(i int) {
+			var diag float64
+			v := x[i*incx]
+			for k := t.Indptr[i]; k < t.Indptr[i+1]; k++ {
+				if j := t.Ind[k]; j == i {
+					diag = t.Data[k]
+				} else {
+					v -= t.Data[k] * x[j*incx]
+				}
+			}
+			x[i*incx] = v / diag
+		}
+		if lower {
+			for i := 0; i < n; i++ {
+				solveRow(i)
+			}
+		} else {
+			for i := n - 1; i >= 0; i-- {
+				solveRow(i)
+			}
+		}
+		return
+	}
+
+	// T^T is stored column-wise so once x[i] is computed, it is eliminated from the
+	// remaining equations
+	solveCol := func(i int) {
+		begin, end := t.Indptr[i], t.Indptr[i+1]
+		var diag float64
+		for k := begin; k < end; k++ {
+			if t.Ind[k] == i {
+				diag = t.Data[k]
+				break
+			}
+		}
+		x[i*incx] /= diag
+		v := x[i*incx]
+		for k := begin; k < end; k++ {
+			if j := t.Ind[k]; j != i {
+				x[j*incx] -= t.Data[k] * v
+			}
+		}
+	}
+	if lower {
+		for i := n - 1; i >= 0; i-- {
+			solveCol(i)
+		}
+	} else {
+		for i := 0; i < n; i++ {
+			solveCol(i)
+		}
+	}
+}
+
+// Dbsrmv (block sparse matrix / vector multiply (y <- alpha * A * x + y Or y <- alpha * A^T * x + y))
+// multiplies a dense vector x by block sparse matrix a (or its transpose), and adds it
+// to the dense vector y.  transA is a boolean indicating whether to transpose (true) a.
+// alpha is used to scale a and incx and incy represent the span to be used for indexing into
+// vectors x and y respectively.
+func Dbsrmv(transA bool, alpha float64, a *BlockSparseMatrix, x []float64, incx int, y []float64, incy int) {
+	if alpha == 0 {
+		return
+	}
+
+	r, c := a.R, a.C
+	size := r * c
+
+	if transA {
+		for bi := 0; bi < a.I; bi++ {
+			for k := a.Indptr[bi]; k < a.Indptr[bi+1]; k++ {
+				block := a.Data[k*size : (k+1)*size]
+				yoff := a.Ind[k] * c
+				for ii := 0; ii < r; ii++ {
+					xv := alpha * x[(bi*r+ii)*incx]
+					if xv == 0 {
+						continue
+					}
+					row := block[ii*c : (ii+1)*c]
+					for jj, v := range row {
+						y[(yoff+jj)*incy] += v * xv
+					}
+				}
+			}
+		}
+		return
+	}
+
+	for bi := 0; bi < a.I; bi++ {
+		for ii := 0; ii < r; ii++ {
+			var sum float64
+			for k := a.Indptr[bi]; k < a.Indptr[bi+1]; k++ {
+				row := a.Data[k*size+ii*c : k*size+(ii+1)*c]
+				xoff := a.Ind[k] * c
+				for jj, v := range row {
+					sum += v * x[(xoff+jj)*incx]
+				}
+			}
+			y[(bi*r+ii)*incy] += alpha * sum
+		}
+	}
+}
@@ -145,3 +145,189 @@ func TestDusmv(t *testing.T) {
 		}
 	}
 }
+
+func TestDbsrmv(t *testing.T) {
+	// 1, 2, 0, 0,
+	// 3, 4, 0, 0,
+	// 0, 0, 5, 0,
+	// 6, 0, 0, 7,
+	a := &BlockSparseMatrix{
+		I: 2, J: 2,
+		R: 2, C: 2,
+		Indptr: []int{0, 1, 3},
+		Ind:    []int{0, 0, 1},
+		Data: []float64{
+			1, 2, 3, 4,
+			0, 0, 6, 0,
+			5, 0, 0, 7,
+		},
+	}
+
+	tests := []struct {
+		transA   bool
+		alpha    float64
+		x        []float64
+		incx     int
+		y        []float64
+		incy     int
+		expected []float64
+	}{
+		{
+			transA:   false,
+			alpha:    0,
+			x:        []float64{1, 2, 3, 4},
+			incx:     1,
+			y:        []float64{0, 0, 0, 0},
+			incy:     1,
+			expected: []float64{0, 0, 0, 0},
+		},
+		{
+			transA:   false,
+			alpha:    1,
+			x:        []float64{1, 2, 3, 4},
+			incx:     1,
+			y:        []float64{0, 0, 0, 1},
+			incy:     1,
+			expected: []float64{5, 11, 15, 35},
+		},
+		{
+			transA:   true,
+			alpha:    2,
+			x:        []float64{1, 2, 3, 4},
+			incx:     1,
+			y:        []float64{0, 0, 0, 0},
+			incy:     1,
+			expected: []float64{62, 20, 30, 56},
+		},
+		{
+			transA: false,
+			alpha:  1,
+			x: []float64{
+				1, 5,
+				2, 5,
+				3, 5,
+				4, 5,
+			},
+			incx: 2,
+			y: []float64{
+				0, 5,
+				0, 5,
+				0, 5,
+				0, 5,
+			},
+			incy: 2,
+			expected: []float64{
+				5, 5,
+				11, 5,
+				15, 5,
+				34, 5,
+			},
+		},
+	}
+
+	for ti, test := range tests {
+		Dbsrmv(test.transA, test.alpha, a, test.x, test.incx, test.y, test.incy)
+
+		for i, v := range test.expected {
+			if v != test.y[i] {
+				t.Errorf("Test %d: Expected %f at %d but received %f", ti, v, i, test.y[i])
+			}
+		}
+	}
+}
+
+func TestDussv(t *testing.T) {
+	// 2, 0, 0,
+	// 1, 4, 0,
+	// 0, 3, 5,
+	lower := &SparseMatrix{
+		I: 3, J: 3,
+		Indptr: []int{0, 1, 3, 5},
+		Ind:    []int{0, 1, 0, 2, 1},
+		Data:   []float64{2, 4, 1, 5, 3},
+	}
+	// 2, 1, 0,
+	// 0, 4, 3,
+	// 0, 0, 5,
+	upper := &SparseMatrix{
+		I: 3, J: 3,
+		Indptr: []int{0, 2, 4, 5},
+		Ind:    []int{1, 0, 1, 2, 2},
+		Data:   []float64{1, 2, 4, 3, 5},
+	}
+
+	tests := []struct {
+		transT   bool
+		lower    bool
+		alpha    float64
+		t        *SparseMatrix
+		x        []float64
+		incx     int
+		expected []float64
+	}{
+		{
+			transT:   false,
+			lower:    true,
+			alpha:    1,
+			t:        lower,
+			x:        []float64{2, 9, 26},
+			incx:     1,
+			expected: []float64{1, 2, 4},
+		},
+		{
+			transT:   true,
+			lower:    true,
+			alpha:    1,
+			t:        lower,
+			x:        []float64{4, 20, 20},
+			incx:     1,
+			expected: []float64{1, 2, 4},
+		},
+		{
+			transT:   false,
+			lower:    false,
+			alpha:    1,
+			t:        upper,
+			x:        []float64{4, 20, 20},
+			incx:     1,
+			expected: []float64{1, 2, 4},
+		},
+		{
+			transT:   true,
+			lower:    false,
+			alpha:    1,
+			t:        upper,
+			x:        []float64{2, 9, 26},
+			incx:     1,
+			expected: []float64{1, 2, 4},
+		},
+		{
+			transT:   false,
+			lower:    true,
+			alpha:    2,
+			t:        lower,
+			x:        []float64{2, 5, 9, 5, 26, 5},
+			incx:     2,
+			expected: []float64{2, 5, 4, 5, 8, 5},
+		},
+		{
+			transT:   true,
+			lower:    false,
+			alpha:    -1,
+			t:        upper,
+			x:        []float64{2, 5, 9, 5, 26, 5},
+			incx:     2,
+			expected: []float64{-1, 5, -2, 5, -4, 5},
+		},
+	}
+
+	for ti, test := range tests {
+		Dussv(test.transT, test.lower, test.alpha, test.t, test.x, test.incx)
+
+		for i, v := range test.expected {
+			if v != test.x[i] {
+				t.Errorf("Test %d: Expected %f at %d but received %f", ti, v, i, test.x[i])
+			}
+		}
+	}
+}
@@ -16,3 +16,16 @@ func Dusmm(transA bool, k int, alpha float64, a *SparseMatrix, b []float64, ldb
 		Dusmv(transA, alpha, a, b[i:], ldb, c[i:], ldc)
 	}
 }
+
+// Dussm (Sparse triangular solve (B <- alpha * T^-1 * B Or B <- alpha * T^-T * B))
+// solves a system of equations with multiple right hand sides where B is a dense
+// matrix and T is a triangular sparse matrix.  B is modified to hold the solution.
+// transT is a boolean indicating whether to transpose (true) t and lower indicates
+// whether t is lower (true) or upper (false) triangular.  k represents the number of
+// columns in matrix B and ldb is the span to be used for indexing into matrix B.
+func Dussm(transT bool, lower bool, k int, alpha float64, t *SparseMatrix, b []float64, ldb int) {
+	// Perform k triangular solves: i-th column of B gets T^-1*(i-th column of B)
+	for i := 0; i < k; i++ {
+		Dussv(transT, lower, alpha, t, b[i:], ldb)
+	}
+}
@@ -174,3 +174,67 @@ func TestDusmm(t *testing.T) {
 		}
 	}
 }
+
+func TestDussm(t *testing.T) {
+	// 2, 0, 0,
+	// 1, 4, 0,
+	// 0, 3, 5,
+	lower := &SparseMatrix{
+		I: 3, J: 3,
+		Indptr: []int{0, 1, 3, 5},
+		Ind:    []int{0, 0, 1, 1, 2},
+		Data:   []float64{2, 1, 4, 3, 5},
+	}
+
+	tests := []struct {
+		transT bool
+		alpha  float64
+		k      int
+		bData  []float64
+		ldb    int
+		eData  []float64
+	}{
+		{
+			transT: false,
+			alpha:  1,
+			k:      2,
+			bData: []float64{
+				2, 4,
+				9, 18,
+				26, 52,
+			},
+			ldb: 2,
+			eData: []float64{
+				1, 2,
+				2, 4,
+				4, 8,
+			},
+		},
+		{
+			transT: true,
+			alpha:  0.5,
+			k:      2,
+			bData: []float64{
+				4, 8, 7,
+				20, 40, 7,
+				20, 40, 7,
+			},
+			ldb: 3,
+			eData: []float64{
+				0.5, 1, 7,
+				1, 2, 7,
+				2, 4, 7,
+			},
+		},
+	}
+
+	for ti, test := range tests {
+		Dussm(test.transT, true, test.k, test.alpha, lower, test.bData, test.ldb)
+
+		for i, v := range test.eData {
+			if v != test.bData[i] {
+				t.Errorf("Test %d: Expected %f at %d but received %f", ti, v, i, test.bData[i])
+			}
+		}
+	}
+}
@@ -120,3 +120,36 @@ func (m *SparseMatrix) Cull(epsilon float64) *SparseMatrix {
 		Data:   newData,
 	}
 }
+
+// BlockSparseMatrix represents the common structure for representing block compressed
+// sparse matrix formats e.g. BSR (Block Sparse Row).  I and J are the number of block
+// rows and block columns respectively and R and C are the number of rows and columns
+// within each dense block.  Indptr and Ind index the blocks in the same way as
+// SparseMatrix indexes individual elements and each block is stored contiguously in
+// Data in row major order i.e. block k occupies Data[k*R*C:(k+1)*R*C].
+type BlockSparseMatrix struct {
+	I, J   int
+	R, C   int
+	Indptr []int
+	Ind    []int
+	Data   []float64
+}
+
+// At returns the element of the matrix located at (scalar) coordinate i, j.
+func (m *BlockSparseMatrix) At(i, j int) float64 {
+	if uint(i) < 0 || uint(i) >= uint(m.I*m.R) {
+		panic("sparse/blas: index out of range")
+	}
+	if uint(j) < 0 || uint(j) >= uint(m.J*m.C) {
+		panic("sparse/blas: index out of range")
+	}
+
+	bi, bj := i/m.R, j/m.C
+	for k := m.Indptr[bi]; k < m.Indptr[bi+1]; k++ {
+		if m.Ind[k] == bj {
+			return m.Data[k*m.R*m.C+(i%m.R)*m.C+j%m.C]
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,410 @@
+package sparse
+
+import (
+	"sort"
+
+	"github.com/james-bowman/sparse/blas"
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+	_ Sparser       = (*BSR)(nil)
+	_ TypeConverter = (*BSR)(nil)
+)
+
+// BSR is a Block Sparse Row format sparse matrix implementation (sometimes called Block Compressed
+// Row Storage (BCRS) format) and implements the Matrix interface from gonum/matrix.  BSR matrices
+// are similar to CSR matrices except that rather than indexing individual non-zero elements, BSR
+// matrices index small dense r * c blocks of elements.  Each stored block is held contiguously in
+// row major order.  Matrices with a natural block structure, for example the stiffness matrices
+// arising from finite element problems with several degrees of freedom per node, can be stored
+// with significantly less index storage than CSR and processed more efficiently as the dense
+// blocks make better use of memory bandwidth and vector instructions.  The dimensions of the matrix
+// must be exact multiples of the block dimensions.
+// As this type implements the gonum mat.Matrix interface, it may be used with any of the Gonum mat
+// functions that accept Matrix types as parameters in place of other matrix types included in the Gonum
+// mat package e.g. mat.Dense.
+type BSR struct {
+	matrix blas.BlockSparseMatrix
+}
+
+// NewBSR creates a new Block Sparse Row format sparse matrix.
+// The matrix is initialised to the size of the specified r * c dimensions (rows * columns)
+// comprised of dense blocks of size br * bc.  indptr and ind contain the block row pointers and
+// block column indexes of the stored blocks and data contains the values of the stored blocks,
+// each block occupying br * bc consecutive elements in row major order.  The supplied slices
+// will be used as the backing storage to the matrix so changes to values of the slices will be
+// reflected in the created matrix and vice versa.  NewBSR will panic if r is not a multiple of br
+// or c is not a multiple of bc.
+func NewBSR(r, c, br, bc int, indptr []int, ind []int, data []float64) *BSR {
+	if r < 0 || br <= 0 || r%br != 0 {
+		panic(mat.ErrRowAccess)
+	}
+	if c < 0 || bc <= 0 || c%bc != 0 {
+		panic(mat.ErrColAccess)
+	}
+
+	return &BSR{
+		matrix: blas.BlockSparseMatrix{
+			I: r / br, J: c / bc,
+			R: br, C: bc,
+			Indptr: indptr,
+			Ind:    ind,
+			Data:   data,
+		},
+	}
+}
+
+// Dims returns the size of the matrix as the number of rows and columns
+func (b *BSR) Dims() (int, int) {
+	return b.matrix.I * b.matrix.R, b.matrix.J * b.matrix.C
+}
+
+// BlockDims returns the dimensions of each of the dense blocks comprising the matrix as
+// the number of rows and columns.
+func (b *BSR) BlockDims() (int, int) {
+	return b.matrix.R, b.matrix.C
+}
+
+// At returns the element of the matrix located at row i and column j.  At will panic if specified values
+// for i or j fall outside the dimensions of the matrix.
+func (b *BSR) At(i, j int) float64 {
+	return b.matrix.At(i, j)
+}
+
+// T transposes the matrix creating a new BSR matrix with transposed blocks.  Unlike CSR and CSC,
+// the returned matrix does not share the backing data storage of the receiver.
+func (b *BSR) T() mat.Matrix {
+	r, c := b.matrix.R, b.matrix.C
+	size := r * c
+	nnzb := len(b.matrix.Ind)
+
+	indptr := make([]int, b.matrix.J+1)
+	ind := make([]int, nnzb)
+	data := make([]float64, len(b.matrix.Data))
+
+	for _, bj := range b.matrix.Ind {
+		indptr[bj+1]++
+	}
+	for j := 0; j < b.matrix.J; j++ {
+		indptr[j+1] += indptr[j]
+	}
+
+	next := getInts(b.matrix.J, false)
+	copy(next, indptr[:b.matrix.J])
+	for bi := 0; bi < b.matrix.I; bi++ {
+		for k := b.matrix.Indptr[bi]; k < b.matrix.Indptr[bi+1]; k++ {
+			p := next[b.matrix.Ind[k]]
+			next[b.matrix.Ind[k]]++
+			ind[p] = bi
+			src := b.matrix.Data[k*size : (k+1)*size]
+			dst := data[p*size : (p+1)*size]
+			for ii := 0; ii < r; ii++ {
+				for jj := 0; jj < c; jj++ {
+					dst[jj*r+ii] = src[ii*c+jj]
+				}
+			}
+		}
+	}
+	putInts(next)
+
+	return &BSR{
+		matrix: blas.BlockSparseMatrix{
+			I: b.matrix.J, J: b.matrix.I,
+			R: c, C: r,
+			Indptr: indptr,
+			Ind:    ind,
+			Data:   data,
+		},
+	}
+}
+
+// NNZ returns the number of stored elements in the sparse matrix.  As entire blocks are stored,
+// this number includes any explicit zero values contained within the stored blocks.
+func (b *BSR) NNZ() int {
+	return len(b.matrix.Data)
+}
+
+// NNZB returns the Number of Non Zero Blocks stored in the sparse matrix.
+func (b *BSR) NNZB() int {
+	return len(b.matrix.Ind)
+}
+
+// Trace returns the trace.
+func (b *BSR) Trace() float64 {
+	var trace float64
+	r, c := b.Dims()
+	if c < r {
+		r = c
+	}
+	for i := 0; i < r; i++ {
+		trace += b.matrix.At(i, i)
+	}
+	return trace
+}
+
+// RawBlockMatrix returns a pointer to the underlying blas block sparse matrix.
+func (b *BSR) RawBlockMatrix() *blas.BlockSparseMatrix {
+	return &b.matrix
+}
+
+// DoNonZero calls the function fn for each of the non-zero elements of the receiver.
+// The function fn takes a row/column index and the element value of the receiver at
+// (i, j).  Explicit zero values stored within blocks are not visited.  The order of
+// visiting to each non-zero element is block row major and then row major within each block.
+func (b *BSR) DoNonZero(fn func(i, j int, v float64)) {
+	r, c := b.matrix.R, b.matrix.C
+	size := r * c
+	for bi := 0; bi < b.matrix.I; bi++ {
+		for k := b.matrix.Indptr[bi]; k < b.matrix.Indptr[bi+1]; k++ {
+			block := b.matrix.Data[k*size : (k+1)*size]
+			for ii := 0; ii < r; ii++ {
+				for jj := 0; jj < c; jj++ {
+					if v := block[ii*c+jj]; v != 0 {
+						fn(bi*r+ii, b.matrix.Ind[k]*c+jj, v)
+					}
+				}
+			}
+		}
+	}
+}
+
+// MulVecTo performs matrix vector multiplication (dst+=A*x or dst+=A^T*x), where A is
+// the receiver, and stores the result in dst.  MulVecTo panics if ac != len(x) or
+// ar != len(dst)
+func (b *BSR) MulVecTo(dst []float64, trans bool, x []float64) {
+	ar, ac := b.Dims()
+	if trans {
+		ar, ac = ac, ar
+	}
+	if ac != len(x) || ar != len(dst) {
+		panic(mat.ErrShape)
+	}
+
+	blas.Dbsrmv(trans, 1, &b.matrix, x, 1, dst, 1)
+}
+
+// ToDense returns a mat.Dense dense format version of the matrix.  The returned mat.Dense
+// matrix will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (b *BSR) ToDense() *mat.Dense {
+	r, c := b.Dims()
+	dense := mat.NewDense(r, c, nil)
+	b.DoNonZero(func(i, j int, v float64) {
+		dense.Set(i, j, v)
+	})
+	return dense
+}
+
+// ToDOK returns a DOK (Dictionary Of Keys) sparse format version of the matrix.  The returned DOK
+// matrix will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (b *BSR) ToDOK() *DOK {
+	r, c := b.Dims()
+	dok := NewDOK(r, c)
+	b.DoNonZero(func(i, j int, v float64) {
+		dok.Set(i, j, v)
+	})
+	return dok
+}
+
+// ToCOO returns a COOrdinate sparse format version of the matrix.  The returned COO matrix will
+// not share underlying storage with the receiver nor is the receiver modified by this call.
+// Explicit zero values stored within blocks are not included in the returned matrix.
+func (b *BSR) ToCOO() *COO {
+	r, c := b.Dims()
+	coo := NewCOO(r, c, nil, nil, nil)
+	b.DoNonZero(func(i, j int, v float64) {
+		coo.rows = append(coo.rows, i)
+		coo.cols = append(coo.cols, j)
+		coo.data = append(coo.data, v)
+	})
+	return coo
+}
+
+// ToCSR returns a Compressed Sparse Row sparse format version of the matrix.  The returned CSR matrix
+// will not share underlying storage with the receiver nor is the receiver modified by this call.
+// Explicit zero values stored within blocks are not included in the returned matrix.
+func (b *BSR) ToCSR() *CSR {
+	r, c := b.matrix.R, b.matrix.C
+	size := r * c
+	rows, cols := b.Dims()
+
+	indptr := make([]int, rows+1)
+	ind := make([]int, 0, len(b.matrix.Data))
+	data := make([]float64, 0, len(b.matrix.Data))
+
+	for bi := 0; bi < b.matrix.I; bi++ {
+		for ii := 0; ii < r; ii++ {
+			for k := b.matrix.Indptr[bi]; k < b.matrix.Indptr[bi+1]; k++ {
+				row := b.matrix.Data[k*size+ii*c : k*size+(ii+1)*c]
+				for jj, v := range row {
+					if v != 0 {
+						ind = append(ind, b.matrix.Ind[k]*c+jj)
+						data = append(data, v)
+					}
+				}
+			}
+			indptr[bi*r+ii+1] = len(ind)
+		}
+	}
+
+	return NewCSR(rows, cols, indptr, ind, data)
+}
+
+// ToCSC returns a Compressed Sparse Column sparse format version of the matrix.  The returned CSC matrix
+// will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (b *BSR) ToCSC() *CSC {
+	return b.ToCOO().ToCSCReuseMem()
+}
+
+// ToBSR returns the receiver if it already has blocks of size r * c, otherwise a new
+// BSR matrix with blocks of the requested size is returned.
+func (b *BSR) ToBSR(r, c int) *BSR {
+	if b.matrix.R == r && b.matrix.C == c {
+		return b
+	}
+	return b.ToCSR().ToBSR(r, c)
+}
+
+// ToType returns an alternative format version fo the matrix in the format specified.
+func (b *BSR) ToType(matType MatrixType) mat.Matrix {
+	return matType.Convert(b)
+}
+
+// ToBSR returns a Block Sparse Row sparse format version of the matrix comprised of dense blocks of
+// size r * c.  Any block containing at least one non-zero element will be stored in its entirety
+// (including zero values).  The returned BSR matrix will not share underlying storage with the receiver
+// nor is the receiver modified by this call.  ToBSR will panic if the dimensions of the receiver are
+// not exact multiples of the block dimensions.
+func (c *CSR) ToBSR(r, cb int) *BSR {
+	rows, cols := c.Dims()
+	if r <= 0 || rows%r != 0 || cb <= 0 || cols%cb != 0 {
+		panic(mat.ErrShape)
+	}
+	nbr, nbc := rows/r, cols/cb
+	size := r * cb
+
+	indptr := make([]int, nbr+1)
+	var ind []int
+	var data []float64
+
+	// slot maps block column to position of the block within the current block row
+	slot := getInts(nbc, false)
+	for i := range slot {
+		slot[i] = -1
+	}
+	var blockCols []int
+
+	for bi := 0; bi < nbr; bi++ {
+		blockCols = blockCols[:0]
+		for i := bi * r; i < (bi+1)*r; i++ {
+			for k := c.matrix.Indptr[i]; k < c.matrix.Indptr[i+1]; k++ {
+				bj := c.matrix.Ind[k] / cb
+				if slot[bj] < 0 {
+					slot[bj] = 0
+					blockCols = append(blockCols, bj)
+				}
+			}
+		}
+		sort.Ints(blockCols)
+		begin := len(ind)
+		for n, bj := range blockCols {
+			slot[bj] = begin + n
+			ind = append(ind, bj)
+		}
+		data = append(data, make([]float64, len(blockCols)*size)...)
+
+		for i := bi * r; i < (bi+1)*r; i++ {
+			ii := i - bi*r
+			for k := c.matrix.Indptr[i]; k < c.matrix.Indptr[i+1]; k++ {
+				j := c.matrix.Ind[k]
+				data[slot[j/cb]*size+ii*cb+j%cb] += c.matrix.Data[k]
+			}
+		}
+
+		for _, bj := range blockCols {
+			slot[bj] = -1
+		}
+		indptr[bi+1] = len(ind)
+	}
+	putInts(slot)
+
+	return NewBSR(rows, cols, r, cb, indptr, ind, data)
+}
+
+// ToBSR returns a Block Sparse Row sparse format version of the matrix comprised of dense blocks of
+// size r * c.  Duplicate elements are summed.  The returned BSR matrix will not share underlying storage
+// with the receiver nor is the receiver modified by this call.  ToBSR will panic if the dimensions of
+// the receiver are not exact multiples of the block dimensions.
+func (c *COO) ToBSR(r, cb int) *BSR {
+	return c.ToCSR().ToBSR(r, cb)
+}
+
+// mulBSRBSR handles CSR = BSR * BSR where the block column size of lhs matches the block row size
+// of rhs.  Products of blocks are accumulated as dense blocks before being gathered into
+// the receiver.
+func (c *CSR) mulBSRBSR(lhs *BSR, rhs *BSR) {
+	a, b := &lhs.matrix, &rhs.matrix
+	ar, ac, bc := a.R, a.C, b.C
+	asize, bsize, csize := ar*ac, b.R*bc, ar*bc
+
+	slot := getInts(b.J, false)
+	for i := range slot {
+		slot[i] = -1
+	}
+	var blockCols []int
+	var acc []float64
+
+	for bi := 0; bi < a.I; bi++ {
+		blockCols = blockCols[:0]
+		for k := a.Indptr[bi]; k < a.Indptr[bi+1]; k++ {
+			kb := a.Ind[k]
+			for t := b.Indptr[kb]; t < b.Indptr[kb+1]; t++ {
+				if slot[b.Ind[t]] < 0 {
+					slot[b.Ind[t]] = len(blockCols)
+					blockCols = append(blockCols, b.Ind[t])
+				}
+			}
+		}
+		acc = useFloats(acc, len(blockCols)*csize, true)
+
+		for k := a.Indptr[bi]; k < a.Indptr[bi+1]; k++ {
+			ablock := a.Data[k*asize : (k+1)*asize]
+			kb := a.Ind[k]
+			for t := b.Indptr[kb]; t < b.Indptr[kb+1]; t++ {
+				bblock := b.Data[t*bsize : (t+1)*bsize]
+				cblock := acc[slot[b.Ind[t]]*csize : (slot[b.Ind[t]]+1)*csize]
+				for ii := 0; ii < ar; ii++ {
+					for kk := 0; kk < ac; kk++ {
+						av := ablock[ii*ac+kk]
+						if av == 0 {
+							continue
+						}
+						brow := bblock[kk*bc : (kk+1)*bc]
+						crow := cblock[ii*bc : (ii+1)*bc]
+						for jj, bv := range brow {
+							crow[jj] += av * bv
+						}
+					}
+				}
+			}
+		}
+
+		for ii := 0; ii < ar; ii++ {
+			for n, bj := range blockCols {
+				crow := acc[n*csize+ii*bc : n*csize+(ii+1)*bc]
+				for jj, v := range crow {
+					if v != 0 {
+						c.matrix.Ind = append(c.matrix.Ind, bj*bc+jj)
+						c.matrix.Data = append(c.matrix.Data, v)
+					}
+				}
+			}
+			c.matrix.Indptr[bi*ar+ii+1] = len(c.matrix.Ind)
+		}
+
+		for _, bj := range blockCols {
+			slot[bj] = -1
+		}
+	}
+	putInts(slot)
+}
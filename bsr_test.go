@@ -0,0 +1,194 @@
+package sparse
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/james-bowman/sparse/blas"
+	"gonum.org/v1/gonum/mat"
+)
+
+// randomData returns the elements of a random r * c dense matrix in row major
+// order with approximately the specified density of non-zero values.
+func randomData(r, c int, density float64) []float64 {
+	data := make([]float64, r*c)
+	for i := range data {
+		if rand.Float64() < density {
+			data[i] = rand.Float64()
+		}
+	}
+	return data
+}
+
+func TestBSRConversion(t *testing.T) {
+	var tests = []struct {
+		r, c   int
+		br, bc int
+		data   []float64
+		nnzb   int
+	}{
+		{
+			r: 4, c: 6,
+			br: 2, bc: 2,
+			data: []float64{
+				1, 2, 0, 0, 0, 3,
+				4, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 5, 6,
+				0, 0, 0, 0, 7, 8,
+			},
+			nnzb: 3,
+		},
+		{
+			r: 6, c: 3,
+			br: 3, bc: 3,
+			data: []float64{
+				1, 2, 3,
+				4, 5, 6,
+				7, 8, 9,
+				0, 0, 0,
+				0, 0, 0,
+				0, 1, 0,
+			},
+			nnzb: 2,
+		},
+		{
+			r: 3, c: 4,
+			br: 1, bc: 2,
+			data: []float64{
+				0, 0, 0, 1,
+				0, 2, 0, 0,
+				3, 0, 0, 4,
+			},
+			nnzb: 4,
+		},
+	}
+
+	for ti, test := range tests {
+		expected := mat.NewDense(test.r, test.c, test.data)
+		csr := CreateCSR(test.r, test.c, test.data).(*CSR)
+
+		bsr := csr.ToBSR(test.br, test.bc)
+		if bsr.NNZB() != test.nnzb {
+			t.Errorf("Test %d: expected %d blocks but found %d", ti, test.nnzb, bsr.NNZB())
+		}
+		if bsr.NNZ() != test.nnzb*test.br*test.bc {
+			t.Errorf("Test %d: expected %d stored elements but found %d", ti, test.nnzb*test.br*test.bc, bsr.NNZ())
+		}
+		if !mat.Equal(expected, bsr) {
+			t.Errorf("Test %d: expected\n%v\nbut received\n%v", ti, mat.Formatted(expected), mat.Formatted(bsr))
+		}
+
+		for name, m := range map[string]mat.Matrix{
+			"ToDense":  bsr.ToDense(),
+			"ToDOK":    bsr.ToDOK(),
+			"ToCOO":    bsr.ToCOO(),
+			"ToCSR":    bsr.ToCSR(),
+			"ToCSC":    bsr.ToCSC(),
+			"COOToBSR": CreateCOO(test.r, test.c, test.data).(*COO).ToBSR(test.br, test.bc),
+			"ToType":   csr.ToType(BSRFormat(test.br, test.bc)),
+		} {
+			if !mat.Equal(expected, m) {
+				t.Errorf("Test %d (%s): expected\n%v\nbut received\n%v", ti, name, mat.Formatted(expected), mat.Formatted(m))
+			}
+		}
+
+		if bsr.ToCSR().NNZ() != csr.NNZ() {
+			t.Errorf("Test %d: expected %d non zeros after round trip but found %d", ti, csr.NNZ(), bsr.ToCSR().NNZ())
+		}
+
+		var nnz int
+		bsr.DoNonZero(func(i, j int, v float64) {
+			if v == 0 || v != expected.At(i, j) {
+				t.Errorf("Test %d: unexpected value %f at (%d, %d)", ti, v, i, j)
+			}
+			nnz++
+		})
+		if nnz != csr.NNZ() {
+			t.Errorf("Test %d: expected to visit %d non zeros but visited %d", ti, csr.NNZ(), nnz)
+		}
+
+		if !mat.Equal(expected.T(), bsr.T()) {
+			t.Errorf("Test %d: expected transpose\n%v\nbut received\n%v", ti, mat.Formatted(expected.T()), mat.Formatted(bsr.T()))
+		}
+		var trace float64
+		for i := 0; i < test.r && i < test.c; i++ {
+			trace += expected.At(i, i)
+		}
+		if bsr.Trace() != trace {
+			t.Errorf("Test %d: expected trace %f but received %f", ti, trace, bsr.Trace())
+		}
+	}
+}
+
+func TestBSRMulVecTo(t *testing.T) {
+	for _, size := range []struct{ r, c, br, bc int }{
+		{r: 6, c: 6, br: 3, bc: 3},
+		{r: 8, c: 6, br: 2, bc: 3},
+		{r: 30, c: 40, br: 3, bc: 2},
+	} {
+		csr := CreateCSR(size.r, size.c, randomData(size.r, size.c, 0.3)).(*CSR)
+		bsr := csr.ToBSR(size.br, size.bc)
+
+		for _, trans := range []bool{false, true} {
+			r, c := size.r, size.c
+			if trans {
+				r, c = c, r
+			}
+			x := make([]float64, c)
+			for i := range x {
+				x[i] = float64(i + 1)
+			}
+			want := make([]float64, r)
+			got := make([]float64, r)
+			for i := range want {
+				want[i] = 1
+				got[i] = 1
+			}
+			csr.MulVecTo(want, trans, x)
+			bsr.MulVecTo(got, trans, x)
+
+			if !mat.EqualApprox(mat.NewVecDense(r, want), mat.NewVecDense(r, got), 1e-12) {
+				t.Errorf("MulVecTo mismatch (trans=%t) for %v: expected %v but received %v", trans, size, want, got)
+			}
+		}
+
+		x := mat.NewVecDense(size.c, nil)
+		for i := 0; i < size.c; i++ {
+			x.SetVec(i, float64(i))
+		}
+		var want mat.VecDense
+		want.MulVec(csr, x)
+		got := make([]float64, size.r)
+		blas.Dbsrmv(false, 1, bsr.RawBlockMatrix(), x.RawVector().Data, 1, got, 1)
+		if !mat.EqualApprox(&want, mat.NewVecDense(size.r, got), 1e-12) {
+			t.Errorf("Dbsrmv mismatch for %v", size)
+		}
+	}
+}
+
+func TestBSRMul(t *testing.T) {
+	var tests = []struct {
+		ar, ac, abr, abc int
+		bc, bbr, bbc     int
+	}{
+		{ar: 6, ac: 6, abr: 3, abc: 3, bc: 6, bbr: 3, bbc: 3},
+		{ar: 4, ac: 6, abr: 2, abc: 3, bc: 8, bbr: 3, bbc: 4},
+		// incompatible blocks fall back to generic multiplication
+		{ar: 4, ac: 6, abr: 2, abc: 2, bc: 6, bbr: 3, bbc: 3},
+	}
+
+	for ti, test := range tests {
+		a := CreateCSR(test.ar, test.ac, randomData(test.ar, test.ac, 0.4)).(*CSR)
+		b := CreateCSR(test.ac, test.bc, randomData(test.ac, test.bc, 0.4)).(*CSR)
+
+		var want mat.Dense
+		want.Mul(a, b)
+
+		var got CSR
+		got.Mul(a.ToBSR(test.abr, test.abc), b.ToBSR(test.bbr, test.bbc))
+
+		if !mat.EqualApprox(&want, &got, 1e-12) {
+			t.Errorf("Test %d: expected\n%v\nbut received\n%v", ti, mat.Formatted(&want), mat.Formatted(&got))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package sparse
+
+import (
+	"context"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+// CG solves the system of linear equations A * x = b for x using the Conjugate
+// Gradient method where A is a symmetric positive definite matrix (or operator).
+// If a preconditioner is specified in the settings it is applied i.e. CG is
+// equivalent to PCG.  settings may be nil in which case the default settings are
+// used.
+//
+// The result is returned along with a nil error if the solver converged to the
+// required tolerance.  Otherwise the result holds the best solution found, its
+// Status records the outcome and the error is a *NotConvergedError if the maximum
+// number of iterations was reached, a *BreakdownError if A (or the preconditioner)
+// is not positive definite or the error from ctx if the context was cancelled.
+func CG(ctx context.Context, a MulVecToer, b []float64, settings *SolverSettings) (*SolverResult, error) {
+	n := len(b)
+	r := make([]float64, n)
+	state := newIterativeState(ctx, a, b, settings, r)
+	x := state.result.X
+	if state.converged(0, floats.Norm(r, 2)) {
+		return state.result, nil
+	}
+
+	z := make([]float64, n)
+	p := make([]float64, n)
+	ap := make([]float64, n)
+	state.precondition(z, r)
+	copy(p, z)
+	rz := floats.Dot(r, z)
+
+	for k := 1; ; k++ {
+		if err := state.next(k); err != nil {
+			return state.result, err
+		}
+
+		zero(ap)
+		a.MulVecTo(ap, false, p)
+		pAp := floats.Dot(p, ap)
+		if !(pAp > 0) {
+			return state.result, state.breakdown(k, "matrix is not positive definite (p^T * A * p <= 0)")
+		}
+		alpha := rz / pAp
+		floats.AddScaled(x, alpha, p)
+		floats.AddScaled(r, -alpha, ap)
+		if state.converged(k, floats.Norm(r, 2)) {
+			return state.result, nil
+		}
+
+		state.precondition(z, r)
+		rzNext := floats.Dot(r, z)
+		if !(rzNext > 0) {
+			return state.result, state.breakdown(k, "preconditioner is not positive definite (r^T * M^-1 * r <= 0)")
+		}
+		beta := rzNext / rz
+		rz = rzNext
+		floats.AddScaledTo(p, z, beta, p)
+	}
+}
+
+// PCG solves the system of linear equations A * x = b for x using the Preconditioned
+// Conjugate Gradient method where A is a symmetric positive definite matrix (or
+// operator) and m is a symmetric positive definite preconditioner approximating A.
+// m overrides any preconditioner specified in the settings.  See CG for details of
+// the settings, result and errors.
+func PCG(ctx context.Context, a MulVecToer, b []float64, m Preconditioner, settings *SolverSettings) (*SolverResult, error) {
+	var s SolverSettings
+	if settings != nil {
+		s = *settings
+	}
+	s.Preconditioner = m
+	return CG(ctx, a, b, &s)
+}
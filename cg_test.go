@@ -0,0 +1,158 @@
+package sparse
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// diagonalPreconditioner is a Jacobi (diagonal scaling) preconditioner used for testing.
+type diagonalPreconditioner []float64
+
+func (d diagonalPreconditioner) Apply(dst, src []float64) {
+	for i, v := range src {
+		dst[i] = v / d[i]
+	}
+}
+
+// residual returns the relative residual ||b - A*x|| / ||b||.
+func residual(a MulVecToer, b, x []float64) float64 {
+	r := make([]float64, len(b))
+	a.MulVecTo(r, false, x)
+	floats.Sub(r, b)
+	return floats.Norm(r, 2) / floats.Norm(b, 2)
+}
+
+func TestCG(t *testing.T) {
+	a := laplacian2D(20, 20)
+	n, _ := a.Dims()
+	b := randomData(n, 1, 1)
+
+	// scale the rows/cols so that diagonal preconditioning is beneficial
+	scale := make([]float64, n)
+	for i := range scale {
+		scale[i] = 1 + float64(i%7)
+	}
+	dok := NewDOK(n, n)
+	a.DoNonZero(func(i, j int, v float64) {
+		dok.Set(i, j, scale[i]*v*scale[j])
+	})
+	scaled := dok.ToCSR()
+	diag := make(diagonalPreconditioner, n)
+	for i := range diag {
+		diag[i] = scaled.At(i, i)
+	}
+
+	tests := []struct {
+		name  string
+		solve func(settings *SolverSettings) (*SolverResult, error)
+	}{
+		{
+			name: "CG",
+			solve: func(settings *SolverSettings) (*SolverResult, error) {
+				return CG(context.Background(), scaled, b, settings)
+			},
+		},
+		{
+			name: "PCG",
+			solve: func(settings *SolverSettings) (*SolverResult, error) {
+				return PCG(context.Background(), scaled, b, diag, settings)
+			},
+		},
+		{
+			name: "CG with preconditioner in settings",
+			solve: func(settings *SolverSettings) (*SolverResult, error) {
+				s := *settings
+				s.Preconditioner = diag
+				return CG(context.Background(), scaled, b, &s)
+			},
+		},
+	}
+
+	iterations := make(map[string]int)
+	for _, test := range tests {
+		result, err := test.solve(&SolverSettings{Tolerance: 1e-10})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if result.Status != Converged {
+			t.Errorf("%s: expected status Converged but was %v", test.name, result.Status)
+		}
+		if res := residual(scaled, b, result.X); res > 1e-9 {
+			t.Errorf("%s: expected relative residual <= 1e-9 but was %g", test.name, res)
+		}
+		if len(result.History) != result.Iterations+1 {
+			t.Errorf("%s: expected %d history entries but received %d", test.name, result.Iterations+1, len(result.History))
+		}
+		if result.History[0] != 1 {
+			t.Errorf("%s: expected initial relative residual of 1 but was %g", test.name, result.History[0])
+		}
+		if result.Residual != result.History[len(result.History)-1] {
+			t.Errorf("%s: residual %g does not match final history entry %g", test.name, result.Residual, result.History[len(result.History)-1])
+		}
+		iterations[test.name] = result.Iterations
+	}
+	if iterations["PCG"] >= iterations["CG"] {
+		t.Errorf("expected preconditioning to reduce iterations but PCG took %d and CG took %d", iterations["PCG"], iterations["CG"])
+	}
+
+	// starting from the solution should converge immediately
+	var want mat.VecDense
+	if err := want.SolveVec(scaled.ToDense(), mat.NewVecDense(n, b)); err != nil {
+		t.Fatalf("unexpected error from mat.VecDense.SolveVec %v", err)
+	}
+	result, err := CG(context.Background(), scaled, b, &SolverSettings{InitX: want.RawVector().Data})
+	if err != nil {
+		t.Errorf("unexpected error from initial solution %v", err)
+	}
+	if result.Iterations != 0 {
+		t.Errorf("expected 0 iterations from initial solution but took %d", result.Iterations)
+	}
+
+	// zero right hand side
+	result, err = CG(context.Background(), scaled, make([]float64, n), nil)
+	if err != nil || floats.Norm(result.X, 2) != 0 {
+		t.Errorf("expected zero solution for zero right hand side but received %v (error %v)", result.X, err)
+	}
+}
+
+func TestCGErrors(t *testing.T) {
+	a := laplacian2D(10, 10)
+	n, _ := a.Dims()
+	b := randomData(n, 1, 1)
+
+	result, err := CG(context.Background(), a, b, &SolverSettings{MaxIterations: 3})
+	var notConverged *NotConvergedError
+	if !errors.As(err, &notConverged) {
+		t.Fatalf("expected *NotConvergedError but received %v", err)
+	}
+	if result.Status != NotConverged {
+		t.Errorf("expected status NotConverged but was %v", result.Status)
+	}
+	if result.Iterations != 3 || notConverged.Iterations != 3 {
+		t.Errorf("expected 3 iterations but result reported %d and error reported %d", result.Iterations, notConverged.Iterations)
+	}
+	if notConverged.Residual != result.Residual {
+		t.Errorf("expected error residual %g to match result residual %g", notConverged.Residual, result.Residual)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = CG(ctx, a, b, nil)
+	if !errors.Is(err, context.Canceled) || result.Status != Cancelled {
+		t.Errorf("expected context.Canceled and status Cancelled but received %v and %v", err, result.Status)
+	}
+
+	indefinite := CreateCSR(2, 2, []float64{
+		1, 0,
+		0, -1,
+	}).(*CSR)
+	result, err = CG(context.Background(), indefinite, []float64{1, 1}, nil)
+	var breakdown *BreakdownError
+	if !errors.As(err, &breakdown) || result.Status != Breakdown {
+		t.Errorf("expected *BreakdownError and status Breakdown but received %v and %v", err, result.Status)
+	}
+}
@@ -1,20 +1,102 @@
 package sparse
 
 import (
+	"errors"
+	"fmt"
 	"math"
+	"sort"
 
-	"gonum.org/v1/gonum/floats"
+	"github.com/james-bowman/sparse/blas"
 	"gonum.org/v1/gonum/mat"
 )
 
 // Cholesky shadows the gonum mat.Cholesly type
 type Cholesky struct {
-	// internal representation is CSR in lower triangular form
-	chol *CSR
+	// internal representation is CSC in lower triangular form with the
+	// diagonal stored as the first element of each column
+	chol *CSC
+	cond float64
 
-	// some operations use a columnar version
-	cholc *CSC
-	cond  float64
+	// err is the error from the last factorisation, if any
+	err error
+
+	// perm is the fill reducing permutation applied to the matrix before
+	// factorising i.e. the factorised matrix is P * A * P^T.  pinv is the
+	// inverse permutation.  Both are nil for the natural order.
+	perm []int
+	pinv []int
+
+	// sym is the result of the symbolic analysis shared by all matrices
+	// with the same sparsity pattern
+	sym *cholSymbolic
+}
+
+// NotPositiveDefiniteError is the error returned when attempting to factorise a
+// matrix that is not symmetric positive definite.  It may be matched against
+// mat.ErrNotPSD with errors.Is.
+type NotPositiveDefiniteError struct {
+	// Index is the row (and column) of the original (unpermuted) matrix at which
+	// the factorisation broke down.
+	Index int
+
+	// Pivot is the non-positive (or non-finite) value encountered for the
+	// diagonal element of L squared.
+	Pivot float64
+}
+
+// Error implements the error interface.
+func (e *NotPositiveDefiniteError) Error() string {
+	return fmt.Sprintf("sparse: matrix is not positive definite at row %d (pivot %g)", e.Index, e.Pivot)
+}
+
+// Is allows NotPositiveDefiniteError to be matched against mat.ErrNotPSD with
+// errors.Is.
+func (e *NotPositiveDefiniteError) Is(target error) bool {
+	return target == mat.ErrNotPSD
+}
+
+// ErrPatternMismatch is returned when refactorising a matrix whose sparsity
+// pattern does not match the pattern of the originally factorised matrix.
+var ErrPatternMismatch = errors.New("sparse: sparsity pattern does not match symbolic factorisation")
+
+// cholSymbolic is the result of the symbolic analysis of a Cholesky
+// factorisation i.e. the structure of the factor L of P * A * P^T which
+// depends only on the sparsity pattern of A.
+type cholSymbolic struct {
+	n int
+
+	// parent is the elimination tree with roots having a parent of -1
+	parent []int
+
+	// colCounts holds the number of non-zeros in each column of L
+	colCounts []int
+
+	// indptr and ind are the row pattern of L in CSR form.  Column indices
+	// within each row are sorted so the diagonal is the last entry of each row.
+	indptr []int
+	ind    []int
+
+	// colptr and rowind are the column pattern of L in CSC form.  Row indices
+	// within each column are sorted so the diagonal is the first entry of each
+	// column.
+	colptr []int
+	rowind []int
+}
+
+// CholeskyOption is an optional setting for Cholesky factorisation.
+type CholeskyOption func(*choleskySettings)
+
+type choleskySettings struct {
+	ordering Ordering
+}
+
+// WithOrdering returns a CholeskyOption specifying the fill reducing ordering
+// applied to the matrix before factorising.  By default, the matrix is factorised
+// in its natural order.
+func WithOrdering(o Ordering) CholeskyOption {
+	return func(s *choleskySettings) {
+		s.ordering = o
+	}
 }
 
 // Dims of the matrix
@@ -37,31 +119,36 @@ func min(a, b int) int {
 
 // At from the matrix
 func (ch *Cholesky) At(i, j int) float64 {
+	if ch.pinv != nil {
+		i, j = ch.pinv[i], ch.pinv[j]
+	}
+	if i > j {
+		i, j = j, i
+	}
+	// A[i,j] is the dot product of rows i and j of L where the non-zero
+	// elements of row i are given by the symbolic row pattern
 	var val float64
-	ri := ch.chol.RowView(i).(*Vector)
-	rj := ch.chol.RowView(j).(*Vector)
-	// FIXME: check types
-	val = dotSparseSparseNoSortBefore(ri, rj, nil, min(i, j)+1)
+	for _, k := range ch.sym.ind[ch.sym.indptr[i]:ch.sym.indptr[i+1]] {
+		val += ch.element(i, k) * ch.element(j, k)
+	}
 	return val
 }
 
+// element returns L[i,j] by searching column j of L
+func (ch *Cholesky) element(i, j int) float64 {
+	l := &ch.chol.matrix
+	rows := l.Ind[l.Indptr[j]:l.Indptr[j+1]]
+	if k := sort.SearchInts(rows, i); k < len(rows) && rows[k] == i {
+		return l.Data[l.Indptr[j]+k]
+	}
+	return 0
+}
+
 // T is the same as symmetric
 func (ch *Cholesky) T() mat.Matrix {
 	return ch
 }
 
-func newCSR(r, c int) *CSR {
-	// FIXME: creating a CSR directly leads to panics
-	coo := NewCOO(r, c, nil, nil, nil)
-	return coo.ToCSR()
-}
-
-func newCSC(r, c int) *CSC {
-	// FIXME: creating a CSC directly leads to panics
-	coo := NewCOO(r, c, nil, nil, nil)
-	return coo.ToCSC()
-}
-
 // Det returns the determinant of the factored matrix
 func (ch *Cholesky) Det() float64 {
 	return math.Exp(ch.LogDet())
@@ -69,26 +156,512 @@ func (ch *Cholesky) Det() float64 {
 
 // LogDet returns ln(determinant) of the factored matrix
 func (ch *Cholesky) LogDet() float64 {
+	l := &ch.chol.matrix
 	det := 0.0
-	for i := 0; i < ch.Symmetric(); i++ {
-		det += 2 * math.Log(ch.chol.At(i, i))
+	for j := 0; j < ch.Symmetric(); j++ {
+		det += 2 * math.Log(l.Data[l.Indptr[j]])
 	}
 	return det
 }
 
 // Factorize a CSR
 // the CSR must be symmetric positive-definite or this won't work
-// FIXME: enforce sym positive definite
-func (ch *Cholesky) Factorize(a *CSR) {
+// options may be supplied e.g. to specify a fill reducing ordering
+// both the symbolic analysis of the sparsity pattern and the numeric factorisation
+// are performed - matrices sharing the sparsity pattern may then be factorised
+// more cheaply using Refactorize
+// if a is not positive definite a *NotPositiveDefiniteError is returned and the
+// factorisation may not be used
+func (ch *Cholesky) Factorize(a *CSR, opts ...CholeskyOption) error {
 	r, c := a.Dims()
 	if r != c {
 		panic(mat.ErrShape)
 	}
-	ch.chol = newCSR(r, c)
-	cholCSR(a, ch.chol)
+	var settings choleskySettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	ch.perm, ch.pinv = nil, nil
+	if settings.ordering != nil {
+		ch.perm = settings.ordering.Permutation(a)
+		ch.pinv = make([]int, r)
+		for i, p := range ch.perm {
+			ch.pinv[p] = i
+		}
+	}
+	ch.sym = analyseCholesky(a, ch.perm)
+	ch.chol = NewCSC(r, c, ch.sym.colptr, ch.sym.rowind, make([]float64, len(ch.sym.rowind)))
+	return ch.factorize(a)
+}
+
+// Refactorize factorises a reusing the symbolic analysis (ordering, elimination tree
+// and sparsity pattern of L) from the last call to Factorize.  This avoids recomputing
+// the structure of the factor when factorising many matrices that share a sparsity
+// pattern but differ in their values.  The sparsity pattern of a must match that of
+// the originally factorised matrix (or a subset of it), otherwise ErrPatternMismatch
+// is returned and the receiver is left unchanged.  If a is not positive definite a
+// *NotPositiveDefiniteError is returned and the factorisation may not be used.
+func (ch *Cholesky) Refactorize(a *CSR) error {
+	if ch.sym == nil {
+		panic("sparse: Refactorize called before Factorize")
+	}
+	r, c := a.Dims()
+	if r != ch.sym.n || c != ch.sym.n {
+		panic(mat.ErrShape)
+	}
+	if !ch.sym.matches(a, ch.pinv) {
+		return ErrPatternMismatch
+	}
+	return ch.factorize(a)
+}
+
+// EliminationTree returns the elimination tree of the factorised matrix
+// P * A * P^T where the parent of node i is parent[i] and roots have a
+// parent of -1.
+func (ch *Cholesky) EliminationTree() []int {
+	parent := make([]int, len(ch.sym.parent))
+	copy(parent, ch.sym.parent)
+	return parent
+}
+
+// analyseCholesky performs the symbolic analysis of the Cholesky factorisation of
+// P * A * P^T where perm is the permutation P (nil for the natural order).
+func analyseCholesky(a *CSR, perm []int) *cholSymbolic {
+	n := orderingDims(a)
+	ptr, adj := symmetricGraph(a)
+	parent, pinv := etree(n, ptr, adj, perm)
+
+	sym := &cholSymbolic{
+		n:         n,
+		parent:    parent,
+		colCounts: make([]int, n),
+		indptr:    make([]int, n+1),
+		ind:       make([]int, 0, n+len(adj)),
+	}
+	mark := make([]int, n)
+	for i := 0; i < n; i++ {
+		mark[i] = i
+		v := i
+		if perm != nil {
+			v = perm[i]
+		}
+		// the pattern of row i of L is the union of the paths in the elimination
+		// tree from each k < i adjacent to i up to i
+		start := len(sym.ind)
+		for _, w := range adj[ptr[v]:ptr[v+1]] {
+			for k := pinv[w]; k < i && mark[k] != i; k = parent[k] {
+				mark[k] = i
+				sym.ind = append(sym.ind, k)
+				sym.colCounts[k]++
+			}
+		}
+		sort.Ints(sym.ind[start:])
+		sym.ind = append(sym.ind, i)
+		sym.colCounts[i]++
+		sym.indptr[i+1] = len(sym.ind)
+	}
+
+	// transpose the row pattern into the column pattern
+	sym.colptr = make([]int, n+1)
+	for j, c := range sym.colCounts {
+		sym.colptr[j+1] = sym.colptr[j] + c
+	}
+	next := make([]int, n)
+	copy(next, sym.colptr[:n])
+	sym.rowind = make([]int, len(sym.ind))
+	for i := 0; i < n; i++ {
+		for _, j := range sym.ind[sym.indptr[i]:sym.indptr[i+1]] {
+			sym.rowind[next[j]] = i
+			next[j]++
+		}
+	}
+	return sym
+}
+
+// matches returns true if every non-zero element of a, permuted by pinv (nil for
+// the natural order), lies within the sparsity pattern of L or L^T.
+func (sym *cholSymbolic) matches(a *CSR, pinv []int) bool {
+	m := &a.matrix
+	for r := 0; r < sym.n; r++ {
+		for _, c := range m.Ind[m.Indptr[r]:m.Indptr[r+1]] {
+			i, j := r, c
+			if pinv != nil {
+				i, j = pinv[r], pinv[c]
+			}
+			if i < j {
+				i, j = j, i
+			}
+			row := sym.ind[sym.indptr[i]:sym.indptr[i+1]]
+			if k := sort.SearchInts(row, j); k == len(row) || row[k] != j {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// factorize performs the numeric phase of the factorisation of a into the
+// preallocated column pattern of L computed during the symbolic analysis.  L is
+// computed a column at a time (left-looking) as
+//
+//	L[j:n,j] = (A[j:n,j] - L[j:n,0:j] * L[j,0:j]^T) / L[j,j]
+//
+// where the columns k < j contributing to column j are exactly those in the
+// non-zero pattern of row j of L from the symbolic analysis.  As columns are
+// computed in order, the elements of each earlier column k with row index >= j
+// are contiguous and start at next[k], the first of which is L[j,k].
+func (ch *Cholesky) factorize(a *CSR) error {
+	sym := ch.sym
+	m := &a.matrix
+	lp, li, lx := sym.colptr, sym.rowind, ch.chol.matrix.Data
+	ch.err = nil
+	ch.cond = math.Inf(1)
+
+	// the infinity norm of A (computed from the lower triangle) for the condition
+	// number estimate
+	rowSums := getFloats(sym.n, true)
+	defer putFloats(rowSums)
+
+	// x holds the current column of A (and L) scattered into dense form
+	x := getFloats(sym.n, true)
+	defer putFloats(x)
+
+	// next holds the position within each computed column of the first element
+	// with a row index not less than the current column
+	next := getInts(sym.n, false)
+	defer putInts(next)
+
+	for j := 0; j < sym.n; j++ {
+		// A is symmetric so the lower part of column j of P * A * P^T is taken
+		// from row j of P * A * P^T
+		v := j
+		if ch.perm != nil {
+			v = ch.perm[j]
+		}
+		for p := m.Indptr[v]; p < m.Indptr[v+1]; p++ {
+			i := m.Ind[p]
+			if ch.pinv != nil {
+				i = ch.pinv[i]
+			}
+			if i >= j {
+				x[i] += m.Data[p]
+				rowSums[i] += math.Abs(m.Data[p])
+				if i != j {
+					rowSums[j] += math.Abs(m.Data[p])
+				}
+			}
+		}
+
+		for _, k := range sym.ind[sym.indptr[j] : sym.indptr[j+1]-1] {
+			ljk := lx[next[k]]
+			for p := next[k]; p < lp[k+1]; p++ {
+				x[li[p]] -= lx[p] * ljk
+			}
+			next[k]++
+		}
+
+		d := x[j]
+		x[j] = 0
+		if !(d > 0) {
+			ch.err = &NotPositiveDefiniteError{Index: v, Pivot: d}
+			return ch.err
+		}
+		ljj := math.Sqrt(d)
+		lx[lp[j]] = ljj
+		for p := lp[j] + 1; p < lp[j+1]; p++ {
+			lx[p] = x[li[p]] / ljj
+			x[li[p]] = 0
+		}
+		next[j] = lp[j] + 1
+	}
+
+	var norm float64
+	for _, v := range rowSums {
+		norm = math.Max(norm, v)
+	}
+	ch.updateCond(norm)
+	return nil
+}
+
+// updateCond updates the condition number estimate of the factorised matrix given
+// the infinity norm of A.  As A is symmetric, ||A^-1||_inf = ||A^-1||_1 which is
+// estimated using the factorisation.
+func (ch *Cholesky) updateCond(norm float64) {
+	ch.cond = norm * invNorm1Est(ch.sym.n, func(x []float64, trans bool) {
+		ch.solve(x)
+	})
+}
+
+// Cond returns the condition number of the factorised matrix estimated in the
+// infinity norm.
+func (ch *Cholesky) Cond() float64 {
+	return ch.cond
+}
+
+// Update updates the factorisation in place to that of A + alpha * x * x^T where A is
+// the currently factorised matrix, mirroring mat.Cholesky.SymRankOne.  If alpha is
+// negative this is a downdate.  Only the columns of L along the paths in the
+// elimination tree from the non-zero elements of (P *) x are modified and any
+// fill-in resulting from the non-zero pattern of x is added to the pattern of L.
+// If the update would result in a matrix that is not positive definite (e.g. a
+// downdate removing too much or x containing NaN or Inf values) a
+// *NotPositiveDefiniteError is returned and the factorisation (including the
+// pattern of L) is left unchanged.
+func (ch *Cholesky) Update(alpha float64, x mat.Vector) error {
+	n := ch.Symmetric()
+	if x.Len() != n {
+		panic(mat.ErrShape)
+	}
+	if ch.err != nil {
+		return ch.err
+	}
+	if alpha == 0 {
+		return nil
+	}
+	sign := 1.0
+	if alpha < 0 {
+		sign = -1
+	}
+	scale := math.Sqrt(math.Abs(alpha))
+
+	// w = sqrt(|alpha|) * P * x
+	w := getFloats(n, true)
+	defer putFloats(w)
+	var nz []int
+	set := func(i int, v float64) {
+		if ch.pinv != nil {
+			i = ch.pinv[i]
+		}
+		if v != 0 {
+			w[i] = scale * v
+			nz = append(nz, i)
+		}
+	}
+	if v, ok := x.(*Vector); ok {
+		for k, i := range v.ind {
+			set(i, v.data[k])
+		}
+	} else {
+		for i := 0; i < n; i++ {
+			set(i, x.AtVec(i))
+		}
+	}
+	sort.Ints(nz)
+
+	// the symbolic analysis and factor are saved so that they may be restored if
+	// the update fails.  Any fill-in replaces (rather than modifies) the pattern
+	// and values of L so only the columns modified in place need to be copied.
+	sym, chol := *ch.sym, ch.chol
+	path := ch.updatePattern(nz)
+
+	lp, li, lx := ch.sym.colptr, ch.sym.rowind, ch.chol.matrix.Data
+	var saved [][]float64
+	for s, k := range path {
+		start, end := lp[k], lp[k+1]
+		col := make([]float64, end-start)
+		copy(col, lx[start:end])
+		saved = append(saved, col)
+
+		lkk := lx[start]
+		r2 := lkk*lkk + sign*w[k]*w[k]
+		if !(r2 > 0) || math.IsInf(r2, 1) {
+			// restore the modified columns and the original pattern
+			for t, col := range saved[:s+1] {
+				copy(lx[lp[path[t]]:], col)
+			}
+			*ch.sym, ch.chol = sym, chol
+			index := k
+			if ch.perm != nil {
+				index = ch.perm[k]
+			}
+			return &NotPositiveDefiniteError{Index: index, Pivot: r2}
+		}
+		r := math.Sqrt(r2)
+		c, sn := r/lkk, w[k]/lkk
+		lx[start] = r
+		for p := start + 1; p < end; p++ {
+			i := li[p]
+			lx[p] = (lx[p] + sign*sn*w[i]) / c
+			w[i] = c*w[i] - sn*lx[p]
+		}
+		w[k] = 0
+	}
+
+	// the infinity norm of A = L * L^T is estimated as ||A||_1 as A is symmetric
+	norm := invNorm1Est(n, func(x []float64, trans bool) {
+		ch.mul(x)
+	})
+	ch.updateCond(norm)
+	return nil
+}
+
+// Downdate updates the factorisation in place to that of A - alpha * x * x^T where A
+// is the currently factorised matrix.  Downdate is equivalent to Update(-alpha, x).
+func (ch *Cholesky) Downdate(alpha float64, x mat.Vector) error {
+	return ch.Update(-alpha, x)
+}
+
+// updatePattern extends the pattern of L (and the symbolic analysis) with the fill-in
+// resulting from a rank one update with a vector with non-zero elements at the sorted
+// (permuted) indices nz.  The columns of L modified by the update are returned in
+// ascending order.  The modified columns form the path in the (updated) elimination
+// tree from the first non-zero where the pattern of each column on the path is the
+// union of its existing pattern and the pattern of its predecessor on the path.
+func (ch *Cholesky) updatePattern(nz []int) []int {
+	sym := ch.sym
+	var path []int
+	fill := make(map[int][]int)
+
+	pending := nz
+	for len(pending) > 0 {
+		k := pending[0]
+		path = append(path, k)
+		rows := sym.rowind[sym.colptr[k]+1 : sym.colptr[k+1]]
+
+		// merge the sorted rows of column k with the remaining pending indices
+		merged := make([]int, 0, len(rows)+len(pending)-1)
+		a, b := rows, pending[1:]
+		for len(a) > 0 && len(b) > 0 {
+			switch {
+			case a[0] < b[0]:
+				merged = append(merged, a[0])
+				a = a[1:]
+			case a[0] > b[0]:
+				merged = append(merged, b[0])
+				b = b[1:]
+			default:
+				merged = append(merged, a[0])
+				a, b = a[1:], b[1:]
+			}
+		}
+		merged = append(append(merged, a...), b...)
+		if len(merged) > len(rows) {
+			fill[k] = merged
+		}
+		pending = merged
+	}
+
+	if len(fill) == 0 {
+		return path
+	}
+
+	// rebuild the column pattern of L including the fill-in
+	lp, li, lx := sym.colptr, sym.rowind, ch.chol.matrix.Data
+	colptr := make([]int, sym.n+1)
+	for j := 0; j < sym.n; j++ {
+		count := lp[j+1] - lp[j]
+		if rows, ok := fill[j]; ok {
+			count = len(rows) + 1
+		}
+		colptr[j+1] = colptr[j] + count
+	}
+	rowind := make([]int, colptr[sym.n])
+	data := make([]float64, colptr[sym.n])
+	for j := 0; j < sym.n; j++ {
+		rows, ok := fill[j]
+		if !ok {
+			copy(rowind[colptr[j]:], li[lp[j]:lp[j+1]])
+			copy(data[colptr[j]:], lx[lp[j]:lp[j+1]])
+			continue
+		}
+		rowind[colptr[j]] = j
+		data[colptr[j]] = lx[lp[j]]
+		copy(rowind[colptr[j]+1:], rows)
+		// copy the existing values into their new positions
+		q := colptr[j] + 1
+		for p := lp[j] + 1; p < lp[j+1]; p++ {
+			for rowind[q] != li[p] {
+				q++
+			}
+			data[q] = lx[p]
+		}
+	}
+	sym.setColumnPattern(colptr, rowind)
+	ch.chol = NewCSC(sym.n, sym.n, colptr, rowind, data)
+	return path
+}
+
+// setColumnPattern sets the column pattern of L and recomputes the elimination
+// tree, column counts and row pattern from it.
+func (sym *cholSymbolic) setColumnPattern(colptr, rowind []int) {
+	sym.colptr, sym.rowind = colptr, rowind
+	sym.colCounts = make([]int, sym.n)
+	sym.parent = make([]int, sym.n)
+	sym.indptr = make([]int, sym.n+1)
+	for j := 0; j < sym.n; j++ {
+		sym.colCounts[j] = colptr[j+1] - colptr[j]
+		sym.parent[j] = -1
+		if sym.colCounts[j] > 1 {
+			sym.parent[j] = rowind[colptr[j]+1]
+		}
+		for _, i := range rowind[colptr[j]:colptr[j+1]] {
+			sym.indptr[i+1]++
+		}
+	}
+	for i := 0; i < sym.n; i++ {
+		sym.indptr[i+1] += sym.indptr[i]
+	}
+	next := make([]int, sym.n)
+	copy(next, sym.indptr[:sym.n])
+	sym.ind = make([]int, len(rowind))
+	for j := 0; j < sym.n; j++ {
+		for _, i := range rowind[colptr[j]:colptr[j+1]] {
+			sym.ind[next[i]] = j
+			next[i]++
+		}
+	}
+}
+
+// mul computes x = L * L^T * x in place
+func (ch *Cholesky) mul(x []float64) {
+	l := &ch.chol.matrix
+	y := getFloats(len(x), false)
+	defer putFloats(y)
+	for j := range y {
+		var v float64
+		for p := l.Indptr[j]; p < l.Indptr[j+1]; p++ {
+			v += l.Data[p] * x[l.Ind[p]]
+		}
+		y[j] = v
+	}
+	for i := range x {
+		x[i] = 0
+	}
+	for j, v := range y {
+		for p := l.Indptr[j]; p < l.Indptr[j+1]; p++ {
+			x[l.Ind[p]] += l.Data[p] * v
+		}
+	}
+}
+
+// Permutation returns the fill reducing permutation applied to the matrix before
+// factorising such that row (and column) i of the factorised matrix P * A * P^T is
+// row (and column) perm[i] of A.  If no ordering was specified, the identity
+// permutation is returned.
+func (ch *Cholesky) Permutation() []int {
+	if ch.perm == nil {
+		r := ch.Symmetric()
+		perm := make([]int, r)
+		for i := range perm {
+			perm[i] = i
+		}
+		return perm
+	}
+	perm := make([]int, len(ch.perm))
+	copy(perm, ch.perm)
+	return perm
+}
+
+// FactorNNZ returns the number of non-zero elements stored in the Cholesky factor
+// L including the diagonal.  This reflects the fill-in resulting from the ordering
+// used for the factorisation.
+func (ch *Cholesky) FactorNNZ() int {
+	return ch.chol.NNZ()
 }
 
 // LTo returns the factored matrix in lower-triangular form as a CSR
+// if an ordering was used, L is the factor of the permuted matrix P * A * P^T
 func (ch *Cholesky) LTo(dst *CSR) {
 	r, c := ch.chol.Dims()
 	rDst, cDst := dst.Dims()
@@ -100,83 +673,92 @@ func (ch *Cholesky) LTo(dst *CSR) {
 	})
 }
 
-func (ch *Cholesky) buildCholC() {
-	if ch.cholc == nil {
-		r := ch.Symmetric()
-		ch.cholc = newCSC(r, r)
-		ch.chol.DoNonZero(func(i, j int, v float64) {
-			ch.cholc.Set(i, j, v)
-		})
-	}
-}
-
 // SolveVecTo shadows Cholesky.SolveVecTo
 // dst is Dense as this doesn't make any sense with sparse solutions
+// if the factorisation failed, the error from the factorisation is returned and if
+// the matrix is ill-conditioned a mat.Condition error is returned along with the
+// solution
 func (ch *Cholesky) SolveVecTo(dst *mat.VecDense, b mat.Vector) error {
 	r := ch.Symmetric()
 	dstLen := dst.Len()
 	if r != dstLen {
 		panic(mat.ErrShape)
 	}
+	if ch.err != nil {
+		return ch.err
+	}
+	ch.solveVecTo(dst, b)
+	if ch.cond > mat.ConditionTolerance {
+		return mat.Condition(ch.cond)
+	}
+	return nil
+}
+
+// solveVecTo solves A * x = b placing the result in dst
+func (ch *Cholesky) solveVecTo(dst *mat.VecDense, b mat.Vector) {
+	r := ch.Symmetric()
 
-	// we are going to need to scan down columns too
-	ch.buildCholC()
+	// if an ordering was used solve L * L^T * (P * x) = P * b
+	x := getFloats(r, false)
+	defer putFloats(x)
+	for i := range x {
+		p := i
+		if ch.perm != nil {
+			p = ch.perm[i]
+		}
+		x[i] = b.AtVec(p)
+	}
+	ch.solve(x)
+	for i, v := range x {
+		p := i
+		if ch.perm != nil {
+			p = ch.perm[i]
+		}
+		dst.SetVec(p, v)
+	}
+}
 
-	// textbook setup and approach:
-	// Ax=b
-	// LLtx=b
-	// L is ch.Chol
+// solve solves L * L^T * x = b in place by forward and backward substitution
+// where x initially contains b
+func (ch *Cholesky) solve(x []float64) {
+	// L is stored in CSC form so the underlying compressed matrix is the upper
+	// triangular L^T
+	l := &ch.chol.matrix
 
 	// forward substitute
 	// Ly=b
-	y := mat.NewVecDense(r, nil)
-	for i := 0; i < r; i++ {
-		denom := ch.chol.At(i, i)
-		k := b.AtVec(i)
-		sum := 0.0
-		ch.chol.DoRowNonZero(i, func(x, z int, v float64) {
-			if z < i {
-				sum += y.AtVec(z) * v
-			}
-		})
-		y.SetVec(i, (k-sum)/denom)
-	}
+	blas.Dussv(true, false, 1, l, x, 1)
 
 	// backward substitute
 	// Lt x=y
-	for i := r - 1; i >= 0; i-- {
-		denom := ch.chol.At(i, i)
-		k := y.AtVec(i)
-		sum := 0.0
-		ch.cholc.DoColNonZero(i, func(x, z int, v float64) {
-			if x > i {
-				sum += dst.AtVec(x) * v
-			}
-		})
-		dst.SetVec(i, (k-sum)/denom)
-	}
-
-	return nil
+	blas.Dussv(false, false, 1, l, x, 1)
 }
 
 // SolveTo goes column-by-column and applies SolveVecTo
+// errors are returned as for SolveVecTo
 func (ch *Cholesky) SolveTo(dst *mat.Dense, b mat.Matrix) error {
 	rows, cols := b.Dims()
 	n := ch.Symmetric()
 	if dst.IsEmpty() {
 		dst.ReuseAs(n, cols)
 	}
+	if ch.err != nil {
+		return ch.err
+	}
 	bv, bHasColView := b.(mat.ColViewer)
 	for c := 0; c < cols; c++ {
 		dstView := dst.ColView(c).(*mat.VecDense)
 		if bHasColView {
 			cv := bv.ColView(c)
-			ch.SolveVecTo(dstView, cv)
+			ch.solveVecTo(dstView, cv)
 		} else {
-			cv := mat.NewVecDense(rows, nil)
-			ch.SolveVecTo(dstView, cv)
+			cv := mat.NewVecDense(rows, mat.Col(nil, c, b))
+			ch.solveVecTo(dstView, cv)
 		}
 	}
+	if ch.cond > mat.ConditionTolerance {
+		return mat.Condition(ch.cond)
+	}
 	return nil
 }
 
@@ -202,37 +784,3 @@ func cholSimple(matrix mat.Matrix, lower *mat.TriDense) {
 		}
 	}
 }
-
-// the core sparse factoring algo
-// this is simply the textbook "dot product" algo using a sparse dot
-func cholCSR(matrix *CSR, lower *CSR) {
-	r, _ := matrix.Dims()
-
-	for i := 0; i < r; i++ {
-		if matrix.RowNNZ(i) == 0 {
-			continue
-		}
-		for j := 0; j <= i; j++ {
-			iRow := lower.RowView(i)
-			iRowS, iRowIsSparse := iRow.(*Vector)
-			jRow := lower.RowView(j)
-			jRowS, jRowIsSparse := jRow.(*Vector)
-			if !iRowIsSparse || !jRowIsSparse {
-				panic(mat.ErrShape)
-			}
-			if i == j {
-				sum := floats.Dot(jRowS.data, jRowS.data)
-				if sum == 0.0 && matrix.At(i, i) == 0.0 {
-					continue
-				}
-				lower.Set(j, j, math.Sqrt(matrix.At(i, i)-sum))
-			} else {
-				rowDotSum := dotSparseSparseNoSort(iRowS, jRowS, nil)
-				if rowDotSum == 0.0 && matrix.At(i, j) == 0.0 {
-					continue
-				}
-				lower.Set(i, j, (matrix.At(i, j)-rowDotSum)/lower.At(j, j))
-			}
-		}
-	}
-}
@@ -1,6 +1,7 @@
 package sparse
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"testing"
@@ -51,6 +52,29 @@ func TestCholesky(t *testing.T) {
 	}
 }
 
+func TestCholeskyPoisson(t *testing.T) {
+	t.Parallel()
+	for name, a := range map[string]*CSR{
+		"2D": laplacian2D(9, 7),
+		"3D": laplacian3D(4, 5, 3),
+	} {
+		n, _ := a.Dims()
+		want := mat.NewTriDense(n, false, nil)
+		cholSimple(a, want)
+
+		var chol Cholesky
+		chol.Factorize(a)
+		got := NewCOO(n, n, nil, nil, nil).ToCSR()
+		chol.LTo(got)
+		if !mat.EqualApprox(want, got, 1e-12) {
+			t.Errorf("%s: factor does not match reference implementation", name)
+		}
+		if nnz := CholeskyNNZ(a, nil); chol.FactorNNZ() != nnz {
+			t.Errorf("%s: expected %d non-zeros in L but received %d", name, nnz, chol.FactorNNZ())
+		}
+	}
+}
+
 func TestCholeskySolveVecTo(t *testing.T) {
 	t.Parallel()
 	for idx, test := range []struct {
@@ -209,7 +233,9 @@ func cholMatches(a *mat.SymDense) bool {
 	csrRes := coo.ToCSR()
 	aCOO := matToCOO(a, 1e-10)
 	aCSR := aCOO.ToCSR()
-	cholCSR(aCSR, csrRes)
+	var sc Cholesky
+	sc.Factorize(aCSR)
+	sc.LTo(csrRes)
 	if !mat.EqualApprox(&L, csrRes, 1e-10) {
 		for i := 0; i < n; i++ {
 			for j := 0; j < n; j++ {
@@ -365,11 +391,343 @@ func sparseCholBench(b *testing.B, size int, frac float64) {
 	if frac != 0.0 {
 		mDense = randomSymDensePosDefinite(size, frac, src)
 	}
-	coo := NewCOO(size, size, nil, nil, nil)
-	csrRes := coo.ToCSR()
 	aCSR := matToCSR(mDense, 1e-8)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		cholCSR(aCSR, csrRes)
+		var chol Cholesky
+		chol.Factorize(aCSR)
+	}
+}
+
+func TestCholeskyRefactorize(t *testing.T) {
+	a := laplacian2D(8, 7)
+	n, _ := a.Dims()
+
+	// same sparsity pattern with different values
+	b := NewDOK(n, n)
+	a.DoNonZero(func(i, j int, v float64) {
+		if i == j {
+			b.Set(i, j, v+float64(i%3))
+		} else {
+			b.Set(i, j, v*0.5)
+		}
+	})
+
+	// a subset of the sparsity pattern
+	subset := NewDOK(n, n)
+	a.DoNonZero(func(i, j int, v float64) {
+		if (i != 0 || j != 1) && (i != 1 || j != 0) {
+			subset.Set(i, j, v)
+		}
+	})
+
+	// additional non-zero elements outside the pattern of L
+	outside := NewDOK(n, n)
+	a.DoNonZero(func(i, j int, v float64) {
+		outside.Set(i, j, v)
+	})
+	outside.Set(0, n-1, -0.1)
+	outside.Set(n-1, 0, -0.1)
+
+	for name, o := range map[string]Ordering{
+		"natural": NaturalOrdering{},
+		"AMD":     AMDOrdering{},
+	} {
+		var chol Cholesky
+		chol.Factorize(a, WithOrdering(o))
+		nnz := chol.FactorNNZ()
+
+		for mname, m := range map[string]*CSR{"values": b.ToCSR(), "subset": subset.ToCSR()} {
+			if err := chol.Refactorize(m); err != nil {
+				t.Errorf("%s (%s): unexpected error %v", name, mname, err)
+				continue
+			}
+			if !mat.EqualApprox(m, &chol, 1e-12) {
+				t.Errorf("%s (%s): expected factorised matrix\n%v\nbut received\n%v", name, mname, mat.Formatted(m), mat.Formatted(&chol))
+			}
+			if chol.FactorNNZ() != nnz {
+				t.Errorf("%s (%s): expected %d non-zeros in L but received %d", name, mname, nnz, chol.FactorNNZ())
+			}
+
+			rhs := mat.NewVecDense(n, randomData(n, 1, 1))
+			var x, got mat.VecDense
+			x.ReuseAsVec(n)
+			chol.SolveVecTo(&x, rhs)
+			got.MulVec(m, &x)
+			if !mat.EqualApprox(rhs, &got, 1e-10) {
+				t.Errorf("%s (%s): incorrect solution after refactorising", name, mname)
+			}
+		}
+
+		before := mat.DenseCopyOf(&chol)
+		if err := chol.Refactorize(outside.ToCSR()); err != ErrPatternMismatch {
+			t.Errorf("%s: expected ErrPatternMismatch but received %v", name, err)
+		}
+		if !mat.Equal(before, &chol) {
+			t.Errorf("%s: factorisation modified after pattern mismatch", name)
+		}
+	}
+}
+
+func TestCholeskyEliminationTree(t *testing.T) {
+	var tests = []struct {
+		a    *CSR
+		want []int
+	}{
+		{
+			a:    CreateCSR(4, 4, []float64{2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2}).(*CSR),
+			want: []int{-1, -1, -1, -1},
+		},
+		{
+			a:    CreateCSR(4, 4, []float64{2, -1, 0, 0, -1, 2, -1, 0, 0, -1, 2, -1, 0, 0, -1, 2}).(*CSR),
+			want: []int{1, 2, 3, -1},
+		},
+		{
+			// arrow matrix with the dense row/column last
+			a:    CreateCSR(4, 4, []float64{4, 0, 0, 1, 0, 4, 0, 1, 0, 0, 4, 1, 1, 1, 1, 4}).(*CSR),
+			want: []int{3, 3, 3, -1},
+		},
+	}
+
+	for ti, test := range tests {
+		var chol Cholesky
+		chol.Factorize(test.a)
+		if got := chol.EliminationTree(); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("Test %d: expected elimination tree %v but received %v", ti, test.want, got)
+		}
+	}
+}
+
+func TestCholeskyNotPositiveDefinite(t *testing.T) {
+	var tests = []struct {
+		a     *CSR
+		opts  []CholeskyOption
+		index int
+	}{
+		{
+			a: CreateCSR(3, 3, []float64{
+				4, 2, 0,
+				2, 1, 1,
+				0, 1, 3,
+			}).(*CSR),
+			index: 1,
+		},
+		{
+			a: CreateCSR(3, 3, []float64{
+				2, 0, 0,
+				0, -1, 0,
+				0, 0, 3,
+			}).(*CSR),
+			index: 1,
+		},
+		{
+			a: CreateCSR(4, 4, []float64{
+				2, 0, 0, 1,
+				0, 2, 0, 0,
+				0, 0, 2, 0,
+				1, 0, 0, -3,
+			}).(*CSR),
+			opts:  []CholeskyOption{WithOrdering(NestedDissectionOrdering{})},
+			index: 3,
+		},
+	}
+
+	for ti, test := range tests {
+		var chol Cholesky
+		err := chol.Factorize(test.a, test.opts...)
+		if !errors.Is(err, mat.ErrNotPSD) {
+			t.Errorf("Test %d: expected error matching mat.ErrNotPSD but received %v", ti, err)
+			continue
+		}
+		if e := err.(*NotPositiveDefiniteError); e.Index != test.index {
+			t.Errorf("Test %d: expected failure at row %d but received %d", ti, test.index, e.Index)
+		}
+
+		var x mat.VecDense
+		x.ReuseAsVec(test.a.matrix.I)
+		if err := chol.SolveVecTo(&x, mat.NewVecDense(test.a.matrix.I, nil)); !errors.Is(err, mat.ErrNotPSD) {
+			t.Errorf("Test %d: expected error matching mat.ErrNotPSD from solve but received %v", ti, err)
+		}
+		if !math.IsInf(chol.Cond(), 1) {
+			t.Errorf("Test %d: expected infinite condition number but received %f", ti, chol.Cond())
+		}
+	}
+}
+
+func TestCholeskyCond(t *testing.T) {
+	t.Parallel()
+	src := rand.NewSource(1)
+	for _, a := range []*mat.SymDense{
+		mat.NewSymDense(3, []float64{
+			4, 1, 1,
+			1, 2, 3,
+			1, 3, 6,
+		}),
+		randomSymDensePosDefinite(60, 0.05, src),
+		mat.NewSymDense(2, []float64{
+			1, 1,
+			1, 1 + 1e-15,
+		}),
+	} {
+		var want mat.Cholesky
+		if !want.Factorize(a) {
+			t.Fatal("unexpected Cholesky factorization failure: not positive definite")
+		}
+		var chol Cholesky
+		if err := chol.Factorize(matToCSR(a, 0), WithOrdering(AMDOrdering{})); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if cond := mat.Cond(a, math.Inf(1)); math.Abs(cond-chol.Cond()) > 0.5*cond {
+			t.Errorf("expected condition number of approximately %g but received %g (mat.Cholesky %g)", cond, chol.Cond(), want.Cond())
+		}
+
+		var x mat.VecDense
+		x.ReuseAsVec(a.Symmetric())
+		err := chol.SolveVecTo(&x, mat.NewVecDense(a.Symmetric(), nil))
+		if _, ok := err.(mat.Condition); ok != (chol.Cond() > mat.ConditionTolerance) {
+			t.Errorf("unexpected error %v for condition number %g", err, chol.Cond())
+		}
+	}
+}
+
+// fixedOrdering is an Ordering returning a predetermined permutation.
+type fixedOrdering []int
+
+func (o fixedOrdering) Permutation(a *CSR) []int {
+	return o
+}
+
+func TestCholeskyUpdate(t *testing.T) {
+	a := laplacian2D(6, 5)
+	n, _ := a.Dims()
+
+	x := NewVector(n, []int{0, 13, 29}, []float64{1, -2, 0.5})
+	xd := mat.NewVecDense(n, nil)
+	x.DoNonZero(func(i, j int, v float64) {
+		xd.SetVec(i, v)
+	})
+
+	for name, o := range map[string]Ordering{
+		"natural": NaturalOrdering{},
+		"AMD":     AMDOrdering{},
+	} {
+		for _, vec := range []mat.Vector{x, xd} {
+			var chol Cholesky
+			if err := chol.Factorize(a, WithOrdering(o)); err != nil {
+				t.Fatalf("%s: unexpected error %v", name, err)
+			}
+			alpha := 0.7
+			if err := chol.Update(alpha, vec); err != nil {
+				t.Errorf("%s (%T): unexpected error %v", name, vec, err)
+			}
+
+			var want mat.Dense
+			want.Outer(alpha, xd, xd)
+			want.Add(&want, a)
+			if !mat.EqualApprox(&want, &chol, 1e-10) {
+				t.Errorf("%s (%T): expected updated matrix\n%v\nbut received\n%v", name, vec, mat.Formatted(&want), mat.Formatted(&chol))
+			}
+			wantCSR := matToCSR(&want, 0)
+			if got, nnz := chol.FactorNNZ(), CholeskyNNZ(wantCSR, chol.Permutation()); got != nnz {
+				t.Errorf("%s (%T): expected %d non-zeros in L after update but received %d", name, vec, nnz, got)
+			}
+			var fresh Cholesky
+			fresh.Factorize(wantCSR, WithOrdering(fixedOrdering(chol.Permutation())))
+			if got, want := chol.EliminationTree(), fresh.EliminationTree(); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%s (%T): expected elimination tree %v but received %v", name, vec, want, got)
+			}
+
+			rhs := mat.NewVecDense(n, randomData(n, 1, 1))
+			var sol, got mat.VecDense
+			sol.ReuseAsVec(n)
+			chol.SolveVecTo(&sol, rhs)
+			got.MulVec(&want, &sol)
+			if !mat.EqualApprox(rhs, &got, 1e-10) {
+				t.Errorf("%s (%T): incorrect solution after update", name, vec)
+			}
+
+			// a downdate too large to preserve positive definiteness must fail
+			// leaving the factorisation unchanged
+			before := mat.DenseCopyOf(&chol)
+			if err := chol.Downdate(100, vec); !errors.Is(err, mat.ErrNotPSD) {
+				t.Errorf("%s (%T): expected error matching mat.ErrNotPSD but received %v", name, vec, err)
+			}
+			if !mat.EqualApprox(before, &chol, 1e-12) {
+				t.Errorf("%s (%T): factorisation modified by failed downdate", name, vec)
+			}
+
+			if err := chol.Downdate(alpha, vec); err != nil {
+				t.Errorf("%s (%T): unexpected error %v", name, vec, err)
+			}
+			if !mat.EqualApprox(a, &chol, 1e-10) {
+				t.Errorf("%s (%T): expected downdated matrix\n%v\nbut received\n%v", name, vec, mat.Formatted(a), mat.Formatted(&chol))
+			}
+			if cond := mat.Cond(a.ToDense(), math.Inf(1)); math.Abs(cond-chol.Cond()) > 0.5*cond {
+				t.Errorf("%s (%T): expected condition number of approximately %g but received %g", name, vec, cond, chol.Cond())
+			}
+
+			// the extended pattern remains valid for refactorising
+			if err := chol.Refactorize(a); err != nil {
+				t.Errorf("%s (%T): unexpected error %v", name, vec, err)
+			}
+			if !mat.EqualApprox(a, &chol, 1e-10) {
+				t.Errorf("%s (%T): incorrect refactorisation after update", name, vec)
+			}
+		}
+	}
+}
+
+func TestCholeskyUpdateFailure(t *testing.T) {
+	a := laplacian2D(6, 5)
+	n, _ := a.Dims()
+
+	// the non-zero elements of x are far apart so the update causes fill-in
+	tests := []struct {
+		desc  string
+		alpha float64
+		x     *Vector
+	}{
+		{desc: "NaN update", alpha: 1, x: NewVector(n, []int{0, 29}, []float64{1, math.NaN()})},
+		{desc: "NaN first update", alpha: 1, x: NewVector(n, []int{0, 29}, []float64{math.NaN(), 1})},
+		{desc: "Inf update", alpha: 1, x: NewVector(n, []int{0, 29}, []float64{1, math.Inf(1)})},
+		{desc: "large downdate", alpha: -100, x: NewVector(n, []int{0, 29}, []float64{1, -2})},
+	}
+
+	for _, test := range tests {
+		var chol Cholesky
+		if err := chol.Factorize(a); err != nil {
+			t.Fatalf("%s: unexpected error %v", test.desc, err)
+		}
+		before := NewCOO(n, n, nil, nil, nil).ToCSR()
+		chol.LTo(before)
+		nnz, tree := chol.FactorNNZ(), chol.EliminationTree()
+
+		if err := chol.Update(test.alpha, test.x); !errors.Is(err, mat.ErrNotPSD) {
+			t.Errorf("%s: expected error matching mat.ErrNotPSD but received %v", test.desc, err)
+		}
+
+		after := NewCOO(n, n, nil, nil, nil).ToCSR()
+		chol.LTo(after)
+		if !mat.Equal(before, after) {
+			t.Errorf("%s: expected L to be unchanged by failed update", test.desc)
+		}
+		if got := chol.FactorNNZ(); got != nnz {
+			t.Errorf("%s: expected %d non-zeros in L after failed update but received %d", test.desc, nnz, got)
+		}
+		if got := chol.EliminationTree(); fmt.Sprint(got) != fmt.Sprint(tree) {
+			t.Errorf("%s: expected elimination tree %v after failed update but received %v", test.desc, tree, got)
+		}
+
+		// the factorisation remains usable
+		x := NewVector(n, []int{0, 29}, []float64{1, 2})
+		if err := chol.Update(0.5, x); err != nil {
+			t.Errorf("%s: unexpected error %v", test.desc, err)
+		}
+		var want mat.Dense
+		want.Outer(0.5, x, x)
+		want.Add(&want, a)
+		if !mat.EqualApprox(&want, &chol, 1e-10) {
+			t.Errorf("%s: incorrect update after failed update", test.desc)
+		}
 	}
 }
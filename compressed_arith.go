@@ -108,6 +108,13 @@ func (c *CSR) Mul(a, b mat.Matrix) {
 		c.mulCSRCSR(lhs, rhs)
 		return
 	}
+	if bsrA, ok := a.(*BSR); ok {
+		if bsrB, okB := b.(*BSR); okB && bsrA.matrix.C == bsrB.matrix.R {
+			// handle BSR * BSR with compatible blocks
+			c.mulBSRBSR(bsrA, bsrB)
+			return
+		}
+	}
 	if dia, ok := a.(*DIA); ok {
 		if diaB, okB := b.(*DIA); okB {
 			// handle DIA * DIA
@@ -263,91 +270,75 @@ func (c *CSR) mulMatCSR(a mat.Matrix, rhs *CSR) {
 
 // mulDIACSR handles CSR = DIA * CSR (or CSR = CSR * DIA if trans == true)
 func (c *CSR) mulDIACSR(dia *DIA, other *CSR, trans bool) {
-	diagonal := dia.Diagonal()
-	if trans {
-		for i := 0; i < c.matrix.I; i++ {
-			var v float64
-			for k := other.matrix.Indptr[i]; k < other.matrix.Indptr[i+1]; k++ {
-				if other.matrix.Ind[k] < len(diagonal) {
-					v = other.matrix.Data[k] * diagonal[other.matrix.Ind[k]]
-					if v != 0 {
-						c.matrix.Ind = append(c.matrix.Ind, other.matrix.Ind[k])
-						c.matrix.Data = append(c.matrix.Data, v)
-					}
-				}
-			}
-			c.matrix.Indptr[i+1] = len(c.matrix.Ind)
-		}
-	} else {
-		for i := 0; i < c.matrix.I; i++ {
-			var v float64
+	_, cols := c.Dims()
+	spa := NewSPA(cols)
+
+	for i := 0; i < c.matrix.I; i++ {
+		if trans {
 			for k := other.matrix.Indptr[i]; k < other.matrix.Indptr[i+1]; k++ {
-				if i < len(diagonal) {
-					v = other.matrix.Data[k] * diagonal[i]
-					if v != 0 {
-						c.matrix.Ind = append(c.matrix.Ind, other.matrix.Ind[k])
-						c.matrix.Data = append(c.matrix.Data, v)
-					}
-				}
+				s := other.matrix.Data[k]
+				dia.DoRowNonZero(other.matrix.Ind[k], func(_, j int, v float64) {
+					spa.ScatterValue(v, j, s, &c.matrix.Ind)
+				})
 			}
-			c.matrix.Indptr[i+1] = len(c.matrix.Ind)
+		} else {
+			dia.DoRowNonZero(i, func(_, k int, s float64) {
+				begin, end := other.matrix.Indptr[k], other.matrix.Indptr[k+1]
+				spa.Scatter(other.matrix.Data[begin:end], other.matrix.Ind[begin:end], s, &c.matrix.Ind)
+			})
 		}
+		spa.GatherAndZero(&c.matrix.Data, &c.matrix.Ind)
+		c.matrix.Indptr[i+1] = len(c.matrix.Ind)
 	}
 }
 
 // mulDIAMat handles CSR = DIA * mat.Matrix (or CSR = mat.Matrix * DIA if trans == true)
 func (c *CSR) mulDIAMat(dia *DIA, other mat.Matrix, trans bool) {
-	_, cols := other.Dims()
-	diagonal := dia.Diagonal()
-
-	if trans {
-		for i := 0; i < c.matrix.I; i++ {
-			var v float64
-			for k := 0; k < cols; k++ {
-				if k < len(diagonal) {
-					v = other.At(i, k) * diagonal[k]
-					if v != 0 {
-						c.matrix.Ind = append(c.matrix.Ind, k)
-						c.matrix.Data = append(c.matrix.Data, v)
-					}
+	_, cols := c.Dims()
+	_, otherCols := other.Dims()
+	spa := NewSPA(cols)
+
+	for i := 0; i < c.matrix.I; i++ {
+		if trans {
+			for k := 0; k < otherCols; k++ {
+				s := other.At(i, k)
+				if s != 0 {
+					dia.DoRowNonZero(k, func(_, j int, v float64) {
+						spa.ScatterValue(v, j, s, &c.matrix.Ind)
+					})
 				}
 			}
-			c.matrix.Indptr[i+1] = len(c.matrix.Ind)
-		}
-	} else {
-		for i := 0; i < c.matrix.I; i++ {
-			var v float64
-			for k := 0; k < cols; k++ {
-				if i < len(diagonal) {
-					v = other.At(i, k) * diagonal[i]
-					if v != 0 {
-						c.matrix.Ind = append(c.matrix.Ind, k)
-						c.matrix.Data = append(c.matrix.Data, v)
+		} else {
+			dia.DoRowNonZero(i, func(_, k int, s float64) {
+				for j := 0; j < otherCols; j++ {
+					if v := other.At(k, j); v != 0 {
+						spa.ScatterValue(v, j, s, &c.matrix.Ind)
 					}
 				}
-			}
-			c.matrix.Indptr[i+1] = len(c.matrix.Ind)
+			})
 		}
+		spa.GatherAndZero(&c.matrix.Data, &c.matrix.Ind)
+		c.matrix.Indptr[i+1] = len(c.matrix.Ind)
 	}
 }
 
 // mulDIADIA multiplies two diagonal matrices
 func (c *CSR) mulDIADIA(a, b *DIA) {
 	_, ac := a.Dims()
-	br, _ := b.Dims()
-	aDiagonal := a.Diagonal()
-	bDiagonal := a.Diagonal()
+	br, bc := b.Dims()
 	if ac != br {
 		panic(mat.ErrShape)
 	}
-	for i := 0; i < br; i++ {
-		var v float64
-		v = aDiagonal[i] * bDiagonal[i]
-		if v != 0 {
-			c.matrix.Ind = append(c.matrix.Ind, i)
-			c.matrix.Data = append(c.matrix.Data, v)
-		}
-		c.matrix.Indptr[i+1] = i + 1
+	spa := NewSPA(bc)
+
+	for i := 0; i < c.matrix.I; i++ {
+		a.DoRowNonZero(i, func(_, k int, s float64) {
+			b.DoRowNonZero(k, func(_, j int, v float64) {
+				spa.ScatterValue(v, j, s, &c.matrix.Ind)
+			})
+		})
+		spa.GatherAndZero(&c.matrix.Data, &c.matrix.Ind)
+		c.matrix.Indptr[i+1] = len(c.matrix.Ind)
 	}
 }
 
@@ -355,20 +346,23 @@ func (c *CSR) mulDIADIA(a, b *DIA) {
 func (c *CSR) addDIADIA(a, b *DIA, alpha, beta float64) {
 	ar, ac := a.Dims()
 	br, bc := b.Dims()
-	aDiagonal := a.Diagonal()
-	bDiagonal := a.Diagonal()
 	if ac != bc {
 		panic(mat.ErrShape)
 	}
 	if ar != br {
 		panic(mat.ErrShape)
 	}
-	for i := 0; i < br; i++ {
-		var v float64
-		v = aDiagonal[i]*alpha + bDiagonal[i]*beta
-		c.matrix.Ind = append(c.matrix.Ind, i)
-		c.matrix.Data = append(c.matrix.Data, v)
-		c.matrix.Indptr[i+1] = i + 1
+	spa := NewSPA(ac)
+
+	for i := 0; i < ar; i++ {
+		a.DoRowNonZero(i, func(_, j int, v float64) {
+			spa.ScatterValue(v, j, alpha, &c.matrix.Ind)
+		})
+		b.DoRowNonZero(i, func(_, j int, v float64) {
+			spa.ScatterValue(v, j, beta, &c.matrix.Ind)
+		})
+		spa.GatherAndZero(&c.matrix.Data, &c.matrix.Ind)
+		c.matrix.Indptr[i+1] = len(c.matrix.Ind)
 	}
 }
 
@@ -7,11 +7,6 @@ import (
 	"gonum.org/v1/gonum/mat"
 )
 
-type MulVecToer interface {
-	// MulVecTo computes A*x or A^T*x and stores the result into dst.
-	MulVecTo(dst []float64, trans bool, x []float64)
-}
-
 func TestCompressedMulVecTo(t *testing.T) {
 	type MatrixTypes struct {
 		name   string
@@ -189,14 +189,19 @@ func compress(row []int, col []int, data []float64, n int) (ia []int, ja []int,
 
 func dedupe(ia []int, ja []int, d []float64, m int, n int) ([]int, []float64) {
 	//w := make([]int, n)
-	w := getInts(n, true)
+	w := getInts(n, false)
 	defer putInts(w)
+	for i := range w {
+		w[i] = -1
+	}
 	nz := 0
 
 	for i := 0; i < m; i++ {
 		q := nz
 		for j := ia[i]; j < ia[i+1]; j++ {
-			if w[ja[j]] > q {
+			// w holds the position of the last element stored for each column so
+			// the element is a duplicate if it was stored for the current row
+			if w[ja[j]] >= q {
 				d[w[ja[j]]] += d[j]
 			} else {
 				w[ja[j]] = nz
@@ -200,3 +200,35 @@ func TestCOOTranspose(t *testing.T) {
 		}
 	}
 }
+
+func TestCOODuplicates(t *testing.T) {
+	// duplicates (including at the start of each row/column) are summed on conversion
+	coo := NewCOO(3, 3,
+		[]int{0, 0, 1, 2, 1, 1, 0, 2},
+		[]int{0, 0, 1, 0, 2, 1, 2, 0},
+		[]float64{1, 2, 3, 4, 5, 6, 7, 8},
+	)
+	e := mat.NewDense(3, 3, []float64{
+		3, 0, 7,
+		0, 9, 5,
+		12, 0, 0,
+	})
+
+	var tests = []struct {
+		desc    string
+		convert func(c *COO) Sparser
+	}{
+		{desc: "COO -> CSR", convert: func(c *COO) Sparser { return c.ToCSR() }},
+		{desc: "COO -> CSC", convert: func(c *COO) Sparser { return c.ToCSC() }},
+	}
+
+	for _, test := range tests {
+		m := test.convert(coo)
+		if m.NNZ() != 5 {
+			t.Errorf("%s: expected 5 non zero elements but received %d", test.desc, m.NNZ())
+		}
+		if !mat.Equal(e, m) {
+			t.Errorf("%s: expected\n%v\nbut received\n%v\n", test.desc, mat.Formatted(e), mat.Formatted(m))
+		}
+	}
+}
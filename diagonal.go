@@ -1,27 +1,39 @@
 package sparse
 
 import (
+	"sort"
+
 	"gonum.org/v1/gonum/floats"
 	"gonum.org/v1/gonum/mat"
 )
 
 var (
-	_ Sparser       = (*DIA)(nil)
-	_ mat.ColViewer = (*DIA)(nil)
-	_ mat.RowViewer = (*DIA)(nil)
+	_ Sparser            = (*DIA)(nil)
+	_ mat.ColViewer      = (*DIA)(nil)
+	_ mat.RowViewer      = (*DIA)(nil)
+	_ mat.RowNonZeroDoer = (*DIA)(nil)
+	_ mat.ColNonZeroDoer = (*DIA)(nil)
 )
 
-// DIA matrix type is a specialised matrix designed to store DIAgonal values of square symmetrical
-// matrices (all zero values except along the diagonal running top left to bottom right).  The DIA matrix
-// type is specifically designed to take advantage of the sparsity pattern of square symmetrical matrices.
+// DIA matrix type is a specialised matrix designed to store DIAgonal values of banded matrices (all zero
+// values except along a small number of diagonals).  Each stored diagonal is identified by its offset from
+// the main diagonal running top left to bottom right; an offset of 0 represents the main diagonal, positive
+// offsets represent diagonals above the main diagonal and negative offsets represent diagonals below it.
+// The DIA matrix type is specifically designed to take advantage of the sparsity pattern of diagonal,
+// tridiagonal and other banded matrices e.g. finite difference operators.
+//
+// Element t of the diagonal with offset k is located at row t, column t+k when k >= 0
+// and at row t-k, column t when k < 0 i.e. each diagonal is stored starting from its
+// top left element.
 type DIA struct {
-	m, n int
-	data []float64
+	m, n    int
+	offsets []int
+	data    [][]float64
 }
 
 // NewDIA creates a new DIAgonal format sparse matrix.
-// The matrix is initialised to the size of the specified m * m dimensions (rows * columns)
-// (creating a square) with the specified slice containing it's diagonal values.  The diagonal slice
+// The matrix is initialised to the size of the specified m * n dimensions (rows * columns)
+// with the specified slice containing it's (main) diagonal values.  The diagonal slice
 // will be used as the backing slice to the matrix so changes to values of the slice will be reflected
 // in the matrix.
 func NewDIA(m int, n int, diagonal []float64) *DIA {
@@ -32,7 +44,89 @@ func NewDIA(m int, n int, diagonal []float64) *DIA {
 		panic(mat.ErrColAccess)
 	}
 
-	return &DIA{m: m, n: n, data: diagonal}
+	return &DIA{m: m, n: n, offsets: []int{0}, data: [][]float64{diagonal}}
+}
+
+// NewDIAOffsets creates a new DIAgonal format sparse matrix with multiple diagonals.
+// The matrix is initialised to the size of the specified m * n dimensions (rows * columns)
+// with the specified slice of offsets identifying the diagonals stored in the corresponding
+// elements of diagonals.  Each diagonal slice will be used as the backing slice to the matrix
+// so changes to values of the slices will be reflected in the matrix.  NewDIAOffsets will panic
+// if offsets and diagonals are different lengths, if an offset is duplicated or falls outside
+// the matrix or if a diagonal slice is longer than the corresponding diagonal of the matrix.
+func NewDIAOffsets(m int, n int, offsets []int, diagonals [][]float64) *DIA {
+	if m < 0 {
+		panic(mat.ErrRowAccess)
+	}
+	if n < 0 {
+		panic(mat.ErrColAccess)
+	}
+	if len(offsets) != len(diagonals) {
+		panic(mat.ErrShape)
+	}
+
+	d := &DIA{
+		m: m, n: n,
+		offsets: make([]int, len(offsets)),
+		data:    make([][]float64, len(diagonals)),
+	}
+	copy(d.offsets, offsets)
+	copy(d.data, diagonals)
+	sort.Sort(byOffset{d})
+
+	for i, k := range d.offsets {
+		if i > 0 && d.offsets[i-1] == k {
+			panic(mat.ErrShape)
+		}
+		if k <= -m || k >= n || len(d.data[i]) > d.diagonalLen(k) {
+			panic(mat.ErrShape)
+		}
+	}
+
+	return d
+}
+
+// byOffset sorts the diagonals of a DIA matrix into ascending order of offset.
+type byOffset struct {
+	d *DIA
+}
+
+func (b byOffset) Len() int           { return len(b.d.offsets) }
+func (b byOffset) Less(i, j int) bool { return b.d.offsets[i] < b.d.offsets[j] }
+func (b byOffset) Swap(i, j int) {
+	b.d.offsets[i], b.d.offsets[j] = b.d.offsets[j], b.d.offsets[i]
+	b.d.data[i], b.d.data[j] = b.d.data[j], b.d.data[i]
+}
+
+// diagonalLen returns the number of elements along the diagonal with offset k.
+func (d *DIA) diagonalLen(k int) int {
+	var l int
+	if k >= 0 {
+		l = d.n - k
+		if d.m < l {
+			l = d.m
+		}
+	} else {
+		l = d.m + k
+		if d.n < l {
+			l = d.n
+		}
+	}
+	if l < 0 {
+		return 0
+	}
+	return l
+}
+
+// band returns the index into offsets/data of the diagonal with offset k or -1
+// if the diagonal is not stored.
+func (d *DIA) band(k int) int {
+	for i, offset := range d.offsets {
+		if offset == k {
+			return i
+		}
+	}
+	return -1
 }
 
 // Dims returns the size of the matrix as the number of rows and columns
@@ -50,37 +144,129 @@ func (d *DIA) At(i, j int) float64 {
 		panic(mat.ErrColAccess)
 	}
 
-	if i == j {
-		return d.data[i]
+	b := d.band(j - i)
+	if b < 0 {
+		return 0
+	}
+	t := i
+	if j < i {
+		t = j
+	}
+	if t < len(d.data[b]) {
+		return d.data[b][t]
 	}
 	return 0
 }
 
 // T returns the matrix transposed.  In the case of a DIA (DIAgonal) sparse matrix this method
-// returns a new DIA matrix with the m and n values transposed.
+// returns a new DIA matrix with the m and n values transposed and the offsets negated.  The
+// returned matrix shares the same backing storage for the diagonals as the receiver.
 func (d *DIA) T() mat.Matrix {
-	return &DIA{m: d.n, n: d.m, data: d.data}
+	t := &DIA{
+		m: d.n, n: d.m,
+		offsets: make([]int, len(d.offsets)),
+		data:    make([][]float64, len(d.data)),
+	}
+	for i, k := range d.offsets {
+		t.offsets[len(d.offsets)-1-i] = -k
+		t.data[len(d.data)-1-i] = d.data[i]
+	}
+	return t
 }
 
 // DoNonZero calls the function fn for each of the non-zero elements of the receiver.
 // The function fn takes a row/column index and the element value of the receiver at
-// (i, j).  The order of visiting to each non-zero element is from top left to bottom right.
+// (i, j).  The order of visiting to each non-zero element is diagonal by diagonal in
+// ascending order of offset and from top left to bottom right along each diagonal.
 func (d *DIA) DoNonZero(fn func(i, j int, v float64)) {
-	for i, v := range d.data {
-		fn(i, i, v)
+	for b, k := range d.offsets {
+		r0, c0 := 0, k
+		if k < 0 {
+			r0, c0 = -k, 0
+		}
+		for t, v := range d.data[b] {
+			fn(r0+t, c0+t, v)
+		}
+	}
+}
+
+// DoRowNonZero calls the function fn for each of the non-zero elements of row i
+// in the receiver.  The function fn takes a row/column index and the element value
+// of the receiver at (i, j).
+func (d *DIA) DoRowNonZero(i int, fn func(i, j int, v float64)) {
+	if uint(i) < 0 || uint(i) >= uint(d.m) {
+		panic(mat.ErrRowAccess)
+	}
+	for b, k := range d.offsets {
+		j := i + k
+		if j < 0 || j >= d.n {
+			continue
+		}
+		t := i
+		if j < i {
+			t = j
+		}
+		if t < len(d.data[b]) {
+			fn(i, j, d.data[b][t])
+		}
+	}
+}
+
+// DoColNonZero calls the function fn for each of the non-zero elements of column j
+// in the receiver.  The function fn takes a row/column index and the element value
+// of the receiver at (i, j).
+func (d *DIA) DoColNonZero(j int, fn func(i, j int, v float64)) {
+	if uint(j) < 0 || uint(j) >= uint(d.n) {
+		panic(mat.ErrColAccess)
+	}
+	for b := len(d.offsets) - 1; b >= 0; b-- {
+		i := j - d.offsets[b]
+		if i < 0 || i >= d.m {
+			continue
+		}
+		t := i
+		if j < i {
+			t = j
+		}
+		if t < len(d.data[b]) {
+			fn(i, j, d.data[b][t])
+		}
 	}
 }
 
 // NNZ returns the Number of Non Zero elements in the sparse matrix.
 func (d *DIA) NNZ() int {
-	return len(d.data)
+	var nnz int
+	for _, diagonal := range d.data {
+		nnz += len(diagonal)
+	}
+	return nnz
 }
 
-// Diagonal returns the diagonal values of the matrix from top left to bottom right.
+// Diagonal returns the main diagonal values of the matrix from top left to bottom right.
 // The values are returned as a slice backed by the same array as backing the receiver
-// so changes to values in the returned slice will be reflected in the receiver.
+// so changes to values in the returned slice will be reflected in the receiver.  If the
+// main diagonal is not stored, nil is returned.
 func (d *DIA) Diagonal() []float64 {
-	return d.data
+	return d.Band(0)
+}
+
+// Band returns the values of the diagonal with offset k from top left to bottom right.
+// The values are returned as a slice backed by the same array as backing the receiver
+// so changes to values in the returned slice will be reflected in the receiver.  If the
+// diagonal is not stored, nil is returned.
+func (d *DIA) Band(k int) []float64 {
+	if b := d.band(k); b >= 0 {
+		return d.data[b]
+	}
+	return nil
+}
+
+// Offsets returns the offsets of the diagonals stored in the matrix in ascending order.
+// An offset of 0 represents the main diagonal, positive offsets represent diagonals
+// above the main diagonal and negative offsets diagonals below it.
+func (d *DIA) Offsets() []int {
+	return d.offsets
 }
 
 // RowView slices the matrix and returns a Vector containing a copy of elements
@@ -110,9 +296,9 @@ func (d *DIA) ScatterRow(i int, row []float64) []float64 {
 	if row == nil {
 		row = make([]float64, d.n)
 	}
-	if i < len(d.data) {
-		row[i] = d.data[i]
-	}
+	d.DoRowNonZero(i, func(i, j int, v float64) {
+		row[j] = v
+	})
 	return row
 }
 
@@ -131,9 +317,9 @@ func (d *DIA) ScatterCol(j int, col []float64) []float64 {
 	if col == nil {
 		col = make([]float64, d.m)
 	}
-	if j < len(d.data) {
-		col[j] = d.data[j]
-	}
+	d.DoColNonZero(j, func(i, j int, v float64) {
+		col[i] = v
+	})
 	return col
 }
 
@@ -151,12 +337,24 @@ func (d *DIA) MulVecTo(dst []float64, trans bool, x []float64) {
 		}
 	}
 
-	for i, v := range d.data {
-		dst[i] += v * x[i]
+	for b, k := range d.offsets {
+		r0, c0 := 0, k
+		if k < 0 {
+			r0, c0 = -k, 0
+		}
+		diagonal := d.data[b]
+		if trans {
+			r0, c0 = c0, r0
+		}
+		y := dst[r0 : r0+len(diagonal)]
+		v := x[c0 : c0+len(diagonal)]
+		for t, e := range diagonal {
+			y[t] += e * v[t]
+		}
 	}
 }
 
 // Trace returns the trace.
 func (d *DIA) Trace() float64 {
-	return floats.Sum(d.data)
+	return floats.Sum(d.Diagonal())
 }
@@ -1,6 +1,7 @@
 package sparse
 
 import (
+	"reflect"
 	"testing"
 
 	"gonum.org/v1/gonum/floats/scalar"
@@ -155,3 +156,176 @@ func TestDIATrace(t *testing.T) {
 
 	}
 }
+
+// createBandedDIA creates a DIA matrix storing every diagonal of the dense
+// row major data containing at least one non-zero value.
+func createBandedDIA(m, n int, data []float64) *DIA {
+	var offsets []int
+	var diagonals [][]float64
+	for k := -m + 1; k < n; k++ {
+		r0, c0 := 0, k
+		if k < 0 {
+			r0, c0 = -k, 0
+		}
+		var diagonal []float64
+		var nonZero bool
+		for t := 0; r0+t < m && c0+t < n; t++ {
+			v := data[(r0+t)*n+c0+t]
+			diagonal = append(diagonal, v)
+			nonZero = nonZero || v != 0
+		}
+		if nonZero {
+			offsets = append(offsets, k)
+			diagonals = append(diagonals, diagonal)
+		}
+	}
+	return NewDIAOffsets(m, n, offsets, diagonals)
+}
+
+func TestDIAOffsets(t *testing.T) {
+	var tests = []struct {
+		r, c    int
+		data    []float64
+		offsets []int
+	}{
+		{
+			r: 4, c: 4,
+			data: []float64{
+				2, -1, 0, 0,
+				-1, 2, -1, 0,
+				0, -1, 2, -1,
+				0, 0, -1, 2,
+			},
+			offsets: []int{-1, 0, 1},
+		},
+		{
+			r: 3, c: 5,
+			data: []float64{
+				0, 0, 1, 0, 0,
+				4, 0, 0, 2, 0,
+				0, 5, 0, 0, 3,
+			},
+			offsets: []int{-1, 2},
+		},
+		{
+			r: 5, c: 3,
+			data: []float64{
+				1, 0, 6,
+				0, 2, 0,
+				7, 0, 3,
+				0, 8, 0,
+				0, 0, 9,
+			},
+			offsets: []int{-2, 0, 2},
+		},
+	}
+
+	for ti, test := range tests {
+		expected := mat.NewDense(test.r, test.c, test.data)
+		dia := createBandedDIA(test.r, test.c, test.data)
+
+		if !reflect.DeepEqual(test.offsets, dia.Offsets()) {
+			t.Errorf("Test %d: expected offsets %v but received %v", ti, test.offsets, dia.Offsets())
+		}
+		if !mat.Equal(expected, dia) {
+			t.Errorf("Test %d: expected\n%v\nbut received\n%v", ti, mat.Formatted(expected), mat.Formatted(dia))
+		}
+		if !mat.Equal(expected.T(), dia.T()) {
+			t.Errorf("Test %d: expected transpose\n%v\nbut received\n%v", ti, mat.Formatted(expected.T()), mat.Formatted(dia.T()))
+		}
+
+		for i := 0; i < test.r; i++ {
+			if !mat.Equal(expected.RowView(i), dia.RowView(i)) {
+				t.Errorf("Test %d: row %d mismatch: expected %v but received %v", ti, i, expected.RawRowView(i), dia.ScatterRow(i, nil))
+			}
+		}
+		for j := 0; j < test.c; j++ {
+			if !mat.Equal(expected.ColView(j), dia.ColView(j)) {
+				t.Errorf("Test %d: column %d mismatch: expected %v but received %v", ti, j, mat.Col(nil, j, expected), dia.ScatterCol(j, nil))
+			}
+		}
+
+		var nnz int
+		dia.DoNonZero(func(i, j int, v float64) {
+			if v != expected.At(i, j) {
+				t.Errorf("Test %d: expected %f at (%d, %d) but received %f", ti, expected.At(i, j), i, j, v)
+			}
+			nnz++
+		})
+		if nnz != dia.NNZ() {
+			t.Errorf("Test %d: expected %d Non Zero elements but found %d", ti, dia.NNZ(), nnz)
+		}
+
+		var tr float64
+		for i := 0; i < test.r && i < test.c; i++ {
+			tr += expected.At(i, i)
+		}
+		if tr != dia.Trace() {
+			t.Errorf("Test %d: trace mismatch: expected %f but received %f", ti, tr, dia.Trace())
+		}
+
+		for _, trans := range []bool{false, true} {
+			r, c := test.r, test.c
+			var m mat.Matrix = expected
+			if trans {
+				r, c = c, r
+				m = expected.T()
+			}
+			x := make([]float64, c)
+			for i := range x {
+				x[i] = float64(i + 1)
+			}
+			got := make([]float64, r)
+			dia.MulVecTo(got, trans, x)
+
+			var want mat.VecDense
+			want.MulVec(m, mat.NewVecDense(c, x))
+			if !mat.Equal(&want, mat.NewVecDense(r, got)) {
+				t.Errorf("Test %d: MulVecTo (trans=%t) expected %v but received %v", ti, trans, want.RawVector().Data, got)
+			}
+		}
+	}
+}
+
+func TestDIAOffsetsArithmetic(t *testing.T) {
+	a := []float64{
+		2, -1, 0, 0,
+		-1, 2, -1, 0,
+		0, -1, 2, -1,
+		3, 0, -1, 2,
+	}
+	b := []float64{
+		1, 0, 5, 0,
+		0, 2, 0, 6,
+		0, 0, 3, 0,
+		0, 0, 0, 4,
+	}
+	denseA := mat.NewDense(4, 4, a)
+	denseB := mat.NewDense(4, 4, b)
+	diaA := createBandedDIA(4, 4, a)
+	diaB := createBandedDIA(4, 4, b)
+
+	var want mat.Dense
+	want.Mul(denseA, denseB)
+
+	for name, operands := range map[string][2]mat.Matrix{
+		"DIA * DIA":   {diaA, diaB},
+		"DIA * CSR":   {diaA, CreateCSR(4, 4, b)},
+		"CSR * DIA":   {CreateCSR(4, 4, a), diaB},
+		"DIA * Dense": {diaA, denseB},
+		"Dense * DIA": {denseA, diaB},
+	} {
+		var got CSR
+		got.Mul(operands[0], operands[1])
+		if !mat.Equal(&want, &got) {
+			t.Errorf("%s: expected\n%v\nbut received\n%v", name, mat.Formatted(&want), mat.Formatted(&got))
+		}
+	}
+
+	want.Add(denseA, denseB)
+	var got CSR
+	got.Add(diaA, diaB)
+	if !mat.Equal(&want, &got) {
+		t.Errorf("DIA + DIA: expected\n%v\nbut received\n%v", mat.Formatted(&want), mat.Formatted(&got))
+	}
+}
@@ -0,0 +1,423 @@
+package sparse
+
+import (
+	"context"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// EigenTarget specifies which eigenvalues (and corresponding eigenvectors) are
+// computed by the iterative eigensolvers.
+type EigenTarget int
+
+const (
+	// LargestAlgebraic targets the eigenvalues with the largest (most positive)
+	// real part.
+	LargestAlgebraic EigenTarget = iota
+
+	// SmallestAlgebraic targets the eigenvalues with the smallest (most negative)
+	// real part.
+	SmallestAlgebraic
+
+	// LargestMagnitude targets the eigenvalues with the largest magnitude (absolute
+	// value).
+	LargestMagnitude
+
+	// SmallestMagnitude targets the eigenvalues with the smallest magnitude
+	// (absolute value).  Convergence to the smallest eigenvalues is typically slow,
+	// shift-invert mode (with a shift of 0) is usually much faster.
+	SmallestMagnitude
+)
+
+// EigenSettings holds the settings for the iterative eigensolvers.  The zero value
+// (or a nil *EigenSettings) uses the default for each setting.
+type EigenSettings struct {
+	// Tolerance is the relative tolerance for convergence of the eigenpairs i.e. an
+	// eigenpair (lambda, v) is converged when ||A*v - lambda*v|| <= Tolerance * ||A||
+	// (where ||A|| is estimated from the computed Ritz values).  If Tolerance is zero,
+	// a default of 1e-10 is used.
+	Tolerance float64
+
+	// MaxIterations is the maximum number of restarts.  If MaxIterations is zero, a
+	// default of 300 is used.
+	MaxIterations int
+
+	// SubspaceSize is the size of the Krylov subspace (the number of basis vectors
+	// held in memory) between restarts.  It must be greater than the number of
+	// eigenvalues requested.  If SubspaceSize is zero, a default of max(2k+1, 20)
+	// (limited to the size of the matrix) is used.
+	SubspaceSize int
+
+	// InitVec is the starting vector.  If InitVec is nil, a random starting vector
+	// (from a fixed seed so results are reproducible) is used.
+	InitVec []float64
+
+	// ShiftInvert specifies shift-invert mode in which the eigenvalues nearest to
+	// Shift are computed (the target is ignored) by computing the largest magnitude
+	// eigenvalues of (A - Shift*I)^-1.  The matrix must implement mat.Matrix so
+	// that A - Shift*I may be factorised by sparse LU factorisation.  If the
+	// factorisation fails (Shift is an eigenvalue of A) a *SingularError is
+	// returned.
+	ShiftInvert bool
+
+	// Shift is the shift (sigma) used in shift-invert mode.
+	Shift float64
+}
+
+// defaults returns a copy of the settings (or the default settings if s is nil)
+// with default values filled in for k eigenvalues of an n x n matrix.
+func (s *EigenSettings) defaults(n, k int) EigenSettings {
+	var settings EigenSettings
+	if s != nil {
+		settings = *s
+	}
+	if settings.Tolerance == 0 {
+		settings.Tolerance = 1e-10
+	}
+	if settings.MaxIterations == 0 {
+		settings.MaxIterations = 300
+	}
+	if settings.SubspaceSize == 0 {
+		settings.SubspaceSize = 2*k + 1
+		if settings.SubspaceSize < 20 {
+			settings.SubspaceSize = 20
+		}
+		if settings.SubspaceSize > n {
+			settings.SubspaceSize = n
+		}
+	}
+	if settings.SubspaceSize <= k || settings.SubspaceSize > n {
+		panic("sparse: subspace size must be greater than k and no greater than n")
+	}
+	if settings.InitVec != nil && len(settings.InitVec) != n {
+		panic(mat.ErrShape)
+	}
+	return settings
+}
+
+// krylovBasis is an orthonormal basis of (up to m+1) vectors of length n.
+type krylovBasis struct {
+	v   [][]float64
+	rnd *rand.Rand
+}
+
+func newKrylovBasis(n, m int, init []float64) *krylovBasis {
+	b := &krylovBasis{v: make([][]float64, m+1), rnd: rand.New(rand.NewSource(1))}
+	for i := range b.v {
+		b.v[i] = make([]float64, n)
+	}
+	if init != nil {
+		copy(b.v[0], init)
+	}
+	if init == nil || floats.Norm(b.v[0], 2) == 0 {
+		b.random(0)
+	}
+	floats.Scale(1/floats.Norm(b.v[0], 2), b.v[0])
+	return b
+}
+
+// random sets basis vector j to a random vector orthogonal to the preceding vectors.
+func (b *krylovBasis) random(j int) {
+	for i := range b.v[j] {
+		b.v[j][i] = b.rnd.NormFloat64()
+	}
+	h := make([]float64, j)
+	b.orthogonalize(b.v[j], j, h)
+}
+
+// orthogonalize orthogonalises w against the first j basis vectors (using classical
+// Gram-Schmidt with reorthogonalisation) accumulating the projection coefficients
+// into h and returning the norm of the orthogonalised w.
+func (b *krylovBasis) orthogonalize(w []float64, j int, h []float64) float64 {
+	for pass := 0; pass < 2; pass++ {
+		for i := 0; i < j; i++ {
+			c := floats.Dot(b.v[i], w)
+			h[i] += c
+			floats.AddScaled(w, -c, b.v[i])
+		}
+	}
+	return floats.Norm(w, 2)
+}
+
+// extend computes the next basis vector from w = A * v_j (orthogonalising w against
+// the first j+1 basis vectors) placing the projection coefficients into h (of length
+// j+1) and returns the norm of the orthogonalised w.  If w lies within the span of
+// the basis (an invariant subspace was found), a random vector is used instead (or
+// the zero vector if the basis spans the whole space) and zero returned.
+func (b *krylovBasis) extend(j int, w []float64, h []float64, scale float64) float64 {
+	for i := range h {
+		h[i] = 0
+	}
+	beta := b.orthogonalize(w, j+1, h)
+	if beta <= scale*epsilon*float64(len(w)) {
+		if j+1 < len(w) {
+			b.random(j + 1)
+			floats.Scale(1/floats.Norm(b.v[j+1], 2), b.v[j+1])
+		} else {
+			zero(b.v[j+1])
+		}
+		return 0
+	}
+	floats.ScaleTo(b.v[j+1], 1/beta, w)
+	return beta
+}
+
+// combine replaces the first l basis vectors with V * Y[:, cols] and sets basis
+// vector l to the (last) basis vector m.
+func (b *krylovBasis) combine(y *mat.Dense, cols []int, m int) {
+	n := len(b.v[0])
+	l := len(cols)
+	combined := make([][]float64, l)
+	for c, col := range cols {
+		combined[c] = make([]float64, n)
+		for i := 0; i < m; i++ {
+			floats.AddScaled(combined[c], y.At(i, col), b.v[i])
+		}
+	}
+	last := b.v[m]
+	for c := range combined {
+		copy(b.v[c], combined[c])
+	}
+	if l != m {
+		copy(b.v[l], last)
+	}
+}
+
+// vectors returns the basis vectors combined by the columns cols of y as the columns
+// of a dense matrix.
+func (b *krylovBasis) vectors(y *mat.Dense, cols []int, m int) *mat.Dense {
+	n := len(b.v[0])
+	x := mat.NewDense(n, len(cols), nil)
+	col := make([]float64, n)
+	for c, yc := range cols {
+		zero(col)
+		for i := 0; i < m; i++ {
+			floats.AddScaled(col, y.At(i, yc), b.v[i])
+		}
+		x.SetCol(c, col)
+	}
+	return x
+}
+
+// EigenSym computes k eigenvalues (and corresponding eigenvectors) of the n x n
+// symmetric matrix (or operator) a using the thick restart Lanczos method (which is
+// mathematically equivalent to the implicitly restarted Lanczos method) with full
+// reorthogonalisation.  Only the MulVecTo method of a is used so a may be a matrix
+// free operator (except in shift-invert mode).  target specifies which eigenvalues
+// are computed.  The eigenvalues are returned in order of preference for the target
+// (e.g. descending for LargestAlgebraic or nearest to the shift first in shift-invert
+// mode) along with the corresponding (orthonormal) eigenvectors as the columns of an
+// n x k dense matrix.  settings may be nil in which
+// case the default settings are used.  EigenSym panics if k is not in the range
+// [1, n).
+//
+// Convergence is fastest for well separated eigenvalues at the ends of the spectrum.
+// As a single starting vector is used, only one eigenvector of an eigenvalue with
+// multiplicity greater than one may be found.  If the eigenpairs do not converge
+// within the maximum number of restarts (or the context is cancelled), the current
+// approximations are returned along with a *NotConvergedError (or the error from
+// ctx).
+func EigenSym(ctx context.Context, a MulVecToer, n, k int, target EigenTarget, settings *EigenSettings) ([]float64, *mat.Dense, error) {
+	if k < 1 || k >= n {
+		panic("sparse: number of eigenvalues must be in the range [1, n)")
+	}
+	s := settings.defaults(n, k)
+	var op *shiftInvertOp
+	if s.ShiftInvert {
+		var err error
+		if op, err = newShiftInvertOp(a, s.Shift); err != nil {
+			return nil, nil, err
+		}
+		a, target = op, LargestMagnitude
+	}
+	m := s.SubspaceSize
+	basis := newKrylovBasis(n, m, s.InitVec)
+
+	t := mat.NewSymDense(m, nil)
+	h := make([]float64, m)
+	w := make([]float64, n)
+	var eig mat.EigenSym
+	var y mat.Dense
+	var scale float64
+	l := 0
+
+	for iter := 1; ; iter++ {
+		if err := ctx.Err(); err != nil {
+			values, vectors := ritzPairs(basis, &eig, &y, k, m, target, op)
+			return values, vectors, err
+		}
+
+		// extend the Lanczos factorisation A * V_m = V_m * T_m + beta * v_m * e_m^T
+		var beta float64
+		for j := l; j < m; j++ {
+			zero(w)
+			a.MulVecTo(w, false, basis.v[j])
+			beta = basis.extend(j, w, h[:j+1], scale)
+			for i := 0; i <= j; i++ {
+				t.SetSym(i, j, h[i])
+			}
+		}
+
+		if ok := eig.Factorize(t, true); !ok {
+			panic("sparse: eigendecomposition of tridiagonal matrix failed")
+		}
+		eig.VectorsTo(&y)
+		values := eig.Values(nil)
+		scale = math.Max(math.Abs(values[0]), math.Abs(values[m-1]))
+
+		// check convergence of the wanted Ritz pairs
+		wanted := wanted(complexValues(values), target)
+		converged := 0
+		for _, c := range wanted[:k] {
+			if math.Abs(beta*y.At(m-1, c)) <= s.Tolerance*scale {
+				converged++
+			}
+		}
+		if converged == k {
+			vals, vectors := ritzPairs(basis, &eig, &y, k, m, target, op)
+			return vals, vectors, nil
+		}
+		if iter == s.MaxIterations {
+			vals, vectors := ritzPairs(basis, &eig, &y, k, m, target, op)
+			residual := 0.0
+			for _, c := range wanted[:k] {
+				residual = math.Max(residual, math.Abs(beta*y.At(m-1, c))/scale)
+			}
+			return vals, vectors, &NotConvergedError{Iterations: iter, Residual: residual}
+		}
+
+		// thick restart keeping the wanted Ritz vectors (plus some extra to
+		// accelerate convergence)
+		l = k + converged
+		if extra := (m - k) / 2; l < k+extra {
+			l = k + extra
+		}
+		if l > m-1 {
+			l = m - 1
+		}
+		keep := wanted[:l]
+		basis.combine(&y, keep, m)
+		for i := 0; i < m; i++ {
+			for j := i; j < m; j++ {
+				t.SetSym(i, j, 0)
+			}
+		}
+		for i, c := range keep {
+			t.SetSym(i, i, values[c])
+		}
+	}
+}
+
+// complexValues returns the real values as complex values.
+func complexValues(values []float64) []complex128 {
+	c := make([]complex128, len(values))
+	for i, v := range values {
+		c[i] = complex(v, 0)
+	}
+	return c
+}
+
+// wanted returns the indices of the eigenvalues in order of preference for the
+// target.
+func wanted(values []complex128, target EigenTarget) []int {
+	key := func(v complex128) float64 {
+		switch target {
+		case LargestAlgebraic:
+			return -real(v)
+		case SmallestAlgebraic:
+			return real(v)
+		case LargestMagnitude:
+			return -cmplx.Abs(v)
+		case SmallestMagnitude:
+			return cmplx.Abs(v)
+		}
+		panic("sparse: unknown eigenvalue target")
+	}
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return key(values[idx[i]]) < key(values[idx[j]])
+	})
+	return idx
+}
+
+// ritzPairs returns the k wanted Ritz values and vectors from the current
+// decomposition (or nil if no decomposition has been computed).  If op is not nil,
+// the Ritz values are those of the shift-invert operator and are transformed back
+// into eigenvalues of A.
+func ritzPairs(basis *krylovBasis, eig *mat.EigenSym, y *mat.Dense, k, m int, target EigenTarget, op *shiftInvertOp) ([]float64, *mat.Dense) {
+	if y.IsEmpty() {
+		return nil, nil
+	}
+	values := eig.Values(nil)
+	wanted := wanted(complexValues(values), target)[:k]
+	vals := make([]float64, k)
+	for i, c := range wanted {
+		vals[i] = values[c]
+		if op != nil {
+			vals[i] = real(op.eigenvalue(complex(vals[i], 0)))
+		}
+	}
+	return vals, basis.vectors(y, wanted, m)
+}
+
+// shiftInvertOp is the operator (A - sigma*I)^-1 applied via the sparse LU
+// factorisation of A - sigma*I.
+type shiftInvertOp struct {
+	lu    LU
+	sigma float64
+}
+
+// newShiftInvertOp factorises A - sigma*I.  newShiftInvertOp panics if a does not
+// implement mat.Matrix.
+func newShiftInvertOp(a MulVecToer, sigma float64) (*shiftInvertOp, error) {
+	m, ok := a.(mat.Matrix)
+	if !ok {
+		panic("sparse: shift-invert mode requires a matrix")
+	}
+	r, c := m.Dims()
+	if r != c {
+		panic(mat.ErrShape)
+	}
+	dok := NewDOK(r, c)
+	if nz, ok := m.(mat.NonZeroDoer); ok {
+		nz.DoNonZero(func(i, j int, v float64) {
+			dok.Set(i, j, dok.At(i, j)+v)
+		})
+	} else {
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if v := m.At(i, j); v != 0 {
+					dok.Set(i, j, v)
+				}
+			}
+		}
+	}
+	for i := 0; i < r; i++ {
+		dok.Set(i, i, dok.At(i, i)-sigma)
+	}
+	op := &shiftInvertOp{sigma: sigma}
+	return op, op.lu.Factorize(dok)
+}
+
+// MulVecTo computes dst += (A - sigma*I)^-1 * x (or its transpose if trans is true).
+func (op *shiftInvertOp) MulVecTo(dst []float64, trans bool, x []float64) {
+	n := len(dst)
+	tmp := getFloats(n, false)
+	defer putFloats(tmp)
+	op.lu.SolveVecTo(mat.NewVecDense(n, tmp), trans, mat.NewVecDense(n, x))
+	floats.Add(dst, tmp)
+}
+
+// eigenvalue returns the eigenvalue of A corresponding to the eigenvalue nu of
+// (A - sigma*I)^-1.
+func (op *shiftInvertOp) eigenvalue(nu complex128) complex128 {
+	return complex(op.sigma, 0) + 1/nu
+}
@@ -0,0 +1,120 @@
+package sparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// checkEigenpairs checks that the eigenvectors are orthonormal and satisfy
+// A * v = lambda * v.
+func checkEigenpairs(t *testing.T, desc string, a mat.Matrix, values []float64, vectors *mat.Dense, tol float64) {
+	_, k := vectors.Dims()
+	if len(values) != k {
+		t.Fatalf("%s: expected %d eigenvalues but received %d", desc, k, len(values))
+	}
+	var vtv mat.Dense
+	vtv.Mul(vectors.T(), vectors)
+	if !mat.EqualApprox(&vtv, eye(k), 1e-8) {
+		t.Errorf("%s: expected orthonormal eigenvectors", desc)
+	}
+	for i, lambda := range values {
+		var av mat.VecDense
+		av.MulVec(a, vectors.ColView(i))
+		av.AddScaledVec(&av, -lambda, vectors.ColView(i))
+		if r := mat.Norm(&av, 2); r > tol*math.Max(1, math.Abs(lambda)) {
+			t.Errorf("%s: eigenpair %d (%v) has residual %g", desc, i, lambda, r)
+		}
+	}
+}
+
+func eye(n int) *mat.Dense {
+	d := mat.NewDense(n, n, nil)
+	for i := 0; i < n; i++ {
+		d.Set(i, i, 1)
+	}
+	return d
+}
+
+func TestEigenSym(t *testing.T) {
+	for ti, test := range []struct {
+		name string
+		a    *CSR
+		k    int
+	}{
+		{name: "Poisson2D 12x7", a: laplacian2D(12, 7), k: 4},
+		{name: "Poisson3D 6x5x4", a: laplacian3D(6, 5, 4), k: 6},
+		{name: "random symmetric", a: randomSymmetric(150, 0.05), k: 5},
+	} {
+		n, _ := test.a.Dims()
+		var dense mat.EigenSym
+		if ok := dense.Factorize(mat.NewSymDense(n, test.a.ToDense().RawMatrix().Data), false); !ok {
+			t.Fatalf("%s: dense eigendecomposition failed", test.name)
+		}
+		all := dense.Values(nil)
+
+		for _, target := range []EigenTarget{LargestAlgebraic, SmallestAlgebraic} {
+			desc := fmt.Sprintf("%d %s (target %d)", ti, test.name, target)
+			values, vectors, err := EigenSym(context.Background(), test.a, n, test.k, target, nil)
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", desc, err)
+			}
+			want := make([]float64, test.k)
+			if target == LargestAlgebraic {
+				for i := range want {
+					want[i] = all[n-1-i]
+				}
+			} else {
+				copy(want, all)
+			}
+			if !floats.EqualApprox(values, want, 1e-8) {
+				t.Errorf("%s: expected eigenvalues %v but received %v", desc, want, values)
+			}
+			checkEigenpairs(t, desc, test.a, values, vectors, 1e-8)
+		}
+	}
+}
+
+func TestEigenSymSettings(t *testing.T) {
+	a := laplacian2D(20, 13)
+	n, _ := a.Dims()
+
+	init := make([]float64, n)
+	for i := range init {
+		init[i] = 1
+	}
+	values, vectors, err := EigenSym(context.Background(), a, n, 3, LargestAlgebraic, &EigenSettings{SubspaceSize: 10, InitVec: init, Tolerance: 1e-9})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !sort.IsSorted(sort.Reverse(sort.Float64Slice(values))) {
+		t.Errorf("expected eigenvalues in descending order but received %v", values)
+	}
+	checkEigenpairs(t, "small subspace", a, values, vectors, 1e-7)
+
+	values, vectors, err = EigenSym(context.Background(), a, n, 3, SmallestAlgebraic, &EigenSettings{SubspaceSize: 8, MaxIterations: 1})
+	var notConverged *NotConvergedError
+	if !errors.As(err, &notConverged) || len(values) != 3 || vectors == nil {
+		t.Errorf("expected *NotConvergedError with current approximations but received %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err = EigenSym(ctx, a, n, 3, SmallestAlgebraic, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but received %v", err)
+	}
+
+	// the subspace may be exhausted for small matrices
+	small := laplacian2D(3, 2)
+	values, vectors, err = EigenSym(context.Background(), small, 6, 2, SmallestAlgebraic, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	checkEigenpairs(t, "small matrix", small, values, vectors, 1e-10)
+}
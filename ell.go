@@ -0,0 +1,623 @@
+package sparse
+
+import (
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+	_ Sparser       = (*ELL)(nil)
+	_ TypeConverter = (*ELL)(nil)
+
+	_ Sparser       = (*SELL)(nil)
+	_ TypeConverter = (*SELL)(nil)
+)
+
+// ELL is an ELLPACK format sparse matrix implementation.  The non-zero elements of each row are
+// packed to the left and every row is padded to the length of the longest row so that the matrix
+// is stored as 2 dense r * width arrays (one holding column indices and one holding values).  The
+// arrays are stored in column major order so that consecutive rows are adjacent in memory which
+// allows matrix vector multiplication to proceed with unit stride and without the per row
+// loop overhead of CSR.  ELL is best suited to matrices with fairly uniform row lengths as the
+// padding overhead grows with the variation in row length (see SELL and ChooseSpMVFormat).
+// ELL matrices are read only and are intended to be created by converting from CSR format.
+type ELL struct {
+	r, c   int
+	width  int
+	rowLen []int
+	ind    []int
+	data   []float64
+
+	// full is the length of the shortest row so the first full column slices of
+	// ind and data contain no padding.  tails holds the rows longer than full in
+	// descending order of length so that the rows with an element in slice k
+	// (k >= full) are tails[:active[k-full]].
+	full   int
+	tails  []int
+	active []int
+}
+
+// ToELL returns an ELLPACK sparse format version of the matrix.  The returned ELL matrix
+// will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (c *CSR) ToELL() *ELL {
+	r, cols := c.Dims()
+	e := &ELL{r: r, c: cols, rowLen: make([]int, r)}
+	for i := 0; i < r; i++ {
+		e.rowLen[i] = c.matrix.Indptr[i+1] - c.matrix.Indptr[i]
+		if e.rowLen[i] > e.width {
+			e.width = e.rowLen[i]
+		}
+	}
+	e.ind = make([]int, r*e.width)
+	e.data = make([]float64, r*e.width)
+
+	for i := 0; i < r; i++ {
+		begin, end := c.matrix.Indptr[i], c.matrix.Indptr[i+1]
+		var pad int
+		for k := begin; k < end; k++ {
+			e.ind[(k-begin)*r+i] = c.matrix.Ind[k]
+			e.data[(k-begin)*r+i] = c.matrix.Data[k]
+			pad = c.matrix.Ind[k]
+		}
+		// padding reuses a valid column index with a zero value but is never
+		// read when multiplying so that Inf or NaN elements of x are not
+		// propagated into rows via 0 * Inf
+		for k := end - begin; k < e.width; k++ {
+			e.ind[k*r+i] = pad
+		}
+	}
+
+	if r > 0 {
+		e.full = e.width
+		for _, l := range e.rowLen {
+			if l < e.full {
+				e.full = l
+			}
+		}
+	}
+	for i, l := range e.rowLen {
+		if l > e.full {
+			e.tails = append(e.tails, i)
+		}
+	}
+	sort.SliceStable(e.tails, func(a, b int) bool {
+		return e.rowLen[e.tails[a]] > e.rowLen[e.tails[b]]
+	})
+	e.active = make([]int, e.width-e.full)
+	n := len(e.tails)
+	for k := range e.active {
+		for n > 0 && e.rowLen[e.tails[n-1]] <= e.full+k {
+			n--
+		}
+		e.active[k] = n
+	}
+	return e
+}
+
+// Dims returns the size of the matrix as the number of rows and columns
+func (e *ELL) Dims() (int, int) {
+	return e.r, e.c
+}
+
+// At returns the element of the matrix located at row i and column j.  At will panic if specified values
+// for i or j fall outside the dimensions of the matrix.
+func (e *ELL) At(i, j int) float64 {
+	if uint(i) < 0 || uint(i) >= uint(e.r) {
+		panic(mat.ErrRowAccess)
+	}
+	if uint(j) < 0 || uint(j) >= uint(e.c) {
+		panic(mat.ErrColAccess)
+	}
+	for k := 0; k < e.rowLen[i]; k++ {
+		if e.ind[k*e.r+i] == j {
+			return e.data[k*e.r+i]
+		}
+	}
+	return 0
+}
+
+// T transposes the matrix.  This is an implicit transpose, wrapping the matrix in a mat.Transpose type.
+func (e *ELL) T() mat.Matrix {
+	return mat.Transpose{Matrix: e}
+}
+
+// NNZ returns the Number of Non Zero elements in the sparse matrix.  Padding is not included.
+func (e *ELL) NNZ() int {
+	var nnz int
+	for _, l := range e.rowLen {
+		nnz += l
+	}
+	return nnz
+}
+
+// Width returns the number of stored elements per row i.e. the length of the longest row.
+func (e *ELL) Width() int {
+	return e.width
+}
+
+// DoNonZero calls the function fn for each of the non-zero elements of the receiver.
+// The function fn takes a row/column index and the element value of the receiver at
+// (i, j).  The order of visiting to each non-zero element is row major.
+func (e *ELL) DoNonZero(fn func(i, j int, v float64)) {
+	for i := 0; i < e.r; i++ {
+		for k := 0; k < e.rowLen[i]; k++ {
+			fn(i, e.ind[k*e.r+i], e.data[k*e.r+i])
+		}
+	}
+}
+
+// MulVecTo performs matrix vector multiplication (dst+=A*x or dst+=A^T*x), where A is
+// the receiver, and stores the result in dst.  MulVecTo panics if ac != len(x) or
+// ar != len(dst)
+func (e *ELL) MulVecTo(dst []float64, trans bool, x []float64) {
+	ar, ac := e.r, e.c
+	if trans {
+		ar, ac = ac, ar
+	}
+	if ac != len(x) || ar != len(dst) {
+		panic(mat.ErrShape)
+	}
+
+	// the slices without padding are processed branch free with unit stride
+	// and the remaining slices only for the rows with an element in them
+	if trans {
+		for k := 0; k < e.full; k++ {
+			ind := e.ind[k*e.r : (k+1)*e.r]
+			data := e.data[k*e.r : (k+1)*e.r]
+			for i, v := range data {
+				dst[ind[i]] += v * x[i]
+			}
+		}
+		for k := e.full; k < e.width; k++ {
+			ind := e.ind[k*e.r : (k+1)*e.r]
+			data := e.data[k*e.r : (k+1)*e.r]
+			for _, i := range e.tails[:e.active[k-e.full]] {
+				dst[ind[i]] += data[i] * x[i]
+			}
+		}
+		return
+	}
+
+	for k := 0; k < e.full; k++ {
+		ind := e.ind[k*e.r : (k+1)*e.r]
+		data := e.data[k*e.r : (k+1)*e.r]
+		for i, v := range data {
+			dst[i] += v * x[ind[i]]
+		}
+	}
+	for k := e.full; k < e.width; k++ {
+		ind := e.ind[k*e.r : (k+1)*e.r]
+		data := e.data[k*e.r : (k+1)*e.r]
+		for _, i := range e.tails[:e.active[k-e.full]] {
+			dst[i] += data[i] * x[ind[i]]
+		}
+	}
+}
+
+// ToDense returns a mat.Dense dense format version of the matrix.  The returned mat.Dense
+// matrix will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (e *ELL) ToDense() *mat.Dense {
+	return e.ToCSR().ToDense()
+}
+
+// ToDOK returns a DOK (Dictionary Of Keys) sparse format version of the matrix.  The returned DOK
+// matrix will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (e *ELL) ToDOK() *DOK {
+	return e.ToCSR().ToDOK()
+}
+
+// ToCOO returns a COOrdinate sparse format version of the matrix.  The returned COO matrix will
+// not share underlying storage with the receiver nor is the receiver modified by this call.
+func (e *ELL) ToCOO() *COO {
+	return e.ToCSR().ToCOO()
+}
+
+// ToCSR returns a Compressed Sparse Row sparse format version of the matrix.  The returned CSR matrix
+// will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (e *ELL) ToCSR() *CSR {
+	nnz := e.NNZ()
+	indptr := make([]int, e.r+1)
+	ind := make([]int, 0, nnz)
+	data := make([]float64, 0, nnz)
+	for i := 0; i < e.r; i++ {
+		for k := 0; k < e.rowLen[i]; k++ {
+			ind = append(ind, e.ind[k*e.r+i])
+			data = append(data, e.data[k*e.r+i])
+		}
+		indptr[i+1] = len(ind)
+	}
+	return NewCSR(e.r, e.c, indptr, ind, data)
+}
+
+// ToCSC returns a Compressed Sparse Column sparse format version of the matrix.  The returned CSC matrix
+// will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (e *ELL) ToCSC() *CSC {
+	return e.ToCSR().ToCSC()
+}
+
+// ToType returns an alternative format version fo the matrix in the format specified.
+func (e *ELL) ToType(matType MatrixType) mat.Matrix {
+	return matType.Convert(e)
+}
+
+// SELL is a Sliced ELLPACK (SELL-C-σ) format sparse matrix implementation.  Rows are grouped into
+// chunks of C consecutive rows and each chunk is stored in ELLPACK (column major) format, padded
+// only to the length of the longest row within the chunk rather than the longest row in the whole
+// matrix.  To further reduce padding, rows are sorted by length within windows of σ rows before
+// being grouped into chunks.  This retains the vectorisation friendly layout of ELL while
+// significantly reducing the padding overhead for matrices with varying row lengths.
+// SELL matrices are read only and are intended to be created by converting from CSR format.
+type SELL struct {
+	r, c  int
+	chunk int
+	sigma int
+
+	// perm maps the sorted position of each row to the original row index and
+	// pos is the inverse mapping from original row index to sorted position
+	perm []int
+	pos  []int
+
+	// rowLen contains the number of non-zero elements of each row by sorted position
+	rowLen []int
+
+	// chunkPtr contains the offsets into ind and data of the start of each chunk
+	chunkPtr []int
+
+	// chunkFull contains the length of the shortest row of each chunk i.e. the
+	// number of column slices of the chunk containing no padding
+	chunkFull []int
+
+	ind  []int
+	data []float64
+}
+
+// sellPermutation returns the ordering of rows, by length, within windows of sigma rows
+// along with the number of elements stored (including padding) when the rows are sliced
+// into chunks of chunk rows.
+func sellPermutation(rowLen []int, chunk, sigma int) (perm []int, stored int) {
+	r := len(rowLen)
+	perm = make([]int, r)
+	for i := range perm {
+		perm[i] = i
+	}
+	if sigma > 1 {
+		for begin := 0; begin < r; begin += sigma {
+			end := begin + sigma
+			if end > r {
+				end = r
+			}
+			window := perm[begin:end]
+			sort.SliceStable(window, func(a, b int) bool {
+				return rowLen[window[a]] > rowLen[window[b]]
+			})
+		}
+	}
+	for begin := 0; begin < r; begin += chunk {
+		var width int
+		for p := begin; p < begin+chunk && p < r; p++ {
+			if rowLen[perm[p]] > width {
+				width = rowLen[perm[p]]
+			}
+		}
+		stored += width * chunk
+	}
+	return perm, stored
+}
+
+// ToSELL returns a Sliced ELLPACK (SELL-C-σ) sparse format version of the matrix with chunks of
+// chunk rows and rows sorted by length within windows of sigma rows.  A sigma of 1 disables sorting.
+// The returned SELL matrix will not share underlying storage with the receiver nor is the receiver
+// modified by this call.  ToSELL will panic if chunk or sigma are less than 1.
+func (c *CSR) ToSELL(chunk, sigma int) *SELL {
+	if chunk < 1 || sigma < 1 {
+		panic(mat.ErrShape)
+	}
+	r, cols := c.Dims()
+	lens := make([]int, r)
+	for i := range lens {
+		lens[i] = c.matrix.Indptr[i+1] - c.matrix.Indptr[i]
+	}
+	perm, stored := sellPermutation(lens, chunk, sigma)
+
+	nchunks := (r + chunk - 1) / chunk
+	s := &SELL{
+		r: r, c: cols,
+		chunk:     chunk,
+		sigma:     sigma,
+		perm:      perm,
+		pos:       make([]int, r),
+		rowLen:    make([]int, r),
+		chunkPtr:  make([]int, nchunks+1),
+		chunkFull: make([]int, nchunks),
+		ind:       make([]int, stored),
+		data:      make([]float64, stored),
+	}
+
+	for p, i := range perm {
+		s.pos[i] = p
+	}
+
+	for ch := 0; ch < nchunks; ch++ {
+		var width int
+		full := -1
+		for t := 0; t < chunk && ch*chunk+t < r; t++ {
+			p := ch*chunk + t
+			s.rowLen[p] = lens[perm[p]]
+			if s.rowLen[p] > width {
+				width = s.rowLen[p]
+			}
+			if full == -1 || s.rowLen[p] < full {
+				full = s.rowLen[p]
+			}
+		}
+		s.chunkFull[ch] = full
+		offset := s.chunkPtr[ch]
+		s.chunkPtr[ch+1] = offset + width*chunk
+
+		for t := 0; t < chunk && ch*chunk+t < r; t++ {
+			i := perm[ch*chunk+t]
+			begin, end := c.matrix.Indptr[i], c.matrix.Indptr[i+1]
+			var pad int
+			for k := begin; k < end; k++ {
+				s.ind[offset+(k-begin)*chunk+t] = c.matrix.Ind[k]
+				s.data[offset+(k-begin)*chunk+t] = c.matrix.Data[k]
+				pad = c.matrix.Ind[k]
+			}
+			// padding is skipped when multiplying (see ToELL)
+			for k := end - begin; k < width; k++ {
+				s.ind[offset+k*chunk+t] = pad
+			}
+		}
+	}
+	return s
+}
+
+// Dims returns the size of the matrix as the number of rows and columns
+func (s *SELL) Dims() (int, int) {
+	return s.r, s.c
+}
+
+// ChunkSize returns the chunk size (C) and sorting window (σ) of the matrix.
+func (s *SELL) ChunkSize() (chunk, sigma int) {
+	return s.chunk, s.sigma
+}
+
+// At returns the element of the matrix located at row i and column j.  At will panic if specified values
+// for i or j fall outside the dimensions of the matrix.
+func (s *SELL) At(i, j int) float64 {
+	if uint(i) < 0 || uint(i) >= uint(s.r) {
+		panic(mat.ErrRowAccess)
+	}
+	if uint(j) < 0 || uint(j) >= uint(s.c) {
+		panic(mat.ErrColAccess)
+	}
+	p := s.pos[i]
+	offset, t := s.chunkPtr[p/s.chunk], p%s.chunk
+	for k := 0; k < s.rowLen[p]; k++ {
+		if s.ind[offset+k*s.chunk+t] == j {
+			return s.data[offset+k*s.chunk+t]
+		}
+	}
+	return 0
+}
+
+// T transposes the matrix.  This is an implicit transpose, wrapping the matrix in a mat.Transpose type.
+func (s *SELL) T() mat.Matrix {
+	return mat.Transpose{Matrix: s}
+}
+
+// NNZ returns the Number of Non Zero elements in the sparse matrix.  Padding is not included.
+func (s *SELL) NNZ() int {
+	var nnz int
+	for _, l := range s.rowLen {
+		nnz += l
+	}
+	return nnz
+}
+
+// DoNonZero calls the function fn for each of the non-zero elements of the receiver.
+// The function fn takes a row/column index and the element value of the receiver at
+// (i, j).  The order of visiting to each non-zero element is by row in the sorted row order.
+func (s *SELL) DoNonZero(fn func(i, j int, v float64)) {
+	for p, i := range s.perm {
+		offset, t := s.chunkPtr[p/s.chunk], p%s.chunk
+		for k := 0; k < s.rowLen[p]; k++ {
+			fn(i, s.ind[offset+k*s.chunk+t], s.data[offset+k*s.chunk+t])
+		}
+	}
+}
+
+// MulVecTo performs matrix vector multiplication (dst+=A*x or dst+=A^T*x), where A is
+// the receiver, and stores the result in dst.  MulVecTo panics if ac != len(x) or
+// ar != len(dst)
+func (s *SELL) MulVecTo(dst []float64, trans bool, x []float64) {
+	ar, ac := s.r, s.c
+	if trans {
+		ar, ac = ac, ar
+	}
+	if ac != len(x) || ar != len(dst) {
+		panic(mat.ErrShape)
+	}
+
+	nchunks := len(s.chunkPtr) - 1
+	if trans {
+		for ch := 0; ch < nchunks; ch++ {
+			rows := s.perm[ch*s.chunk:]
+			if len(rows) > s.chunk {
+				rows = rows[:s.chunk]
+			}
+			offset, full := s.chunkPtr[ch], s.chunkFull[ch]
+			for k := offset; k < offset+full*s.chunk; k += s.chunk {
+				ind := s.ind[k : k+len(rows)]
+				data := s.data[k : k+len(rows)]
+				for t, v := range data {
+					dst[ind[t]] += v * x[rows[t]]
+				}
+			}
+			for t, l := range s.rowLen[ch*s.chunk : ch*s.chunk+len(rows)] {
+				for k := offset + full*s.chunk + t; k < offset+l*s.chunk; k += s.chunk {
+					dst[s.ind[k]] += s.data[k] * x[rows[t]]
+				}
+			}
+		}
+		return
+	}
+
+	sum := getFloats(s.chunk, true)
+	for ch := 0; ch < nchunks; ch++ {
+		rows := s.perm[ch*s.chunk:]
+		if len(rows) > s.chunk {
+			rows = rows[:s.chunk]
+		}
+		acc := sum[:len(rows)]
+		// the column slices without padding are processed branch free and then
+		// the remaining elements of each longer row
+		offset, full := s.chunkPtr[ch], s.chunkFull[ch]
+		for k := offset; k < offset+full*s.chunk; k += s.chunk {
+			ind := s.ind[k : k+len(rows)]
+			data := s.data[k : k+len(rows)]
+			for t, v := range data {
+				acc[t] += v * x[ind[t]]
+			}
+		}
+		for t, l := range s.rowLen[ch*s.chunk : ch*s.chunk+len(rows)] {
+			for k := offset + full*s.chunk + t; k < offset+l*s.chunk; k += s.chunk {
+				acc[t] += s.data[k] * x[s.ind[k]]
+			}
+		}
+		for t, i := range rows {
+			dst[i] += acc[t]
+			acc[t] = 0
+		}
+	}
+	putFloats(sum)
+}
+
+// ToDense returns a mat.Dense dense format version of the matrix.  The returned mat.Dense
+// matrix will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (s *SELL) ToDense() *mat.Dense {
+	return s.ToCSR().ToDense()
+}
+
+// ToDOK returns a DOK (Dictionary Of Keys) sparse format version of the matrix.  The returned DOK
+// matrix will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (s *SELL) ToDOK() *DOK {
+	return s.ToCSR().ToDOK()
+}
+
+// ToCOO returns a COOrdinate sparse format version of the matrix.  The returned COO matrix will
+// not share underlying storage with the receiver nor is the receiver modified by this call.
+func (s *SELL) ToCOO() *COO {
+	return s.ToCSR().ToCOO()
+}
+
+// ToCSR returns a Compressed Sparse Row sparse format version of the matrix.  The returned CSR matrix
+// will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (s *SELL) ToCSR() *CSR {
+	indptr := make([]int, s.r+1)
+	for p, i := range s.perm {
+		indptr[i+1] = s.rowLen[p]
+	}
+	for i := 0; i < s.r; i++ {
+		indptr[i+1] += indptr[i]
+	}
+	nnz := indptr[s.r]
+	ind := make([]int, nnz)
+	data := make([]float64, nnz)
+	for p, i := range s.perm {
+		offset, t := s.chunkPtr[p/s.chunk], p%s.chunk
+		for k := 0; k < s.rowLen[p]; k++ {
+			ind[indptr[i]+k] = s.ind[offset+k*s.chunk+t]
+			data[indptr[i]+k] = s.data[offset+k*s.chunk+t]
+		}
+	}
+	return NewCSR(s.r, s.c, indptr, ind, data)
+}
+
+// ToCSC returns a Compressed Sparse Column sparse format version of the matrix.  The returned CSC matrix
+// will not share underlying storage with the receiver nor is the receiver modified by this call.
+func (s *SELL) ToCSC() *CSC {
+	return s.ToCSR().ToCSC()
+}
+
+// ToType returns an alternative format version fo the matrix in the format specified.
+func (s *SELL) ToType(matType MatrixType) mat.Matrix {
+	return matType.Convert(s)
+}
+
+// PaddingStats summarises the storage required to hold a matrix in the padded ELL and SELL
+// formats relative to the number of non-zero elements.  It can be used to decide whether
+// a padded format is worthwhile for matrix vector multiplication.
+type PaddingStats struct {
+	// NNZ is the number of non-zero elements in the matrix
+	NNZ int
+
+	// ELLStored is the number of elements stored (including padding) in ELL format
+	ELLStored int
+
+	// SELLStored is the number of elements stored (including padding) in SELL format
+	SELLStored int
+}
+
+// ELLOverhead returns the proportion of additional (padding) elements stored by ELL format
+// relative to the number of non-zero elements e.g. 0.25 represents 25% additional storage.
+func (p PaddingStats) ELLOverhead() float64 {
+	return overhead(p.ELLStored, p.NNZ)
+}
+
+// SELLOverhead returns the proportion of additional (padding) elements stored by SELL format
+// relative to the number of non-zero elements e.g. 0.25 represents 25% additional storage.
+func (p PaddingStats) SELLOverhead() float64 {
+	return overhead(p.SELLStored, p.NNZ)
+}
+
+func overhead(stored, nnz int) float64 {
+	if nnz == 0 {
+		return 0
+	}
+	return float64(stored-nnz) / float64(nnz)
+}
+
+// Padding calculates the padding overhead of converting the matrix a into ELL format and SELL
+// format with the specified chunk size and sorting window without actually performing the conversions.
+// Padding will panic if chunk or sigma are less than 1.
+func Padding(a *CSR, chunk, sigma int) PaddingStats {
+	if chunk < 1 || sigma < 1 {
+		panic(mat.ErrShape)
+	}
+	r, _ := a.Dims()
+	lens := make([]int, r)
+	var width int
+	for i := range lens {
+		lens[i] = a.matrix.Indptr[i+1] - a.matrix.Indptr[i]
+		if lens[i] > width {
+			width = lens[i]
+		}
+	}
+	_, stored := sellPermutation(lens, chunk, sigma)
+	return PaddingStats{
+		NNZ:        a.NNZ(),
+		ELLStored:  width * r,
+		SELLStored: stored,
+	}
+}
+
+// ChooseSpMVFormat selects a format for efficient repeated matrix vector multiplication with
+// the matrix a based upon the padding overhead of the ELL and SELL formats.  ELL format is chosen
+// if its padding overhead is no more than maxOverhead, failing that SELL format with the specified
+// chunk size and sorting window is chosen if its padding overhead is no more than maxOverhead,
+// otherwise CSR format is chosen.  ChooseSpMVFormat will panic if chunk or sigma are less than 1.
+// The returned MatrixType may be used to perform the conversion e.g.
+//
+//	m := a.ToType(ChooseSpMVFormat(a, 8, 64, 0.2))
+func ChooseSpMVFormat(a *CSR, chunk, sigma int, maxOverhead float64) MatrixType {
+	stats := Padding(a, chunk, sigma)
+	if stats.ELLOverhead() <= maxOverhead {
+		return ELLFormat
+	}
+	if stats.SELLOverhead() <= maxOverhead {
+		return SELLFormat(chunk, sigma)
+	}
+	return CSRFormat
+}
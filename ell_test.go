@@ -0,0 +1,229 @@
+package sparse
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestELLSELLConversion(t *testing.T) {
+	var tests = []struct {
+		r, c int
+		data []float64
+	}{
+		{
+			r: 3, c: 4,
+			data: []float64{
+				1, 0, 0, 7,
+				0, 2, 4, 0,
+				3, 0, 3, 6,
+			},
+		},
+		{
+			r: 5, c: 3,
+			data: []float64{
+				0, 0, 0,
+				1, 2, 3,
+				0, 0, 4,
+				5, 0, 0,
+				0, 0, 0,
+			},
+		},
+	}
+
+	for ti, test := range tests {
+		expected := mat.NewDense(test.r, test.c, test.data)
+		csr := CreateCSR(test.r, test.c, test.data).(*CSR)
+
+		for name, m := range map[string]Sparser{
+			"ELL":      csr.ToELL(),
+			"SELL-1-1": csr.ToSELL(1, 1),
+			"SELL-2-1": csr.ToSELL(2, 1),
+			"SELL-2-4": csr.ToSELL(2, 4),
+			"SELL-4-8": csr.ToSELL(4, 8),
+		} {
+			if !mat.Equal(expected, m) {
+				t.Errorf("Test %d (%s): expected\n%v\nbut received\n%v", ti, name, mat.Formatted(expected), mat.Formatted(m))
+			}
+			if m.NNZ() != csr.NNZ() {
+				t.Errorf("Test %d (%s): expected %d non zeros but found %d", ti, name, csr.NNZ(), m.NNZ())
+			}
+			var nnz int
+			m.DoNonZero(func(i, j int, v float64) {
+				if v != expected.At(i, j) {
+					t.Errorf("Test %d (%s): unexpected value %f at (%d, %d)", ti, name, v, i, j)
+				}
+				nnz++
+			})
+			if nnz != csr.NNZ() {
+				t.Errorf("Test %d (%s): expected to visit %d non zeros but visited %d", ti, name, csr.NNZ(), nnz)
+			}
+			if !mat.Equal(expected, m.(TypeConverter).ToCSR()) {
+				t.Errorf("Test %d (%s): round trip to CSR failed", ti, name)
+			}
+			if !mat.Equal(expected.T(), m.T()) {
+				t.Errorf("Test %d (%s): transpose failed", ti, name)
+			}
+		}
+
+		if !mat.Equal(expected, csr.ToType(ELLFormat)) {
+			t.Errorf("Test %d: ToType(ELLFormat) failed", ti)
+		}
+		if !mat.Equal(expected, csr.ToType(SELLFormat(2, 4))) {
+			t.Errorf("Test %d: ToType(SELLFormat) failed", ti)
+		}
+	}
+}
+
+func TestELLSELLMulVecTo(t *testing.T) {
+	for _, size := range []struct{ r, c int }{
+		{r: 7, c: 5},
+		{r: 40, c: 30},
+		{r: 33, c: 64},
+	} {
+		csr := CreateCSR(size.r, size.c, randomData(size.r, size.c, 0.2)).(*CSR)
+
+		for name, m := range map[string]MulVecToer{
+			"ELL":       csr.ToELL(),
+			"SELL-4-1":  csr.ToSELL(4, 1),
+			"SELL-8-32": csr.ToSELL(8, 32),
+			"SELL-3-7":  csr.ToSELL(3, 7),
+		} {
+			for _, trans := range []bool{false, true} {
+				r, c := size.r, size.c
+				if trans {
+					r, c = c, r
+				}
+				x := make([]float64, c)
+				for i := range x {
+					x[i] = float64(i + 1)
+				}
+				want := make([]float64, r)
+				got := make([]float64, r)
+				for i := range want {
+					want[i] = 1
+					got[i] = 1
+				}
+				csr.MulVecTo(want, trans, x)
+				m.MulVecTo(got, trans, x)
+
+				if !mat.EqualApprox(mat.NewVecDense(r, want), mat.NewVecDense(r, got), 1e-12) {
+					t.Errorf("%s MulVecTo mismatch (trans=%t) for %v: expected %v but received %v", name, trans, size, want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestELLSELLMulVecToNonFinite(t *testing.T) {
+	// rows 1 and 3 are shorter than the longest row so are padded and padding must
+	// not propagate the non-finite elements of x into them
+	csr := CreateCSR(4, 3, []float64{
+		1, 2, 3,
+		0, 0, 0,
+		0, 4, 0,
+		0, 0, 0,
+	}).(*CSR)
+
+	for name, m := range map[string]MulVecToer{
+		"ELL":      csr.ToELL(),
+		"SELL-2-1": csr.ToSELL(2, 1),
+		"SELL-4-4": csr.ToSELL(4, 4),
+	} {
+		got := make([]float64, 4)
+		m.MulVecTo(got, false, []float64{math.Inf(1), 1, math.NaN()})
+		if got[1] != 0 || got[2] != 4 || got[3] != 0 {
+			t.Errorf("%s: expected padded rows to be unaffected by non-finite x but received %v", name, got)
+		}
+
+		got = make([]float64, 3)
+		m.MulVecTo(got, true, []float64{0, math.Inf(1), 1, math.NaN()})
+		if got[0] != 0 || got[1] != 4 || got[2] != 0 {
+			t.Errorf("%s: expected transpose to be unaffected by non-finite x in padded rows but received %v", name, got)
+		}
+	}
+}
+
+func TestChooseSpMVFormat(t *testing.T) {
+	var tests = []struct {
+		desc        string
+		r, c        int
+		data        []float64
+		maxOverhead float64
+		ellOverhead float64
+		expected    MatrixType
+	}{
+		{
+			desc: "uniform rows",
+			r:    4, c: 4,
+			data: []float64{
+				2, 1, 0, 0,
+				1, 2, 0, 0,
+				0, 0, 2, 1,
+				0, 0, 1, 2,
+			},
+			maxOverhead: 0,
+			ellOverhead: 0,
+			expected:    ELLFormat,
+		},
+		{
+			desc: "single long row",
+			r:    4, c: 4,
+			data: []float64{
+				1, 1, 1, 1,
+				0, 1, 0, 0,
+				0, 0, 1, 0,
+				0, 0, 0, 1,
+			},
+			maxOverhead: 0.5,
+			ellOverhead: 9.0 / 7.0,
+			expected:    SELLFormat(2, 4),
+		},
+		{
+			desc: "irregular rows",
+			r:    4, c: 4,
+			data: []float64{
+				1, 1, 1, 1,
+				0, 1, 0, 0,
+				0, 0, 1, 1,
+				0, 0, 0, 1,
+			},
+			maxOverhead: 0.1,
+			ellOverhead: 1,
+			expected:    CSRFormat,
+		},
+	}
+
+	for _, test := range tests {
+		csr := CreateCSR(test.r, test.c, test.data).(*CSR)
+		stats := Padding(csr, 2, 4)
+		if stats.ELLOverhead() != test.ellOverhead {
+			t.Errorf("%s: expected ELL overhead of %f but received %f", test.desc, test.ellOverhead, stats.ELLOverhead())
+		}
+		if got := ChooseSpMVFormat(csr, 2, 4, test.maxOverhead); got != test.expected {
+			t.Errorf("%s: expected format %#v but received %#v", test.desc, test.expected, got)
+		}
+		if sell := csr.ToSELL(2, 4); len(sell.data) != stats.SELLStored {
+			t.Errorf("%s: expected %d elements stored in SELL format but found %d", test.desc, stats.SELLStored, len(sell.data))
+		}
+	}
+}
+
+func TestPaddingInvalidChunk(t *testing.T) {
+	csr := CreateCSR(4, 4, randomData(4, 4, 0.5)).(*CSR)
+	for _, test := range []struct{ chunk, sigma int }{
+		{chunk: 0, sigma: 1},
+		{chunk: -2, sigma: 1},
+		{chunk: 2, sigma: 0},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != mat.ErrShape {
+					t.Errorf("chunk %d, sigma %d: expected panic with mat.ErrShape but received %v", test.chunk, test.sigma, r)
+				}
+			}()
+			ChooseSpMVFormat(csr, test.chunk, test.sigma, 0.2)
+		}()
+	}
+}
@@ -0,0 +1,136 @@
+package sparse
+
+import (
+	"context"
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+// GMRES solves the system of linear equations A * x = b for x using the restarted
+// Generalised Minimal RESidual method (GMRES(m)) where A is a general (unsymmetric)
+// square matrix (or operator).  restart is the number of iterations (m) between
+// restarts and so determines the size of the Krylov basis held in memory.  If
+// restart is zero, a default of min(n, 30) is used.  If a preconditioner is
+// specified in the settings it is applied on the right so the residual norms
+// reported are those of the unpreconditioned system.  settings may be nil in which
+// case the default settings are used.  Each iteration (rather than each restart
+// cycle) counts towards the maximum number of iterations.
+//
+// The result is returned along with a nil error if the solver converged to the
+// required tolerance.  Otherwise the result holds the best solution found, its
+// Status records the outcome and the error is a *NotConvergedError if the maximum
+// number of iterations was reached, a *BreakdownError if A is singular or the error
+// from ctx if the context was cancelled.
+func GMRES(ctx context.Context, a MulVecToer, b []float64, restart int, settings *SolverSettings) (*SolverResult, error) {
+	n := len(b)
+	if restart <= 0 {
+		restart = 30
+	}
+	if restart > n {
+		restart = n
+	}
+
+	r := make([]float64, n)
+	state := newIterativeState(ctx, a, b, settings, r)
+	x := state.result.X
+	beta := floats.Norm(r, 2)
+	if state.converged(0, beta) {
+		return state.result, nil
+	}
+
+	// Krylov basis V, Hessenberg matrix H (reduced to upper triangular R by Givens
+	// rotations stored in cs and sn) and the rotated right hand side g
+	v := make([][]float64, restart+1)
+	for i := range v {
+		v[i] = make([]float64, n)
+	}
+	h := make([][]float64, restart+1)
+	for i := range h {
+		h[i] = make([]float64, restart)
+	}
+	cs := make([]float64, restart)
+	sn := make([]float64, restart)
+	g := make([]float64, restart+1)
+	y := make([]float64, restart)
+	z := make([]float64, n)
+
+	k := 0
+	for {
+		floats.ScaleTo(v[0], 1/beta, r)
+		zero(g)
+		g[0] = beta
+
+		var j int
+		var err error
+		done := false
+		for j = 0; j < restart; j++ {
+			if err = state.next(k + 1); err != nil {
+				break
+			}
+			k++
+
+			// w = A * M^-1 * v_j orthogonalised against V by modified Gram-Schmidt
+			w := v[j+1]
+			state.precondition(z, v[j])
+			zero(w)
+			a.MulVecTo(w, false, z)
+			for i := 0; i <= j; i++ {
+				h[i][j] = floats.Dot(w, v[i])
+				floats.AddScaled(w, -h[i][j], v[i])
+			}
+			hn := floats.Norm(w, 2)
+			h[j+1][j] = hn
+
+			// apply previous rotations to the new column and eliminate h[j+1][j]
+			for i := 0; i < j; i++ {
+				h[i][j], h[i+1][j] = cs[i]*h[i][j]+sn[i]*h[i+1][j], -sn[i]*h[i][j]+cs[i]*h[i+1][j]
+			}
+			rho := math.Hypot(h[j][j], h[j+1][j])
+			if rho == 0 {
+				err = state.breakdown(k, "matrix is singular")
+				break
+			}
+			cs[j], sn[j] = h[j][j]/rho, h[j+1][j]/rho
+			h[j][j], h[j+1][j] = rho, 0
+			g[j], g[j+1] = cs[j]*g[j], -sn[j]*g[j]
+
+			// if hn is zero the Krylov subspace is invariant and g[j+1] is zero so
+			// x is the exact solution
+			if state.converged(k, math.Abs(g[j+1])) {
+				j++
+				done = true
+				break
+			}
+			floats.Scale(1/hn, w)
+		}
+
+		// x = x + M^-1 * V * y where R * y = g
+		for i := j - 1; i >= 0; i-- {
+			sum := g[i]
+			for l := i + 1; l < j; l++ {
+				sum -= h[i][l] * y[l]
+			}
+			y[i] = sum / h[i][i]
+		}
+		zero(r)
+		for i := 0; i < j; i++ {
+			floats.AddScaled(r, y[i], v[i])
+		}
+		state.precondition(z, r)
+		floats.Add(x, z)
+
+		if done || err != nil {
+			return state.result, err
+		}
+
+		// restart from the true residual r = b - A * x
+		copy(r, b)
+		state.mulVec(r, -1, x, false)
+		beta = floats.Norm(r, 2)
+		if beta == 0 {
+			state.converged(k, beta)
+			return state.result, nil
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package sparse
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// convectionDiffusion2D returns the 5 point upwind finite difference discretisation
+// of the 2D convection-diffusion operator for an nx * ny grid with the specified
+// cell Peclet number (convection acting in the positive x and y directions).
+func convectionDiffusion2D(nx, ny int, peclet float64) *CSR {
+	n := nx * ny
+	dok := NewDOK(n, n)
+	for x := 0; x < nx; x++ {
+		for y := 0; y < ny; y++ {
+			i := x*ny + y
+			dok.Set(i, i, 4+2*peclet)
+			if x > 0 {
+				dok.Set(i, i-ny, -1-peclet)
+			}
+			if x < nx-1 {
+				dok.Set(i, i+ny, -1)
+			}
+			if y > 0 {
+				dok.Set(i, i-1, -1-peclet)
+			}
+			if y < ny-1 {
+				dok.Set(i, i+1, -1)
+			}
+		}
+	}
+	return dok.ToCSR()
+}
+
+// unsymmetricSolver is the signature shared by the solvers for unsymmetric systems
+// under test.
+type unsymmetricSolver func(ctx context.Context, a MulVecToer, b []float64, settings *SolverSettings) (*SolverResult, error)
+
+var unsymmetricSolvers = []struct {
+	name  string
+	solve unsymmetricSolver
+}{
+	{
+		name: "GMRES(20)",
+		solve: func(ctx context.Context, a MulVecToer, b []float64, settings *SolverSettings) (*SolverResult, error) {
+			return GMRES(ctx, a, b, 20, settings)
+		},
+	},
+	{
+		name: "GMRES(n)",
+		solve: func(ctx context.Context, a MulVecToer, b []float64, settings *SolverSettings) (*SolverResult, error) {
+			return GMRES(ctx, a, b, len(b), settings)
+		},
+	},
+	{name: "BiCGStab", solve: BiCGStab},
+}
+
+func TestUnsymmetricSolvers(t *testing.T) {
+	a := convectionDiffusion2D(15, 15, 2)
+	n, _ := a.Dims()
+	b := randomData(n, 1, 1)
+	diag := make(diagonalPreconditioner, n)
+	for i := range diag {
+		diag[i] = a.At(i, i)
+	}
+
+	for _, solver := range unsymmetricSolvers {
+		for _, m := range []Preconditioner{nil, diag} {
+			result, err := solver.solve(context.Background(), a, b, &SolverSettings{Tolerance: 1e-10, MaxIterations: 10 * n, Preconditioner: m})
+			if err != nil {
+				t.Fatalf("%s (preconditioned=%t): unexpected error %v", solver.name, m != nil, err)
+			}
+			if result.Status != Converged {
+				t.Errorf("%s (preconditioned=%t): expected status Converged but was %v", solver.name, m != nil, result.Status)
+			}
+			if res := residual(a, b, result.X); res > 1e-9 {
+				t.Errorf("%s (preconditioned=%t): expected relative residual <= 1e-9 but was %g", solver.name, m != nil, res)
+			}
+			if len(result.History) != result.Iterations+1 {
+				t.Errorf("%s (preconditioned=%t): expected %d history entries but received %d", solver.name, m != nil, result.Iterations+1, len(result.History))
+			}
+		}
+
+		// starting from a good initial guess should take fewer iterations
+		first, _ := solver.solve(context.Background(), a, b, &SolverSettings{Tolerance: 1e-4})
+		result, err := solver.solve(context.Background(), a, b, &SolverSettings{Tolerance: 1e-10, MaxIterations: 10 * n, InitX: first.X})
+		if err != nil {
+			t.Errorf("%s: unexpected error from initial guess %v", solver.name, err)
+		}
+		if result.History[0] > 1e-4 {
+			t.Errorf("%s: expected initial residual <= 1e-4 from initial guess but was %g", solver.name, result.History[0])
+		}
+	}
+}
+
+func TestUnsymmetricSolverErrors(t *testing.T) {
+	a := convectionDiffusion2D(10, 10, 2)
+	n, _ := a.Dims()
+	b := randomData(n, 1, 1)
+
+	for _, solver := range unsymmetricSolvers {
+		result, err := solver.solve(context.Background(), a, b, &SolverSettings{MaxIterations: 3})
+		var notConverged *NotConvergedError
+		if !errors.As(err, &notConverged) {
+			t.Errorf("%s: expected *NotConvergedError but received %v", solver.name, err)
+		}
+		if result.Status != NotConverged || result.Iterations != 3 {
+			t.Errorf("%s: expected status NotConverged after 3 iterations but was %v after %d", solver.name, result.Status, result.Iterations)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		result, err = solver.solve(ctx, a, b, nil)
+		if !errors.Is(err, context.Canceled) || result.Status != Cancelled {
+			t.Errorf("%s: expected context.Canceled and status Cancelled but received %v and %v", solver.name, err, result.Status)
+		}
+
+		singular := CreateCSR(2, 2, []float64{
+			0, 0,
+			0, 1,
+		}).(*CSR)
+		result, err = solver.solve(context.Background(), singular, []float64{1, 0}, nil)
+		var breakdown *BreakdownError
+		if !errors.As(err, &breakdown) || result.Status != Breakdown {
+			t.Errorf("%s: expected *BreakdownError and status Breakdown but received %v and %v", solver.name, err, result.Status)
+		}
+	}
+}
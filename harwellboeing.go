@@ -0,0 +1,389 @@
+package sparse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fortranFormatPattern matches a Fortran format descriptor for a repeated integer or
+// real edit descriptor with an optional scale factor e.g. (10I8), (3E26.18),
+// (1P,4D20.12) or (5F15.8).
+var fortranFormatPattern = regexp.MustCompile(`^\(([+-]?\d+P,?)?(\d*)([IEDFG])(\d+)(?:\.(\d+))?(E\d+)?\)$`)
+
+// fortranFormatsPattern matches each of the Fortran format descriptors in a line.
+var fortranFormatsPattern = regexp.MustCompile(`\([^)]*\)`)
+
+// fortranFormat is a parsed Fortran format descriptor describing a line of fixed
+// width fields.
+type fortranFormat struct {
+	// repeat is the number of fields per line.
+	repeat int
+
+	// width is the width of each field in characters.
+	width int
+
+	// digits is the number of digits after the decimal point for reals.
+	digits int
+
+	// kind is the edit descriptor (I for integers or E, D, F or G for reals).
+	kind byte
+}
+
+// parseFortranFormat parses a Fortran format descriptor such as (10I8) or
+// (1P,4E20.12).
+func parseFortranFormat(s string) (fortranFormat, error) {
+	desc := strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	match := fortranFormatPattern.FindStringSubmatch(desc)
+	if match == nil {
+		return fortranFormat{}, fmt.Errorf("sparse: unsupported Fortran format %q", s)
+	}
+	f := fortranFormat{repeat: 1, kind: match[3][0]}
+	if match[2] != "" {
+		f.repeat, _ = strconv.Atoi(match[2])
+	}
+	f.width, _ = strconv.Atoi(match[4])
+	if match[5] != "" {
+		f.digits, _ = strconv.Atoi(match[5])
+	}
+	if f.repeat < 1 || f.width < 1 {
+		return fortranFormat{}, fmt.Errorf("sparse: unsupported Fortran format %q", s)
+	}
+	return f, nil
+}
+
+// String returns the Fortran format descriptor.
+func (f fortranFormat) String() string {
+	if f.kind == 'I' {
+		return fmt.Sprintf("(%dI%d)", f.repeat, f.width)
+	}
+	// 1P scales so there is a single digit before the decimal point as with
+	// strconv.FormatFloat
+	return fmt.Sprintf("(1P,%d%c%d.%d)", f.repeat, f.kind, f.width, f.digits)
+}
+
+// parseFortranFloat parses a Fortran real number which may use D as the exponent
+// letter or omit the exponent letter altogether for 3 digit exponents (e.g.
+// 0.1234-105).
+func parseFortranFloat(s string) (float64, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == 'D' || r == 'd' {
+			return 'E'
+		}
+		return r
+	}, s)
+	if !strings.ContainsAny(s, "Ee") {
+		if i := strings.LastIndexAny(s, "+-"); i > 0 {
+			s = s[:i] + "E" + s[i:]
+		}
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// hbReader reads the lines of a Harwell-Boeing file.
+type hbReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// next returns the next line.
+func (r *hbReader) next() (string, error) {
+	if r.scanner.Scan() {
+		r.line++
+		return strings.TrimRight(r.scanner.Text(), "\r"), nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", r.errorf("unexpected end of data")
+}
+
+// fields reads n fixed width fields formatted according to f.  Blank fields are read
+// as zero as in Fortran.  As n comes from the header, it is not trusted for
+// preallocation beyond maxPrealloc fields.
+func (r *hbReader) fields(n int, f fortranFormat) ([]string, error) {
+	fields := make([]string, 0, min(n, maxPrealloc))
+	for len(fields) < n {
+		line, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		for k := 0; k < f.repeat && len(fields) < n; k++ {
+			start := k * f.width
+			if start >= len(line) {
+				break
+			}
+			end := min(start+f.width, len(line))
+			field := strings.TrimSpace(line[start:end])
+			if field == "" {
+				field = "0"
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// ints reads n integers formatted according to f.
+func (r *hbReader) ints(n int, f fortranFormat) ([]int, error) {
+	fields, err := r.fields(n, f)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]int, len(fields))
+	for i, field := range fields {
+		if v[i], err = strconv.Atoi(field); err != nil {
+			return nil, r.errorf("invalid integer %q", field)
+		}
+	}
+	return v, nil
+}
+
+// floats reads n real numbers formatted according to f.
+func (r *hbReader) floats(n int, f fortranFormat) ([]float64, error) {
+	fields, err := r.fields(n, f)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]float64, len(fields))
+	for i, field := range fields {
+		if v[i], err = parseFortranFloat(field); err != nil {
+			return nil, r.errorf("invalid value %q", field)
+		}
+	}
+	return v, nil
+}
+
+// errorf returns an error describing a problem with the current line.
+func (r *hbReader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("sparse: Harwell-Boeing line %d: %s", r.line, fmt.Sprintf(format, args...))
+}
+
+// headerInts parses the whitespace separated integers of a header line.
+func (r *hbReader) headerInts(line string) ([]int, error) {
+	fields := strings.Fields(line)
+	v := make([]int, len(fields))
+	for i, field := range fields {
+		var err error
+		if v[i], err = strconv.Atoi(field); err != nil {
+			return nil, r.errorf("invalid integer %q", field)
+		}
+	}
+	return v, nil
+}
+
+// ReadHarwellBoeing reads a sparse matrix in Harwell-Boeing (or Rutherford-Boeing)
+// format from r and returns it as a CSC matrix.  Assembled real, integer and pattern
+// matrices that are unsymmetric, rectangular, symmetric or skew-symmetric (e.g.
+// RUA, RRA, RSA, RZA, PUA and PSA) are supported.  The widths of the fixed width
+// fields are determined by parsing the Fortran format descriptors in the header.
+// Pattern matrices are read with a value of 1 for each entry.  Symmetric and
+// skew-symmetric matrices (which store only the lower triangle) are expanded into
+// the full matrix.  Any right hand sides are ignored.  Complex, Hermitian and
+// elemental (unassembled) matrices are not supported.
+func ReadHarwellBoeing(r io.Reader) (*CSC, error) {
+	hb := &hbReader{scanner: bufio.NewScanner(r)}
+
+	// title and key
+	if _, err := hb.next(); err != nil {
+		return nil, err
+	}
+
+	// numbers of lines (cards) for each section (only the right hand side lines
+	// are needed as the number of values determines the number of lines)
+	line, err := hb.next()
+	if err != nil {
+		return nil, err
+	}
+	cards, err := hb.headerInts(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) < 4 {
+		return nil, hb.errorf("expected at least 4 line counts but found %d", len(cards))
+	}
+	rhsCards := 0
+	if len(cards) > 4 {
+		rhsCards = cards[4]
+	}
+
+	// matrix type and dimensions
+	if line, err = hb.next(); err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, hb.errorf("missing matrix type")
+	}
+	mxtype := strings.ToUpper(line[:3])
+	dims, err := hb.headerInts(line[3:])
+	if err != nil {
+		return nil, err
+	}
+	if len(dims) < 3 {
+		return nil, hb.errorf("expected matrix dimensions and number of entries")
+	}
+	rows, cols, nnz := dims[0], dims[1], dims[2]
+	if rows < 0 || cols < 0 || nnz < 0 || int64(cols) >= maxLen {
+		return nil, hb.errorf("invalid matrix dimensions %d x %d with %d entries", rows, cols, nnz)
+	}
+	if !strings.ContainsRune("RPI", rune(mxtype[0])) {
+		return nil, hb.errorf("unsupported matrix type %q", mxtype)
+	}
+	if !strings.ContainsRune("URSZ", rune(mxtype[1])) {
+		return nil, hb.errorf("unsupported matrix type %q", mxtype)
+	}
+	if mxtype[2] != 'A' {
+		return nil, hb.errorf("unsupported matrix type %q (only assembled matrices are supported)", mxtype)
+	}
+	symmetric := mxtype[1] == 'S' || mxtype[1] == 'Z'
+	if symmetric && rows != cols {
+		return nil, hb.errorf("symmetric matrix must be square")
+	}
+
+	// Fortran formats for the pointers, indices and values
+	if line, err = hb.next(); err != nil {
+		return nil, err
+	}
+	descs := fortranFormatsPattern.FindAllString(line, -1)
+	pattern := mxtype[0] == 'P'
+	if len(descs) < 2 || (!pattern && len(descs) < 3) {
+		return nil, hb.errorf("missing Fortran formats")
+	}
+	formats := make([]fortranFormat, len(descs))
+	for i, desc := range descs {
+		if formats[i], err = parseFortranFormat(desc); err != nil {
+			return nil, hb.errorf("%v", err)
+		}
+	}
+	if formats[0].kind != 'I' || formats[1].kind != 'I' {
+		return nil, hb.errorf("pointer and index formats must be integer formats")
+	}
+
+	// right hand side header
+	if rhsCards > 0 {
+		if _, err := hb.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	indptr, err := hb.ints(cols+1, formats[0])
+	if err != nil {
+		return nil, err
+	}
+	ind, err := hb.ints(nnz, formats[1])
+	if err != nil {
+		return nil, err
+	}
+	var data []float64
+	if pattern {
+		data = make([]float64, nnz)
+		for i := range data {
+			data[i] = 1
+		}
+	} else if data, err = hb.floats(nnz, formats[2]); err != nil {
+		return nil, err
+	}
+
+	// convert to zero based indexing and check the structure is valid
+	if indptr[0] != 1 || indptr[cols] != nnz+1 {
+		return nil, hb.errorf("invalid column pointers")
+	}
+	for j := 0; j < cols; j++ {
+		if indptr[j+1] < indptr[j] {
+			return nil, hb.errorf("invalid column pointers")
+		}
+		indptr[j]--
+	}
+	indptr[cols]--
+	for k := range ind {
+		if ind[k] < 1 || ind[k] > rows {
+			return nil, hb.errorf("row index %d is outside the %d x %d matrix", ind[k], rows, cols)
+		}
+		ind[k]--
+	}
+
+	if !symmetric {
+		return NewCSC(rows, cols, indptr, ind, data), nil
+	}
+
+	// expand symmetric matrices by mirroring the off-diagonal entries
+	sign := 1.0
+	if mxtype[1] == 'Z' {
+		sign = -1
+	}
+	coo := NewCOO(rows, cols, make([]int, 0, 2*nnz), make([]int, 0, 2*nnz), make([]float64, 0, 2*nnz))
+	for j := 0; j < cols; j++ {
+		for k := indptr[j]; k < indptr[j+1]; k++ {
+			i := ind[k]
+			coo.append(i, j, data[k])
+			if i != j {
+				coo.append(j, i, sign*data[k])
+			}
+		}
+	}
+	return coo.ToCSC(), nil
+}
+
+// WriteHarwellBoeing writes the matrix m to w in Harwell-Boeing format with the
+// specified title (of up to 72 characters) and key (of up to 8 characters).  The
+// matrix is written as a real unsymmetric (RUA) or rectangular (RRA) assembled
+// matrix with the Fortran formats chosen to fit the pointers and indices and to
+// write values with the precision necessary to be read back exactly.
+func WriteHarwellBoeing(w io.Writer, m *CSC, title, key string) error {
+	rows, cols := m.Dims()
+	raw := m.RawMatrix()
+	nnz := raw.Indptr[cols] - raw.Indptr[0]
+
+	intFormat := func(max int) fortranFormat {
+		width := len(strconv.Itoa(max)) + 1
+		return fortranFormat{repeat: 80 / width, width: width, kind: 'I'}
+	}
+	ptrFormat := intFormat(nnz + 1)
+	indFormat := intFormat(rows)
+	valFormat := fortranFormat{repeat: 3, width: 26, digits: 16, kind: 'E'}
+	lines := func(n int, f fortranFormat) int {
+		return (n + f.repeat - 1) / f.repeat
+	}
+	ptrCards, indCards, valCards := lines(cols+1, ptrFormat), lines(nnz, indFormat), lines(nnz, valFormat)
+
+	mxtype := "RUA"
+	if rows != cols {
+		mxtype = "RRA"
+	}
+
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "%-72.72s%-8.8s\n", title, key)
+	fmt.Fprintf(buf, "%14d%14d%14d%14d%14d\n", ptrCards+indCards+valCards, ptrCards, indCards, valCards, 0)
+	fmt.Fprintf(buf, "%-3s%11s%14d%14d%14d%14d\n", mxtype, "", rows, cols, nnz, 0)
+	fmt.Fprintf(buf, "%-16s%-16s%s\n", ptrFormat, indFormat, valFormat)
+
+	var line []byte
+	writeFields := func(n int, f fortranFormat, field func(k int) string) {
+		for k := 0; k < n; k++ {
+			s := field(k)
+			for i := len(s); i < f.width; i++ {
+				line = append(line, ' ')
+			}
+			line = append(line, s...)
+			if (k+1)%f.repeat == 0 || k == n-1 {
+				line = append(line, '\n')
+				buf.Write(line)
+				line = line[:0]
+			}
+		}
+	}
+	start := raw.Indptr[0]
+	writeFields(cols+1, ptrFormat, func(k int) string {
+		return strconv.Itoa(raw.Indptr[k] - start + 1)
+	})
+	writeFields(nnz, indFormat, func(k int) string {
+		return strconv.Itoa(raw.Ind[start+k] + 1)
+	})
+	writeFields(nnz, valFormat, func(k int) string {
+		return strconv.FormatFloat(raw.Data[start+k], 'E', valFormat.digits, 64)
+	})
+	return buf.Flush()
+}
@@ -0,0 +1,232 @@
+package sparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestParseFortranFormat(t *testing.T) {
+	tests := []struct {
+		desc string
+		want fortranFormat
+	}{
+		{desc: "(10I8)", want: fortranFormat{repeat: 10, width: 8, kind: 'I'}},
+		{desc: "(I5)", want: fortranFormat{repeat: 1, width: 5, kind: 'I'}},
+		{desc: "(3E26.18)", want: fortranFormat{repeat: 3, width: 26, digits: 18, kind: 'E'}},
+		{desc: "(1P,4D20.12)", want: fortranFormat{repeat: 4, width: 20, digits: 12, kind: 'D'}},
+		{desc: "(1P5E16.8)", want: fortranFormat{repeat: 5, width: 16, digits: 8, kind: 'E'}},
+		{desc: " ( 5f15.8 ) ", want: fortranFormat{repeat: 5, width: 15, digits: 8, kind: 'F'}},
+		{desc: "(4E20.12E3)", want: fortranFormat{repeat: 4, width: 20, digits: 12, kind: 'E'}},
+	}
+	for _, test := range tests {
+		f, err := parseFortranFormat(test.desc)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.desc, err)
+			continue
+		}
+		if f != test.want {
+			t.Errorf("%s: expected %+v but received %+v", test.desc, test.want, f)
+		}
+	}
+
+	for _, desc := range []string{"", "10I8", "(10A8)", "(10I)", "(2I4,3E10.2)", "(0I8)"} {
+		if _, err := parseFortranFormat(desc); err == nil {
+			t.Errorf("%s: expected error but received nil", desc)
+		}
+	}
+}
+
+func TestParseFortranFloat(t *testing.T) {
+	tests := map[string]float64{
+		"1.5":         1.5,
+		"-2.5E+02":    -250,
+		"4.0000D+00":  4,
+		"0.3000d+001": 3,
+		"0.1234-105":  0.1234e-105,
+		"-0.5+003":    -500,
+		"7":           7,
+	}
+	for s, want := range tests {
+		v, err := parseFortranFloat(s)
+		if err != nil || v != want {
+			t.Errorf("%s: expected %v but received %v (%v)", s, want, v, err)
+		}
+	}
+}
+
+func TestReadHarwellBoeing(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+		r, c  int
+		data  []float64
+	}{
+		{
+			desc: "RSA",
+			input: `Symmetric test matrix                                                   RSA4
+             5             1             2             2             0
+RSA                        4             4             7             0
+(5I3)           (4I3)           (1P,2D12.4)
+  1  4  5  7  8
+  1  2  4  2
+  3  4  4
+  4.0000D+00  1.0000D+00
+  2.0000D+00  5.0000D+00
+  6.0000D+00  0.3000+001
+  7.0000D+00
+`,
+			r: 4, c: 4,
+			data: []float64{
+				4, 1, 0, 2,
+				1, 5, 0, 0,
+				0, 0, 6, 3,
+				2, 0, 3, 7,
+			},
+		},
+		{
+			desc: "RZA",
+			input: `Skew-symmetric test matrix                                              RZA3
+             3             1             1             1
+RZA                        3             3             2             0
+(4I2)           (2I2)           (2E10.3)
+ 1 3 3 3
+ 2 3
+ 1.000E+00-2.000E+00
+`,
+			r: 3, c: 3,
+			data: []float64{
+				0, -1, 2,
+				1, 0, 0,
+				-2, 0, 0,
+			},
+		},
+		{
+			desc: "PUA",
+			input: `Pattern test matrix                                                     PUA3
+             3             1             2             0             0
+PUA                        3             3             4             0
+(4I4)           (3I4)
+   1   3   4   5
+   1   3   2
+   3
+`,
+			r: 3, c: 3,
+			data: []float64{
+				1, 0, 0,
+				0, 1, 0,
+				1, 0, 1,
+			},
+		},
+		{
+			desc: "RRA with right hand side",
+			input: `Rectangular test matrix with right hand side                            RRA23
+             5             1             1             1             1
+RRA                        2             3             3             0
+(4I5)           (3I5)           (3F10.4)            (2F10.4)
+F                          1             0
+    1    2    3    4
+    2    1    2
+    1.5000   -2.0000    3.2500
+    1.0000    1.0000
+`,
+			r: 2, c: 3,
+			data: []float64{
+				0, -2, 0,
+				1.5, 0, 3.25,
+			},
+		},
+		{
+			desc: "Rutherford-Boeing iua",
+			input: `Rutherford-Boeing integer test matrix                                   IUA2
+             3             1             1             1
+iua                        2             2             3             0
+(3I8)           (3I8)           (3I8)
+       1       3       4
+       1       2       2
+       1       2       3
+`,
+			r: 2, c: 2,
+			data: []float64{
+				1, 0,
+				2, 3,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		m, err := ReadHarwellBoeing(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.desc, err)
+			continue
+		}
+		e := mat.NewDense(test.r, test.c, test.data)
+		if !mat.Equal(e, m) {
+			t.Errorf("%s: expected\n%v\nbut received\n%v\n", test.desc, mat.Formatted(e), mat.Formatted(m))
+		}
+	}
+}
+
+func TestReadHarwellBoeingErrors(t *testing.T) {
+	const title = "Test matrix                                                             TEST\n"
+	tests := map[string]string{
+		"empty":        "",
+		"missing type": title + "3 1 1 1 0\n",
+		"complex":      title + "3 1 1 1 0\nCUA 2 2 2 0\n(3I2) (2I2) (2E10.3)\n 1 2 3\n 1 2\n 1.0 1.0\n",
+		"elemental":    title + "3 1 1 1 0\nRUE 2 2 2 0\n(3I2) (2I2) (2E10.3)\n 1 2 3\n 1 2\n 1.0 1.0\n",
+		"non-square":   title + "3 1 1 1 0\nRSA 2 3 2 0\n(4I2) (2I2) (2E10.3)\n 1 2 3 3\n 1 2\n 1.0 1.0\n",
+		"bad format":   title + "3 1 1 1 0\nRUA 2 2 2 0\n(3X2) (2I2) (2E10.3)\n 1 2 3\n 1 2\n 1.0 1.0\n",
+		"truncated":    title + "3 1 1 1 0\nRUA 2 2 2 0\n(3I2) (2I2) (2E10.3)\n 1 2 3\n 1 2\n",
+		"bad pointers": title + "3 1 1 1 0\nRUA 2 2 2 0\n(3I2) (2I2) (2E10.3)\n 1 3 2\n 1 2\n 1.0 1.0\n",
+		"bad index":    title + "3 1 1 1 0\nRUA 2 2 2 0\n(3I2) (2I2) (2E10.3)\n 1 2 3\n 1 3\n 1.0 1.0\n",
+		"bad value":    title + "3 1 1 1 0\nRUA 2 2 2 0\n(3I2) (2I2) (2E10.3)\n 1 2 3\n 1 2\n       abc 1.0\n",
+		"huge nnz":     title + "3 1 1 1 0\nRUA 2 2 9223372036854775807 0\n(3I2) (2I2) (2E10.3)\n 1 2 3\n 1 2\n 1.0 1.0\n",
+		"huge ncol":    title + "3 1 1 1 0\nRUA 2 9223372036854775807 2 0\n(3I2) (2I2) (2E10.3)\n 1 2 3\n 1 2\n 1.0 1.0\n",
+		"large nnz":    title + "3 1 1 1 0\nRUA 2 2 2000000000 0\n(3I2) (2I2) (2E10.3)\n 1 2 3\n 1 2\n 1.0 1.0\n",
+	}
+	for desc, input := range tests {
+		if _, err := ReadHarwellBoeing(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected error but received nil", desc)
+		}
+	}
+}
+
+func TestHarwellBoeingRoundTrip(t *testing.T) {
+	tests := []struct {
+		r, c    int
+		density float64
+	}{
+		{r: 30, c: 30, density: 0.1},
+		{r: 120, c: 15, density: 0.2},
+		{r: 5, c: 1000, density: 0.05},
+	}
+
+	for _, test := range tests {
+		data := randomData(test.r, test.c, test.density)
+		data[0] = -1.0 / 3.0
+		data[len(data)-1] = 1e-300
+		m := CreateCSC(test.r, test.c, data).(*CSC)
+
+		var buf bytes.Buffer
+		if err := WriteHarwellBoeing(&buf, m, "Round trip test matrix", "RT"); err != nil {
+			t.Fatalf("%d x %d: unexpected error writing: %v", test.r, test.c, err)
+		}
+		for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if len(line) > 80 {
+				t.Errorf("%d x %d: line %d is longer than 80 characters: %q", test.r, test.c, i+1, line)
+			}
+		}
+		read, err := ReadHarwellBoeing(&buf)
+		if err != nil {
+			t.Fatalf("%d x %d: unexpected error reading: %v", test.r, test.c, err)
+		}
+		if read.NNZ() != m.NNZ() {
+			t.Errorf("%d x %d: expected %d non zero elements but received %d", test.r, test.c, m.NNZ(), read.NNZ())
+		}
+		if !mat.Equal(m, read) {
+			t.Errorf("%d x %d: expected\n%v\nbut received\n%v\n", test.r, test.c, mat.Formatted(m), mat.Formatted(read))
+		}
+	}
+}
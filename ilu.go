@@ -0,0 +1,382 @@
+package sparse
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/james-bowman/sparse/blas"
+	"gonum.org/v1/gonum/mat"
+)
+
+// IC0 is an incomplete Cholesky factorisation with zero fill-in (IC(0)) of a
+// symmetric positive definite matrix A such that A ≈ L * L^T where L is lower
+// triangular with the same sparsity pattern as the lower triangle of A.  IC0
+// implements the Preconditioner interface for use with CG/PCG.
+type IC0 struct {
+	l *CSR
+}
+
+// Factorize computes the incomplete Cholesky factorisation of the symmetric matrix a.
+// Only the lower triangle (and diagonal) of a is used.  If a pivot is not positive
+// (which may happen for positive definite matrices that are not diagonally dominant)
+// a *NotPositiveDefiniteError is returned and the factorisation may not be used.
+func (ic *IC0) Factorize(a *CSR) error {
+	r, c := a.Dims()
+	if r != c {
+		panic(mat.ErrShape)
+	}
+	indptr, ind, data := sortedRows(a, func(i, j int) bool { return j <= i })
+
+	// pos maps columns to the positions of their elements in the current row
+	pos := getInts(r, false)
+	defer putInts(pos)
+	for i := range pos {
+		pos[i] = -1
+	}
+
+	for i := 0; i < r; i++ {
+		begin, end := indptr[i], indptr[i+1]
+		if begin == end || ind[end-1] != i {
+			return &NotPositiveDefiniteError{Index: i}
+		}
+		for p := begin; p < end; p++ {
+			pos[ind[p]] = p
+		}
+
+		// l_ij = (a_ij - sum_k<j l_ik * l_jk) / l_jj
+		for p := begin; p < end-1; p++ {
+			j := ind[p]
+			v := data[p]
+			for q := indptr[j]; q < indptr[j+1]-1; q++ {
+				if k := pos[ind[q]]; k >= 0 {
+					v -= data[k] * data[q]
+				}
+			}
+			data[p] = v / data[indptr[j+1]-1]
+		}
+
+		// l_ii = sqrt(a_ii - sum_k<i l_ik^2)
+		d := data[end-1]
+		for p := begin; p < end-1; p++ {
+			d -= data[p] * data[p]
+		}
+		if !(d > 0) {
+			return &NotPositiveDefiniteError{Index: i, Pivot: d}
+		}
+		data[end-1] = math.Sqrt(d)
+
+		for p := begin; p < end; p++ {
+			pos[ind[p]] = -1
+		}
+	}
+	ic.l = NewCSR(r, c, indptr, ind, data)
+	return nil
+}
+
+// LTo extracts the lower triangular factor L into dst.
+func (ic *IC0) LTo(dst *CSR) {
+	dst.Clone(ic.l)
+}
+
+// Apply solves L * L^T * dst = src.
+func (ic *IC0) Apply(dst, src []float64) {
+	copy(dst, src)
+	blas.Dussv(false, true, 1, &ic.l.matrix, dst, 1)
+	blas.Dussv(true, true, 1, &ic.l.matrix, dst, 1)
+}
+
+// incompleteLU holds an incomplete LU factorisation A ≈ L * U where L is unit lower
+// triangular (with the unit diagonal stored explicitly) and U is upper triangular.
+type incompleteLU struct {
+	l, u *CSR
+}
+
+// LTo extracts the unit lower triangular factor L into dst.
+func (f *incompleteLU) LTo(dst *CSR) {
+	dst.Clone(f.l)
+}
+
+// UTo extracts the upper triangular factor U into dst.
+func (f *incompleteLU) UTo(dst *CSR) {
+	dst.Clone(f.u)
+}
+
+// Apply solves L * U * dst = src.
+func (f *incompleteLU) Apply(dst, src []float64) {
+	copy(dst, src)
+	blas.Dussv(false, true, 1, &f.l.matrix, dst, 1)
+	blas.Dussv(false, false, 1, &f.u.matrix, dst, 1)
+}
+
+// ILU0 is an incomplete LU factorisation with zero fill-in (ILU(0)) of a square
+// matrix A such that A ≈ L * U where L is unit lower triangular, U is upper
+// triangular and L and U share the sparsity pattern of A.  No pivoting is performed.
+// ILU0 implements the Preconditioner interface for use with the iterative solvers
+// e.g. GMRES and BiCGStab.
+type ILU0 struct {
+	incompleteLU
+}
+
+// Factorize computes the incomplete LU factorisation of the square matrix a.  If a
+// diagonal element is missing from the sparsity pattern of a or a zero pivot is
+// encountered a *SingularError is returned and the factorisation may not be used.
+func (ilu *ILU0) Factorize(a *CSR) error {
+	r, c := a.Dims()
+	if r != c {
+		panic(mat.ErrShape)
+	}
+	indptr, ind, data := sortedRows(a, nil)
+
+	// diag holds the position of the diagonal element in each row
+	diag := getInts(r, false)
+	defer putInts(diag)
+	pos := getInts(r, false)
+	defer putInts(pos)
+	for i := range pos {
+		pos[i] = -1
+	}
+
+	for i := 0; i < r; i++ {
+		begin, end := indptr[i], indptr[i+1]
+		diag[i] = -1
+		for p := begin; p < end; p++ {
+			pos[ind[p]] = p
+			if ind[p] == i {
+				diag[i] = p
+			}
+		}
+		if diag[i] < 0 {
+			return &SingularError{Index: i, Structural: true}
+		}
+
+		// IKJ variant of Gaussian elimination restricted to the pattern of row i
+		for p := begin; p < diag[i]; p++ {
+			k := ind[p]
+			data[p] /= data[diag[k]]
+			for q := diag[k] + 1; q < indptr[k+1]; q++ {
+				if t := pos[ind[q]]; t >= 0 {
+					data[t] -= data[p] * data[q]
+				}
+			}
+		}
+		if data[diag[i]] == 0 {
+			return &SingularError{Index: i}
+		}
+
+		for p := begin; p < end; p++ {
+			pos[ind[p]] = -1
+		}
+	}
+
+	ilu.l, ilu.u = splitLU(r, indptr, ind, data)
+	return nil
+}
+
+// ILUT is an incomplete LU factorisation with threshold dropping (ILUT) of a square
+// matrix A such that A ≈ L * U where L is unit lower triangular and U is upper
+// triangular.  Fill-in is permitted but small elements are dropped and the number of
+// elements retained in each row of L and U is limited.  No pivoting is performed.
+// ILUT implements the Preconditioner interface for use with the iterative solvers
+// e.g. GMRES and BiCGStab.
+type ILUT struct {
+	// DropTolerance controls which elements are dropped during the factorisation.
+	// As with CSR.Cull, elements within epsilon of zero are dropped where epsilon
+	// is DropTolerance multiplied by the 2-norm of the corresponding row of A.
+	// If DropTolerance is zero, only elements that are exactly zero are dropped.
+	DropTolerance float64
+
+	// FillLimit is the maximum number of (off diagonal) elements retained in each
+	// row of L and each row of U in addition to those in the corresponding row of
+	// A, the largest elements (by magnitude) being retained.  If FillLimit is zero,
+	// the number of elements is not limited.
+	FillLimit int
+
+	incompleteLU
+}
+
+// Factorize computes the incomplete LU factorisation of the square matrix a.  If a
+// zero pivot is encountered a *SingularError is returned and the factorisation may
+// not be used.
+func (ilu *ILUT) Factorize(a *CSR) error {
+	r, c := a.Dims()
+	if r != c {
+		panic(mat.ErrShape)
+	}
+	m := &a.matrix
+
+	lIndptr := make([]int, r+1)
+	uIndptr := make([]int, r+1)
+	var lInd, uInd []int
+	var lData, uData []float64
+
+	// w holds the current row scattered into dense form, nz its non-zero pattern
+	// and lower a min heap of its (unprocessed) indices below the diagonal
+	w := getFloats(r, true)
+	defer putFloats(w)
+	mark := make([]bool, r)
+	nz := make([]int, 0, r)
+	var lower intHeap
+
+	for i := 0; i < r; i++ {
+		var norm float64
+		nz = nz[:0]
+		lower = lower[:0]
+		for p := m.Indptr[i]; p < m.Indptr[i+1]; p++ {
+			j := m.Ind[p]
+			if !mark[j] {
+				mark[j] = true
+				nz = append(nz, j)
+				if j < i {
+					lower = append(lower, j)
+				}
+			}
+			w[j] += m.Data[p]
+			norm += m.Data[p] * m.Data[p]
+		}
+		epsilon := ilu.DropTolerance * math.Sqrt(norm)
+		rowL := len(lower)
+		rowU := len(nz) - rowL
+		heap.Init(&lower)
+
+		for lower.Len() > 0 {
+			k := heap.Pop(&lower).(int)
+			begin, end := uIndptr[k], uIndptr[k+1]
+			w[k] /= uData[begin]
+			if math.Abs(w[k]) <= epsilon {
+				w[k] = 0
+				continue
+			}
+			for q := begin + 1; q < end; q++ {
+				j := uInd[q]
+				if !mark[j] {
+					mark[j] = true
+					nz = append(nz, j)
+					if j < i {
+						heap.Push(&lower, j)
+					}
+				}
+				w[j] -= w[k] * uData[q]
+			}
+		}
+
+		if w[i] == 0 {
+			return &SingularError{Index: i}
+		}
+
+		// gather the retained elements of row i of L and U
+		lStart, uStart := len(lInd), len(uInd)
+		uInd = append(uInd, i)
+		uData = append(uData, w[i])
+		for _, j := range nz {
+			if j == i || math.Abs(w[j]) <= epsilon {
+				continue
+			}
+			if j < i {
+				lInd = append(lInd, j)
+				lData = append(lData, w[j])
+			} else {
+				uInd = append(uInd, j)
+				uData = append(uData, w[j])
+			}
+		}
+		if ilu.FillLimit > 0 {
+			lInd, lData = largest(lInd, lData, lStart, rowL+ilu.FillLimit)
+			uInd, uData = largest(uInd, uData, uStart+1, rowU-1+ilu.FillLimit)
+		}
+		sortSparse(lInd[lStart:], lData[lStart:])
+		sortSparse(uInd[uStart+1:], uData[uStart+1:])
+		lInd = append(lInd, i)
+		lData = append(lData, 1)
+		lIndptr[i+1] = len(lInd)
+		uIndptr[i+1] = len(uInd)
+
+		for _, j := range nz {
+			mark[j] = false
+			w[j] = 0
+		}
+	}
+
+	ilu.l = NewCSR(r, c, lIndptr, lInd, lData)
+	ilu.u = NewCSR(r, c, uIndptr, uInd, uData)
+	return nil
+}
+
+// largest retains only the limit largest (by magnitude) elements of ind and data
+// from start onwards.
+func largest(ind []int, data []float64, start int, limit int) ([]int, []float64) {
+	if len(ind)-start <= limit {
+		return ind, data
+	}
+	sort.Sort(byMagnitude{sparseElements{ind: ind[start:], data: data[start:]}})
+	return ind[:start+limit], data[:start+limit]
+}
+
+// byMagnitude sorts sparse vector elements into descending order of magnitude.
+type byMagnitude struct {
+	sparseElements
+}
+
+func (s byMagnitude) Less(i, j int) bool { return math.Abs(s.data[i]) > math.Abs(s.data[j]) }
+
+// intHeap is a min heap of ints.
+type intHeap []int
+
+func (h intHeap) Len() int            { return len(h) }
+func (h intHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *intHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// sortedRows returns a copy of the rows of a (optionally only those elements for
+// which keep returns true) with the elements of each row sorted into ascending
+// column order.
+func sortedRows(a *CSR, keep func(i, j int) bool) (indptr, ind []int, data []float64) {
+	m := &a.matrix
+	indptr = make([]int, m.I+1)
+	ind = make([]int, 0, len(m.Ind))
+	data = make([]float64, 0, len(m.Data))
+	for i := 0; i < m.I; i++ {
+		for p := m.Indptr[i]; p < m.Indptr[i+1]; p++ {
+			if keep == nil || keep(i, m.Ind[p]) {
+				ind = append(ind, m.Ind[p])
+				data = append(data, m.Data[p])
+			}
+		}
+		indptr[i+1] = len(ind)
+		sortSparse(ind[indptr[i]:], data[indptr[i]:])
+	}
+	return indptr, ind, data
+}
+
+// splitLU splits the combined (row sorted) LU factors into a unit lower triangular
+// L (with the unit diagonal stored explicitly) and an upper triangular U.
+func splitLU(n int, indptr, ind []int, data []float64) (l, u *CSR) {
+	lIndptr := make([]int, n+1)
+	uIndptr := make([]int, n+1)
+	lInd := make([]int, 0, len(ind)/2+n)
+	lData := make([]float64, 0, len(ind)/2+n)
+	uInd := make([]int, 0, len(ind)/2+n)
+	uData := make([]float64, 0, len(ind)/2+n)
+	for i := 0; i < n; i++ {
+		for p := indptr[i]; p < indptr[i+1]; p++ {
+			if j := ind[p]; j < i {
+				lInd = append(lInd, j)
+				lData = append(lData, data[p])
+			} else {
+				uInd = append(uInd, j)
+				uData = append(uData, data[p])
+			}
+		}
+		lInd = append(lInd, i)
+		lData = append(lData, 1)
+		lIndptr[i+1] = len(lInd)
+		uIndptr[i+1] = len(uInd)
+	}
+	return NewCSR(n, n, lIndptr, lInd, lData), NewCSR(n, n, uIndptr, uInd, uData)
+}
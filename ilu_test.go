@@ -0,0 +1,192 @@
+package sparse
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// patternMatches returns true if the product of the factors f1 * f2 matches a at
+// every element of the sparsity pattern of a (the defining property of zero fill
+// incomplete factorisations).
+func patternMatches(a *CSR, f1, f2 mat.Matrix) bool {
+	var prod mat.Dense
+	prod.Mul(f1, f2)
+	ok := true
+	a.DoNonZero(func(i, j int, v float64) {
+		if d := prod.At(i, j) - v; d > 1e-10 || d < -1e-10 {
+			ok = false
+		}
+	})
+	return ok
+}
+
+func TestIC0(t *testing.T) {
+	a := laplacian2D(15, 15)
+	n, _ := a.Dims()
+
+	var ic IC0
+	if err := ic.Factorize(a); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var l CSR
+	ic.LTo(&l)
+	if !patternMatches(a, &l, l.T()) {
+		t.Errorf("expected L * L^T to match A on the sparsity pattern of A")
+	}
+	if l.NNZ() != (a.NNZ()+n)/2 {
+		t.Errorf("expected no fill in but L had %d non-zeros", l.NNZ())
+	}
+
+	b := randomData(n, 1, 1)
+	plain, err := CG(context.Background(), a, b, nil)
+	if err != nil {
+		t.Fatalf("unexpected error from CG %v", err)
+	}
+	preconditioned, err := PCG(context.Background(), a, b, &ic, nil)
+	if err != nil {
+		t.Fatalf("unexpected error from PCG %v", err)
+	}
+	if preconditioned.Iterations >= plain.Iterations {
+		t.Errorf("expected IC0 to reduce iterations but PCG took %d and CG took %d", preconditioned.Iterations, plain.Iterations)
+	}
+
+	// IC(0) of a tridiagonal matrix is the complete factorisation
+	tri := CreateCSR(4, 4, []float64{
+		4, 1, 0, 0,
+		1, 4, 1, 0,
+		0, 1, 4, 1,
+		0, 0, 1, 4,
+	}).(*CSR)
+	if err := ic.Factorize(tri); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	x := make([]float64, 4)
+	ic.Apply(x, []float64{1, 2, 3, 4})
+	if res := residual(tri, []float64{1, 2, 3, 4}, x); res > 1e-14 {
+		t.Errorf("expected exact solution for tridiagonal matrix but relative residual was %g", res)
+	}
+
+	indefinite := CreateCSR(2, 2, []float64{
+		1, 2,
+		2, 1,
+	}).(*CSR)
+	err = ic.Factorize(indefinite)
+	var npd *NotPositiveDefiniteError
+	if !errors.As(err, &npd) || npd.Index != 1 || !errors.Is(err, mat.ErrNotPSD) {
+		t.Errorf("expected *NotPositiveDefiniteError at index 1 but received %v", err)
+	}
+}
+
+func TestILU0(t *testing.T) {
+	a := convectionDiffusion2D(15, 15, 2)
+	n, _ := a.Dims()
+
+	var ilu ILU0
+	if err := ilu.Factorize(a); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var l, u CSR
+	ilu.LTo(&l)
+	ilu.UTo(&u)
+	if !patternMatches(a, &l, &u) {
+		t.Errorf("expected L * U to match A on the sparsity pattern of A")
+	}
+	if l.NNZ()+u.NNZ() != a.NNZ()+n {
+		t.Errorf("expected no fill in but L and U had %d non-zeros", l.NNZ()+u.NNZ())
+	}
+
+	b := randomData(n, 1, 1)
+	for _, solver := range unsymmetricSolvers {
+		plain, err := solver.solve(context.Background(), a, b, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", solver.name, err)
+		}
+		preconditioned, err := solver.solve(context.Background(), a, b, &SolverSettings{Preconditioner: &ilu})
+		if err != nil {
+			t.Fatalf("%s: unexpected error with ILU0 %v", solver.name, err)
+		}
+		if preconditioned.Iterations >= plain.Iterations {
+			t.Errorf("%s: expected ILU0 to reduce iterations but took %d (vs %d)", solver.name, preconditioned.Iterations, plain.Iterations)
+		}
+	}
+
+	missing := CreateCSR(2, 2, []float64{
+		1, 2,
+		3, 0,
+	}).(*CSR)
+	err := ilu.Factorize(missing)
+	var singular *SingularError
+	if !errors.As(err, &singular) || singular.Index != 1 || !singular.Structural {
+		t.Errorf("expected structurally singular error at index 1 but received %v", err)
+	}
+}
+
+func TestILUT(t *testing.T) {
+	// without dropping ILUT is the complete LU factorisation (without pivoting)
+	n := 40
+	a := CreateCSR(n, n, randomUnsymmetric(n, 0.1)).(*CSR)
+	b := randomData(n, 1, 1)
+	var exact ILUT
+	if err := exact.Factorize(a); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	x := make([]float64, n)
+	exact.Apply(x, b)
+	if res := residual(a, b, x); res > 1e-12 {
+		t.Errorf("expected exact solution without dropping but relative residual was %g", res)
+	}
+
+	a = convectionDiffusion2D(15, 15, 2)
+	n, _ = a.Dims()
+	b = randomData(n, 1, 1)
+	var ilu0 ILU0
+	if err := ilu0.Factorize(a); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	zeroFill, err := GMRES(context.Background(), a, b, 20, &SolverSettings{Preconditioner: &ilu0})
+	if err != nil {
+		t.Fatalf("unexpected error from GMRES with ILU0 %v", err)
+	}
+
+	ilut := ILUT{DropTolerance: 1e-3, FillLimit: 5}
+	if err := ilut.Factorize(a); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var l, u CSR
+	ilut.LTo(&l)
+	ilut.UTo(&u)
+	for i := 0; i < n; i++ {
+		lRow, uRow, aLower, aUpper := 0, 0, 0, 0
+		l.DoRowNonZero(i, func(i, j int, v float64) { lRow++ })
+		u.DoRowNonZero(i, func(i, j int, v float64) { uRow++ })
+		a.DoRowNonZero(i, func(i, j int, v float64) {
+			if j < i {
+				aLower++
+			} else if j > i {
+				aUpper++
+			}
+		})
+		if lRow-1 > aLower+ilut.FillLimit || uRow-1 > aUpper+ilut.FillLimit {
+			t.Errorf("row %d: exceeded fill limit with %d elements in L and %d in U", i, lRow, uRow)
+		}
+	}
+	withFill, err := GMRES(context.Background(), a, b, 20, &SolverSettings{Preconditioner: &ilut})
+	if err != nil {
+		t.Fatalf("unexpected error from GMRES with ILUT %v", err)
+	}
+	if withFill.Iterations >= zeroFill.Iterations {
+		t.Errorf("expected ILUT to reduce iterations compared to ILU0 but took %d (vs %d)", withFill.Iterations, zeroFill.Iterations)
+	}
+
+	var ilut2 ILUT
+	singular := CreateCSR(2, 2, []float64{
+		1, 2,
+		1, 2,
+	}).(*CSR)
+	if err := ilut2.Factorize(singular); !errors.Is(err, mat.ErrSingular) {
+		t.Errorf("expected singular error but received %v", err)
+	}
+}
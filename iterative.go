@@ -0,0 +1,255 @@
+package sparse
+
+import (
+	"context"
+	"fmt"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// MulVecToer is a linear operator A that may be multiplied by a vector.  All sparse
+// matrix formats in this package implement MulVecToer so may be used directly with
+// the iterative solvers.  Custom (matrix free) operators may also be used.
+type MulVecToer interface {
+	// MulVecTo computes A*x or A^T*x and adds the result to dst
+	// i.e. dst += A*x or dst += A^T*x.
+	MulVecTo(dst []float64, trans bool, x []float64)
+}
+
+// Preconditioner is an approximation M of a matrix A that is cheap to invert and
+// which is used to accelerate the convergence of iterative solvers.
+type Preconditioner interface {
+	// Apply solves M * dst = src i.e. computes dst = M^-1 * src.  dst and src
+	// will not be the same slice.
+	Apply(dst, src []float64)
+}
+
+// SolverSettings holds the settings for the iterative solvers.  The zero value
+// (or a nil *SolverSettings) uses the default for each setting.
+type SolverSettings struct {
+	// Tolerance is the relative residual tolerance for convergence i.e. the solver
+	// stops when ||b - A*x|| <= Tolerance * ||b||.  If Tolerance is zero, a default
+	// of 1e-8 is used.
+	Tolerance float64
+
+	// MaxIterations is the maximum number of iterations performed.  If
+	// MaxIterations is zero, a default of twice the size of the system is used.
+	MaxIterations int
+
+	// InitX is the initial guess for the solution.  If InitX is nil, the initial
+	// guess is the zero vector.
+	InitX []float64
+
+	// Preconditioner is applied to accelerate convergence.  If Preconditioner is
+	// nil, no preconditioning is applied.
+	Preconditioner Preconditioner
+}
+
+// defaults returns a copy of the settings (or the default settings if s is nil)
+// with default values filled in for the system of size n.
+func (s *SolverSettings) defaults(n int) SolverSettings {
+	var settings SolverSettings
+	if s != nil {
+		settings = *s
+	}
+	if settings.Tolerance == 0 {
+		settings.Tolerance = 1e-8
+	}
+	if settings.MaxIterations == 0 {
+		settings.MaxIterations = 2 * n
+	}
+	if settings.InitX != nil && len(settings.InitX) != n {
+		panic(mat.ErrShape)
+	}
+	return settings
+}
+
+// SolverStatus describes the outcome of an iterative solve.
+type SolverStatus int
+
+const (
+	// NotConverged indicates the solver reached the maximum number of iterations
+	// without converging to the required tolerance.
+	NotConverged SolverStatus = iota
+
+	// Converged indicates the solver converged to the required tolerance.
+	Converged
+
+	// Breakdown indicates the solver broke down and was unable to continue.
+	Breakdown
+
+	// Cancelled indicates the solve was cancelled via its context.
+	Cancelled
+)
+
+// String implements the fmt.Stringer interface.
+func (s SolverStatus) String() string {
+	switch s {
+	case NotConverged:
+		return "NotConverged"
+	case Converged:
+		return "Converged"
+	case Breakdown:
+		return "Breakdown"
+	case Cancelled:
+		return "Cancelled"
+	}
+	return fmt.Sprintf("SolverStatus(%d)", int(s))
+}
+
+// SolverResult holds the result of an iterative solve.
+type SolverResult struct {
+	// X is the approximate solution.
+	X []float64
+
+	// Status describes the outcome of the solve.
+	Status SolverStatus
+
+	// Iterations is the number of iterations performed.
+	Iterations int
+
+	// Residual is the relative residual norm ||b - A*x|| / ||b|| of the solution
+	// (as computed by the solver's recurrence).
+	Residual float64
+
+	// History holds the relative residual norm before the first iteration followed
+	// by the relative residual norm after each iteration.
+	History []float64
+}
+
+// NotConvergedError is the error returned when an iterative solver fails to
+// converge to the required tolerance within the maximum number of iterations.
+type NotConvergedError struct {
+	// Iterations is the number of iterations performed.
+	Iterations int
+
+	// Residual is the relative residual norm of the best solution found.
+	Residual float64
+}
+
+// Error implements the error interface.
+func (e *NotConvergedError) Error() string {
+	return fmt.Sprintf("sparse: solver did not converge after %d iterations (relative residual %g)", e.Iterations, e.Residual)
+}
+
+// BreakdownError is the error returned when an iterative solver breaks down i.e.
+// encounters a division by zero (or a quantity that should be positive but is not)
+// and is unable to continue.  This typically indicates that the matrix (or
+// preconditioner) does not have the properties required by the solver e.g. CG
+// requires a symmetric positive definite matrix.
+type BreakdownError struct {
+	// Iteration is the iteration at which the breakdown occurred.
+	Iteration int
+
+	// Reason describes the cause of the breakdown.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *BreakdownError) Error() string {
+	return fmt.Sprintf("sparse: solver breakdown at iteration %d: %s", e.Iteration, e.Reason)
+}
+
+// iterativeState holds the state common to the iterative solvers.
+type iterativeState struct {
+	ctx      context.Context
+	a        MulVecToer
+	settings SolverSettings
+	result   *SolverResult
+	bNorm    float64
+}
+
+// newIterativeState initialises the state for solving A * x = b and computes the
+// initial residual r = b - A * x0.
+func newIterativeState(ctx context.Context, a MulVecToer, b []float64, s *SolverSettings, r []float64) *iterativeState {
+	n := len(b)
+	state := &iterativeState{
+		ctx:      ctx,
+		a:        a,
+		settings: s.defaults(n),
+		result:   &SolverResult{X: make([]float64, n)},
+		bNorm:    floats.Norm(b, 2),
+	}
+	copy(r, b)
+	if state.settings.InitX != nil {
+		copy(state.result.X, state.settings.InitX)
+		state.mulVec(r, -1, state.result.X, false)
+	}
+	return state
+}
+
+// mulVec computes dst += alpha * A * x (or A^T * x if trans is true).
+func (s *iterativeState) mulVec(dst []float64, alpha float64, x []float64, trans bool) {
+	if alpha == 1 {
+		s.a.MulVecTo(dst, trans, x)
+		return
+	}
+	tmp := getFloats(len(dst), true)
+	s.a.MulVecTo(tmp, trans, x)
+	floats.AddScaled(dst, alpha, tmp)
+	putFloats(tmp)
+}
+
+// precondition computes dst = M^-1 * src using the preconditioner from the settings
+// or copies src to dst if no preconditioner was specified.
+func (s *iterativeState) precondition(dst, src []float64) {
+	if s.settings.Preconditioner == nil {
+		copy(dst, src)
+		return
+	}
+	s.settings.Preconditioner.Apply(dst, src)
+}
+
+// relative returns the residual norm rNorm relative to the norm of the right hand
+// side b (or rNorm if b is zero).
+func (s *iterativeState) relative(rNorm float64) float64 {
+	if s.bNorm == 0 {
+		return rNorm
+	}
+	return rNorm / s.bNorm
+}
+
+// converged records the residual norm after an iteration (or the initial residual
+// norm if the iteration is 0) and returns true if the relative residual is within the
+// tolerance.
+func (s *iterativeState) converged(iteration int, rNorm float64) bool {
+	res := s.relative(rNorm)
+	s.result.Iterations = iteration
+	s.result.Residual = res
+	s.result.History = append(s.result.History, res)
+	if res <= s.settings.Tolerance {
+		s.result.Status = Converged
+		return true
+	}
+	return false
+}
+
+// next returns a non-nil error if the solver should stop before performing the
+// specified iteration either because the context has been cancelled or the maximum
+// number of iterations has been reached.
+func (s *iterativeState) next(iteration int) error {
+	if err := s.ctx.Err(); err != nil {
+		s.result.Status = Cancelled
+		return err
+	}
+	if iteration > s.settings.MaxIterations {
+		s.result.Status = NotConverged
+		return &NotConvergedError{Iterations: s.result.Iterations, Residual: s.result.Residual}
+	}
+	return nil
+}
+
+// breakdown records that the solver broke down at the specified iteration and
+// returns the corresponding *BreakdownError.
+func (s *iterativeState) breakdown(iteration int, reason string) error {
+	s.result.Status = Breakdown
+	return &BreakdownError{Iteration: iteration, Reason: reason}
+}
+
+// zero sets all elements of v to 0.
+func zero(v []float64) {
+	for i := range v {
+		v[i] = 0
+	}
+}
@@ -0,0 +1,580 @@
+package sparse
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// bunchKaufmanAlpha is the pivoting threshold (1 + sqrt(17)) / 8 which minimises the
+// bound on element growth for Bunch-Kaufman pivoting.
+var bunchKaufmanAlpha = (1 + math.Sqrt(17)) / 8
+
+// epsilon is the machine epsilon for float64 used to determine the tolerance for
+// zero pivots.
+const epsilon = 1.0 / (1 << 53)
+
+// LDL is a sparse LDL^T factorisation of a symmetric (possibly indefinite) matrix
+// such that P * A * P^T = L * D * L^T where P is a permutation matrix, L is unit lower
+// triangular and D is block diagonal with 1x1 and 2x2 blocks.  The permutation
+// combines the (optional) fill reducing ordering with Bunch-Kaufman symmetric
+// pivoting so LDL may be used to solve symmetric indefinite systems of equations
+// such as the KKT systems arising in constrained optimisation for which Cholesky
+// factorisation is not possible.
+type LDL struct {
+	n int
+
+	// l is the strictly lower triangular part of L (the unit diagonal is not stored)
+	// in CSC form with row indices relative to the permuted matrix.  lrow is the
+	// same matrix in CSR form for element access.
+	l    *CSC
+	lrow *CSR
+
+	// d holds the diagonal of D and e the sub-diagonal such that e[k] is non-zero
+	// only where a 2x2 block occupies rows and columns k and k+1.
+	d []float64
+	e []float64
+
+	// perm is the permutation P such that row (and column) i of P * A * P^T is row
+	// (and column) perm[i] of A and pinv is its inverse.
+	perm []int
+	pinv []int
+
+	// tol is the magnitude below which a pivot is considered zero
+	tol float64
+}
+
+// Dims returns the dimensions of the factorised matrix.
+func (ldl *LDL) Dims() (r, c int) {
+	return ldl.n, ldl.n
+}
+
+// Symmetric returns the number of rows (and columns) of the factorised matrix.
+func (ldl *LDL) Symmetric() int {
+	return ldl.n
+}
+
+// T returns the receiver as the factorised matrix is symmetric.
+func (ldl *LDL) T() mat.Matrix {
+	return ldl
+}
+
+// At returns the element of the factorised matrix at row i, column j.
+func (ldl *LDL) At(i, j int) float64 {
+	if uint(i) >= uint(ldl.n) {
+		panic(mat.ErrRowAccess)
+	}
+	if uint(j) >= uint(ldl.n) {
+		panic(mat.ErrColAccess)
+	}
+	i, j = ldl.pinv[i], ldl.pinv[j]
+
+	// scatter row j of L and compute row i of L * D dotted with it
+	lj := getFloats(ldl.n, true)
+	defer putFloats(lj)
+	ldl.lrow.DoRowNonZero(j, func(_, k int, v float64) {
+		lj[k] = v
+	})
+	lj[j] = 1
+
+	dlj := func(k int) float64 {
+		v := ldl.d[k] * lj[k]
+		if k > 0 {
+			v += ldl.e[k-1] * lj[k-1]
+		}
+		if k < ldl.n-1 {
+			v += ldl.e[k] * lj[k+1]
+		}
+		return v
+	}
+	val := dlj(i)
+	ldl.lrow.DoRowNonZero(i, func(_, k int, v float64) {
+		val += v * dlj(k)
+	})
+	return val
+}
+
+// Factorize computes the LDL^T factorisation of the symmetric matrix a.  Only the
+// lower triangle of a is referenced.  The same options as for Cholesky factorisation
+// may be supplied e.g. to specify a fill reducing ordering applied before pivoting.
+// Factorize panics if a is not square.  Singular matrices may be factorised
+// (indicated by zero pivots reported by Inertia) but may not be used to solve
+// systems of equations.
+func (ldl *LDL) Factorize(a *CSR, opts ...CholeskyOption) {
+	n := orderingDims(a)
+	var settings choleskySettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	var fill, fillinv []int
+	if settings.ordering != nil {
+		fill = settings.ordering.Permutation(a)
+		fillinv = make([]int, n)
+		for i, p := range fill {
+			fillinv[p] = i
+		}
+	}
+
+	// the active submatrix is stored symmetrically with the off-diagonal elements
+	// of each column held in a map keyed by row (indexed by fill order)
+	cols := make([]map[int]float64, n)
+	diag := make([]float64, n)
+	for i := range cols {
+		cols[i] = make(map[int]float64)
+	}
+	var maxAbs float64
+	m := &a.matrix
+	for r := 0; r < n; r++ {
+		for k := m.Indptr[r]; k < m.Indptr[r+1]; k++ {
+			// the lower triangle is selected using the original indices before
+			// mapping both row and column into fill order
+			if m.Ind[k] > r {
+				continue
+			}
+			i, j := r, m.Ind[k]
+			if fillinv != nil {
+				i, j = fillinv[i], fillinv[j]
+			}
+			v := m.Data[k]
+			maxAbs = math.Max(maxAbs, math.Abs(v))
+			if j == i {
+				diag[i] += v
+				continue
+			}
+			cols[i][j] += v
+			cols[j][i] += v
+		}
+	}
+	// drop elements that cancelled during assembly so that they are not treated as
+	// part of the pattern of the active submatrix
+	for i := range cols {
+		for j, v := range cols[i] {
+			if v == 0 {
+				delete(cols[i], j)
+			}
+		}
+	}
+
+	ldl.n = n
+	ldl.tol = float64(n) * epsilon * maxAbs
+	ldl.d = make([]float64, n)
+	ldl.e = make([]float64, n)
+
+	// order holds the vertices in the order they are eliminated and lrows and
+	// lvals the corresponding (sorted) row vertices and values of each column of L
+	order := make([]int, 0, n)
+	lrows := make([][]int, n)
+	lvals := make([][]float64, n)
+	eliminated := make([]bool, n)
+
+	// neighbours returns the sorted rows of the active column v excluding skip
+	neighbours := func(v, skip int) []int {
+		nbrs := make([]int, 0, len(cols[v]))
+		for i := range cols[v] {
+			if i != skip {
+				nbrs = append(nbrs, i)
+			}
+		}
+		sort.Ints(nbrs)
+		return nbrs
+	}
+	// maxOffDiag returns the largest magnitude off-diagonal element of the active
+	// column v and its row, preferring the lowest row for ties
+	maxOffDiag := func(v int) (float64, int) {
+		max, row := 0.0, -1
+		for i, val := range cols[v] {
+			if a := math.Abs(val); a > max || (a == max && row != -1 && i < row) {
+				max, row = a, i
+			}
+		}
+		return max, row
+	}
+	// remove eliminates vertex v from the active submatrix
+	remove := func(v int) {
+		for i := range cols[v] {
+			delete(cols[i], v)
+		}
+		cols[v] = nil
+		eliminated[v] = true
+	}
+	// update subtracts the symmetric product f(i, j) from each element of the
+	// active submatrix with row and column in nbrs.  Elements that cancel to
+	// exactly zero are removed so they do not appear as neighbours of later pivots.
+	update := func(nbrs []int, f func(i, j int) float64) {
+		for s, i := range nbrs {
+			diag[i] -= f(i, i)
+			for _, j := range nbrs[s+1:] {
+				v := cols[i][j] - f(i, j)
+				if v == 0 {
+					delete(cols[i], j)
+					delete(cols[j], i)
+					continue
+				}
+				cols[i][j] = v
+				cols[j][i] = v
+			}
+		}
+	}
+
+	next := 0
+	for len(order) < n {
+		for eliminated[next] {
+			next++
+		}
+		p, q := next, -1
+		k := len(order)
+
+		// Bunch-Kaufman pivot selection between a 1x1 pivot on p or r and a 2x2
+		// pivot on p and q = r
+		if lambda, r := maxOffDiag(p); lambda > 0 && math.Abs(diag[p]) < bunchKaufmanAlpha*lambda {
+			sigma, _ := maxOffDiag(r)
+			switch {
+			case math.Abs(diag[p])*sigma >= bunchKaufmanAlpha*lambda*lambda:
+			case math.Abs(diag[r]) >= bunchKaufmanAlpha*sigma:
+				p = r
+			default:
+				q = r
+			}
+		}
+
+		if q == -1 {
+			d := diag[p]
+			if math.Abs(d) <= ldl.tol {
+				// a zero pivot (only chosen when the rest of the column is
+				// negligible) is recorded with a zero column of L leaving the
+				// remaining submatrix unchanged
+				ldl.d[k] = 0
+				remove(p)
+				order = append(order, p)
+				continue
+			}
+			nbrs := neighbours(p, -1)
+			l := make(map[int]float64, len(nbrs))
+			for _, i := range nbrs {
+				l[i] = cols[p][i] / d
+			}
+			update(nbrs, func(i, j int) float64 {
+				return l[i] * cols[p][j]
+			})
+
+			ldl.d[k] = d
+			lrows[k], lvals[k] = nbrs, make([]float64, len(nbrs))
+			for s, i := range nbrs {
+				lvals[k][s] = l[i]
+			}
+			remove(p)
+			order = append(order, p)
+			continue
+		}
+
+		nbrs := neighbours(p, q)
+		for _, i := range neighbours(q, p) {
+			if _, ok := cols[p][i]; !ok {
+				nbrs = append(nbrs, i)
+			}
+		}
+		sort.Ints(nbrs)
+
+		// [L[i,p] L[i,q]] = [A[i,p] A[i,q]] * D^-1 for the 2x2 block D
+		a, b, c := diag[p], cols[p][q], diag[q]
+		det := a*c - b*b
+		lp := make(map[int]float64, len(nbrs))
+		lq := make(map[int]float64, len(nbrs))
+		for _, i := range nbrs {
+			ip, iq := cols[p][i], cols[q][i]
+			lp[i] = (ip*c - iq*b) / det
+			lq[i] = (iq*a - ip*b) / det
+		}
+		update(nbrs, func(i, j int) float64 {
+			return lp[i]*cols[p][j] + lq[i]*cols[q][j]
+		})
+
+		ldl.d[k], ldl.e[k], ldl.d[k+1] = a, b, c
+		lrows[k], lvals[k] = nbrs, make([]float64, len(nbrs))
+		lrows[k+1], lvals[k+1] = nbrs, make([]float64, len(nbrs))
+		for s, i := range nbrs {
+			lvals[k][s] = lp[i]
+			lvals[k+1][s] = lq[i]
+		}
+		remove(p)
+		remove(q)
+		order = append(order, p, q)
+	}
+
+	// assemble L in CSC form relative to the elimination order
+	pos := make([]int, n)
+	for k, v := range order {
+		pos[v] = k
+	}
+	indptr := make([]int, n+1)
+	for k := range lrows {
+		indptr[k+1] = indptr[k] + len(lrows[k])
+	}
+	ind := make([]int, indptr[n])
+	data := make([]float64, indptr[n])
+	for k := range lrows {
+		nz := indptr[k]
+		for s, v := range lrows[k] {
+			ind[nz+s] = pos[v]
+			data[nz+s] = lvals[k][s]
+		}
+		sortSparse(ind[nz:indptr[k+1]], data[nz:indptr[k+1]])
+	}
+	ldl.l = NewCSC(n, n, indptr, ind, data)
+	ldl.lrow = ldl.l.ToCSR()
+
+	ldl.perm = make([]int, n)
+	ldl.pinv = make([]int, n)
+	for k, v := range order {
+		if fill != nil {
+			v = fill[v]
+		}
+		ldl.perm[k] = v
+		ldl.pinv[v] = k
+	}
+}
+
+// blocks calls fn for each of the 1x1 and 2x2 blocks of D in turn where k is the
+// first row (and column) of the block and size is 1 or 2.
+func (ldl *LDL) blocks(fn func(k, size int)) {
+	for k := 0; k < ldl.n; k++ {
+		if ldl.e[k] != 0 {
+			fn(k, 2)
+			k++
+			continue
+		}
+		fn(k, 1)
+	}
+}
+
+// blockEigen returns the eigenvalues of the symmetric 2x2 block [a b; b c].
+func blockEigen(a, b, c float64) (float64, float64) {
+	mid := (a + c) / 2
+	rad := math.Hypot((a-c)/2, b)
+	return mid + rad, mid - rad
+}
+
+// zeroPivot returns whether the eigenvalue v of a block of D is considered zero.
+// NaN values (which only arise from non-finite elements of A) are treated as zero
+// so that such factorisations are reported as singular.
+func (ldl *LDL) zeroPivot(v float64) bool {
+	return math.IsNaN(v) || math.Abs(v) <= ldl.tol
+}
+
+// Inertia returns the inertia of the factorised matrix i.e. the number of positive,
+// negative and zero eigenvalues.  By Sylvester's law of inertia these are the
+// same as the inertia of the block diagonal matrix D.  Eigenvalues with magnitude
+// below n * eps * max(|A[i,j]|) (or NaN) are considered zero.
+func (ldl *LDL) Inertia() (pos, neg, zero int) {
+	count := func(v float64) {
+		switch {
+		case ldl.zeroPivot(v):
+			zero++
+		case v > 0:
+			pos++
+		case v < 0:
+			neg++
+		}
+	}
+	ldl.blocks(func(k, size int) {
+		if size == 1 {
+			count(ldl.d[k])
+			return
+		}
+		l1, l2 := blockEigen(ldl.d[k], ldl.e[k], ldl.d[k+1])
+		count(l1)
+		count(l2)
+	})
+	return pos, neg, zero
+}
+
+// singular returns the index of the first block of D containing a zero pivot or
+// -1 if the factorised matrix is non-singular.
+func (ldl *LDL) singular() int {
+	index := -1
+	ldl.blocks(func(k, size int) {
+		if index != -1 {
+			return
+		}
+		if size == 1 {
+			if ldl.zeroPivot(ldl.d[k]) {
+				index = k
+			}
+			return
+		}
+		l1, l2 := blockEigen(ldl.d[k], ldl.e[k], ldl.d[k+1])
+		if ldl.zeroPivot(l1) || ldl.zeroPivot(l2) {
+			index = k
+		}
+	})
+	return index
+}
+
+// Det returns the determinant of the factorised matrix.
+func (ldl *LDL) Det() float64 {
+	det, sign := ldl.LogDet()
+	return math.Exp(det) * sign
+}
+
+// LogDet returns the log of the determinant and the sign of the determinant
+// for the factorised matrix.  As P * A * P^T is a symmetric permutation of A
+// the determinant is the determinant of D.
+func (ldl *LDL) LogDet() (det float64, sign float64) {
+	sign = 1.0
+	ldl.blocks(func(k, size int) {
+		v := ldl.d[k]
+		if size == 2 {
+			v = ldl.d[k]*ldl.d[k+1] - ldl.e[k]*ldl.e[k]
+		}
+		if v < 0 {
+			sign *= -1
+		}
+		det += math.Log(math.Abs(v))
+	})
+	if math.IsInf(det, -1) {
+		return det, 0
+	}
+	return det, sign
+}
+
+// Permutation returns the permutation P from the factorisation, combining the fill
+// reducing ordering and symmetric pivoting, such that row (and column) i of
+// P * A * P^T is row (and column) dst[i] of A.  If dst is nil, a new slice is
+// allocated and returned.  If dst is not nil, it must have length equal to the size
+// of the factorised matrix.
+func (ldl *LDL) Permutation(dst []int) []int {
+	if dst == nil {
+		dst = make([]int, ldl.n)
+	}
+	if len(dst) != ldl.n {
+		panic(mat.ErrShape)
+	}
+	copy(dst, ldl.perm)
+	return dst
+}
+
+// LTo extracts the unit lower triangular matrix L from the factorisation and stores
+// it in dst.  LTo panics if dst is not the same size as the factorised matrix.
+func (ldl *LDL) LTo(dst *CSR) {
+	r, c := dst.Dims()
+	if r != ldl.n || c != ldl.n {
+		panic(mat.ErrShape)
+	}
+	l := &ldl.lrow.matrix
+	indptr := make([]int, ldl.n+1)
+	ind := make([]int, 0, len(l.Ind)+ldl.n)
+	data := make([]float64, 0, len(l.Data)+ldl.n)
+	for i := 0; i < ldl.n; i++ {
+		ind = append(ind, l.Ind[l.Indptr[i]:l.Indptr[i+1]]...)
+		data = append(data, l.Data[l.Indptr[i]:l.Indptr[i+1]]...)
+		ind = append(ind, i)
+		data = append(data, 1)
+		indptr[i+1] = len(ind)
+	}
+	dst.matrix = NewCSR(ldl.n, ldl.n, indptr, ind, data).matrix
+}
+
+// D returns the block diagonal matrix D from the factorisation as a DIA matrix with
+// diagonals at offsets -1, 0 and 1.
+func (ldl *LDL) D() *DIA {
+	d := make([]float64, ldl.n)
+	copy(d, ldl.d)
+	if ldl.n < 2 {
+		return NewDIA(ldl.n, ldl.n, d)
+	}
+	lower := make([]float64, ldl.n-1)
+	upper := make([]float64, ldl.n-1)
+	copy(lower, ldl.e)
+	copy(upper, ldl.e)
+	return NewDIAOffsets(ldl.n, ldl.n, []int{-1, 0, 1}, [][]float64{lower, d, upper})
+}
+
+// SolveVecTo solves the system of linear equations A * x = b using the LDL^T
+// factorisation of A, placing the result in dst.  If dst is empty it is resized to
+// the correct length, otherwise SolveVecTo panics if the length of dst is not the
+// same as the size of the factorised matrix.  If the factorised matrix is singular,
+// a *SingularError is returned.
+func (ldl *LDL) SolveVecTo(dst *mat.VecDense, b mat.Vector) error {
+	n := ldl.n
+	if b.Len() != n {
+		panic(mat.ErrShape)
+	}
+	if dst.IsEmpty() {
+		dst.ReuseAsVec(n)
+	} else if dst.Len() != n {
+		panic(mat.ErrShape)
+	}
+	if k := ldl.singular(); k != -1 {
+		return &SingularError{Index: k}
+	}
+
+	x := getFloats(n, false)
+	defer putFloats(x)
+	for k, i := range ldl.perm {
+		x[k] = b.AtVec(i)
+	}
+
+	// L * y = P * b
+	l := &ldl.l.matrix
+	for j := 0; j < n; j++ {
+		for p := l.Indptr[j]; p < l.Indptr[j+1]; p++ {
+			x[l.Ind[p]] -= l.Data[p] * x[j]
+		}
+	}
+
+	// D * z = y
+	ldl.blocks(func(k, size int) {
+		if size == 1 {
+			x[k] /= ldl.d[k]
+			return
+		}
+		a, b, c := ldl.d[k], ldl.e[k], ldl.d[k+1]
+		det := a*c - b*b
+		x[k], x[k+1] = (c*x[k]-b*x[k+1])/det, (a*x[k+1]-b*x[k])/det
+	})
+
+	// L^T * P * x = z
+	for j := n - 1; j >= 0; j-- {
+		v := x[j]
+		for p := l.Indptr[j]; p < l.Indptr[j+1]; p++ {
+			v -= l.Data[p] * x[l.Ind[p]]
+		}
+		x[j] = v
+	}
+	for k, i := range ldl.perm {
+		dst.SetVec(i, x[k])
+	}
+	return nil
+}
+
+// SolveTo solves the system of linear equations A * X = B using the LDL^T
+// factorisation of A, placing the result in dst.  If dst is empty it is resized to
+// the correct size, otherwise SolveTo panics if dst is not the correct size.  If the
+// factorised matrix is singular, a *SingularError is returned.
+func (ldl *LDL) SolveTo(dst *mat.Dense, b mat.Matrix) error {
+	rows, cols := b.Dims()
+	if rows != ldl.n {
+		panic(mat.ErrShape)
+	}
+	if dst.IsEmpty() {
+		dst.ReuseAs(ldl.n, cols)
+	} else if r, c := dst.Dims(); r != ldl.n || c != cols {
+		panic(mat.ErrShape)
+	}
+
+	bv, bHasColView := b.(mat.ColViewer)
+	for c := 0; c < cols; c++ {
+		dstView := dst.ColView(c).(*mat.VecDense)
+		var cv mat.Vector
+		if bHasColView {
+			cv = bv.ColView(c)
+		} else {
+			cv = mat.NewVecDense(rows, mat.Col(nil, c, b))
+		}
+		if err := ldl.SolveVecTo(dstView, cv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
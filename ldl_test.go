@@ -0,0 +1,279 @@
+package sparse
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/floats/scalar"
+	"gonum.org/v1/gonum/mat"
+)
+
+// kkt returns the symmetric indefinite KKT matrix [H A^T; A 0] where H is the n x n
+// 2D Laplacian of an nx * ny grid and A is a random m x n constraint matrix.
+func kkt(nx, ny, m int) *CSR {
+	h := laplacian2D(nx, ny)
+	n, _ := h.Dims()
+	dok := NewDOK(n+m, n+m)
+	h.DoNonZero(func(i, j int, v float64) {
+		dok.Set(i, j, v)
+	})
+	for i := 0; i < m; i++ {
+		for _, j := range rand.Perm(n)[:3] {
+			v := rand.Float64() + 0.5
+			dok.Set(n+i, j, v)
+			dok.Set(j, n+i, v)
+		}
+	}
+	return dok.ToCSR()
+}
+
+func randomSymmetric(n int, density float64) *CSR {
+	dok := NewDOK(n, n)
+	for i := 0; i < n; i++ {
+		dok.Set(i, i, rand.NormFloat64())
+		for j := 0; j < i; j++ {
+			if rand.Float64() < density {
+				v := rand.NormFloat64()
+				dok.Set(i, j, v)
+				dok.Set(j, i, v)
+			}
+		}
+	}
+	return dok.ToCSR()
+}
+
+func TestLDLFactorize(t *testing.T) {
+	matrices := map[string]*CSR{
+		"SPD": laplacian2D(5, 6),
+		"KKT": kkt(4, 5, 6),
+		"zero diagonal": CreateCSR(4, 4, []float64{
+			0, 1, 0, 2,
+			1, 0, 3, 0,
+			0, 3, 0, 1,
+			2, 0, 1, 0,
+		}).(*CSR),
+		"random": randomSymmetric(40, 0.1),
+	}
+	orderings := map[string]Ordering{
+		"natural": nil,
+		"AMD":     AMDOrdering{},
+	}
+
+	for mname, a := range matrices {
+		n, _ := a.Dims()
+		dense := mat.NewSymDense(n, a.ToDense().RawMatrix().Data)
+
+		var eigen mat.EigenSym
+		if !eigen.Factorize(dense, false) {
+			t.Fatalf("%s: eigendecomposition failed", mname)
+		}
+		var wantPos, wantNeg int
+		for _, v := range eigen.Values(nil) {
+			if v > 0 {
+				wantPos++
+			} else {
+				wantNeg++
+			}
+		}
+
+		for oname, o := range orderings {
+			var opts []CholeskyOption
+			if o != nil {
+				opts = append(opts, WithOrdering(o))
+			}
+			var ldl LDL
+			ldl.Factorize(a, opts...)
+
+			if !mat.EqualApprox(dense, &ldl, 1e-10) {
+				t.Errorf("%s (%s): expected factorised matrix\n%v\nbut received\n%v", mname, oname, mat.Formatted(dense), mat.Formatted(&ldl))
+			}
+
+			// check P * A * P^T = L * D * L^T
+			l := NewCSR(n, n, nil, nil, nil)
+			ldl.LTo(l)
+			var ld, ldlt mat.Dense
+			ld.Mul(l, ldl.D())
+			ldlt.Mul(&ld, l.T())
+			perm := ldl.Permutation(nil)
+			pap := mat.NewDense(n, n, nil)
+			for i := 0; i < n; i++ {
+				for j := 0; j < n; j++ {
+					pap.Set(i, j, dense.At(perm[i], perm[j]))
+				}
+			}
+			if !mat.EqualApprox(pap, &ldlt, 1e-10) {
+				t.Errorf("%s (%s): L * D * L^T does not match P * A * P^T", mname, oname)
+			}
+			for i := 0; i < n; i++ {
+				if l.At(i, i) != 1 {
+					t.Errorf("%s (%s): expected unit diagonal but received %f at %d", mname, oname, l.At(i, i), i)
+				}
+			}
+
+			pos, neg, zero := ldl.Inertia()
+			if pos != wantPos || neg != wantNeg || zero != 0 {
+				t.Errorf("%s (%s): expected inertia (%d, %d, 0) but received (%d, %d, %d)", mname, oname, wantPos, wantNeg, pos, neg, zero)
+			}
+
+			wantDet, wantSign := mat.LogDet(dense)
+			if det, sign := ldl.LogDet(); sign != wantSign || !scalar.EqualWithinAbsOrRel(det, wantDet, 1e-10, 1e-10) {
+				t.Errorf("%s (%s): expected log determinant (%f, %f) but received (%f, %f)", mname, oname, wantDet, wantSign, det, sign)
+			}
+
+			want := mat.NewVecDense(n, randomData(n, 1, 1))
+			var b, got mat.VecDense
+			b.MulVec(dense, want)
+			if err := ldl.SolveVecTo(&got, &b); err != nil {
+				t.Errorf("%s (%s): unexpected error %v", mname, oname, err)
+			}
+			if !mat.EqualApprox(want, &got, 1e-8) {
+				t.Errorf("%s (%s): expected solution\n%v\nbut received\n%v", mname, oname, mat.Formatted(want.T()), mat.Formatted(got.T()))
+			}
+
+			bm := mat.NewDense(n, 2, randomData(n, 2, 1))
+			var x, ax mat.Dense
+			if err := ldl.SolveTo(&x, bm); err != nil {
+				t.Errorf("%s (%s): unexpected error %v", mname, oname, err)
+			}
+			ax.Mul(dense, &x)
+			if !mat.EqualApprox(bm, &ax, 1e-8) {
+				t.Errorf("%s (%s): incorrect solution for multiple right hand sides", mname, oname)
+			}
+		}
+	}
+}
+
+func TestLDLSingular(t *testing.T) {
+	a := CreateCSR(4, 4, []float64{
+		1, 2, 0, 0,
+		2, 4, 0, 0,
+		0, 0, 0, 3,
+		0, 0, 3, -1,
+	}).(*CSR)
+
+	var ldl LDL
+	ldl.Factorize(a)
+
+	if pos, neg, zero := ldl.Inertia(); pos != 2 || neg != 1 || zero != 1 {
+		t.Errorf("expected inertia (2, 1, 1) but received (%d, %d, %d)", pos, neg, zero)
+	}
+	if det, sign := ldl.LogDet(); !math.IsInf(det, -1) || sign != 0 {
+		t.Errorf("expected log determinant (-Inf, 0) but received (%f, %f)", det, sign)
+	}
+
+	var x mat.VecDense
+	err := ldl.SolveVecTo(&x, mat.NewVecDense(4, []float64{1, 2, 3, 4}))
+	if !errors.Is(err, mat.ErrSingular) {
+		t.Errorf("expected error matching mat.ErrSingular but received %v", err)
+	}
+}
+
+func TestLDLSingularCancellation(t *testing.T) {
+	// a block diagonal matrix (symmetrically permuted to interleave the blocks)
+	// containing rank one blocks whose elimination cancels exactly, leaving
+	// explicitly zero columns in the active submatrix, along with indefinite
+	// blocks.  The blocks are
+	//	v * v^T for v = (1, 2, -1, 3)       inertia (1, 0, 3)
+	//	-w * w^T for w = (1, 1, 2)          inertia (0, 1, 2)
+	//	diag(2, -3)                         inertia (1, 1, 0)
+	//	[0 1; 1 0]                          inertia (1, 1, 0)
+	//	[2 1; 1 -2]                         inertia (1, 1, 0)
+	n := 13
+	block := mat.NewDense(n, n, nil)
+	v := []float64{1, 2, -1, 3}
+	for i := range v {
+		for j := range v {
+			block.Set(i, j, v[i]*v[j])
+		}
+	}
+	w := []float64{1, 1, 2}
+	for i := range w {
+		for j := range w {
+			block.Set(4+i, 4+j, -w[i]*w[j])
+		}
+	}
+	block.Set(7, 7, 2)
+	block.Set(8, 8, -3)
+	block.Set(9, 10, 1)
+	block.Set(10, 9, 1)
+	block.Set(11, 11, 2)
+	block.Set(11, 12, 1)
+	block.Set(12, 11, 1)
+	block.Set(12, 12, -2)
+
+	perm := []int{4, 0, 9, 5, 1, 11, 7, 2, 6, 12, 3, 10, 8}
+	data := make([]float64, n*n)
+	for i, pi := range perm {
+		for j, pj := range perm {
+			data[i*n+j] = block.At(pi, pj)
+		}
+	}
+	a := CreateCSR(n, n, data).(*CSR)
+
+	for _, ordering := range []Ordering{nil, AMDOrdering{}} {
+		var ldl LDL
+		if ordering == nil {
+			ldl.Factorize(a)
+		} else {
+			ldl.Factorize(a, WithOrdering(ordering))
+		}
+
+		if pos, neg, zero := ldl.Inertia(); pos != 4 || neg != 4 || zero != 5 {
+			t.Errorf("ordering %T: expected inertia (4, 4, 5) but received (%d, %d, %d)", ordering, pos, neg, zero)
+		}
+		if det := ldl.Det(); det != 0 {
+			t.Errorf("ordering %T: expected determinant 0 but received %f", ordering, det)
+		}
+		if !mat.EqualApprox(a, &ldl, 1e-12) {
+			t.Errorf("ordering %T: expected reconstruction\n%v\nbut received\n%v", ordering, mat.Formatted(a), mat.Formatted(&ldl))
+		}
+
+		var x mat.VecDense
+		err := ldl.SolveVecTo(&x, mat.NewVecDense(n, nil))
+		if !errors.Is(err, mat.ErrSingular) {
+			t.Errorf("ordering %T: expected error matching mat.ErrSingular but received %v", ordering, err)
+		}
+	}
+}
+
+func TestLDLLowerTriangle(t *testing.T) {
+	for name, full := range map[string]*CSR{
+		"SPD": laplacian2D(3, 3),
+		"KKT": kkt(3, 4, 4),
+	} {
+		n, _ := full.Dims()
+		lower := NewDOK(n, n)
+		full.DoNonZero(func(i, j int, v float64) {
+			if j <= i {
+				lower.Set(i, j, v)
+			}
+		})
+		a := lower.ToCSR()
+
+		b := make([]float64, n)
+		for i := range b {
+			b[i] = float64(i + 1)
+		}
+		for oname, ordering := range map[string]Ordering{
+			"natural": NaturalOrdering{},
+			"AMD":     AMDOrdering{},
+			"RCM":     RCMOrdering{},
+		} {
+			var ldl LDL
+			ldl.Factorize(a, WithOrdering(ordering))
+			var x mat.VecDense
+			if err := ldl.SolveVecTo(&x, mat.NewVecDense(n, b)); err != nil {
+				t.Errorf("%s (%s): unexpected error %v", name, oname, err)
+				continue
+			}
+			var r mat.VecDense
+			r.MulVec(full, &x)
+			r.SubVec(&r, mat.NewVecDense(n, b))
+			if res := mat.Norm(&r, 2); res > 1e-10 {
+				t.Errorf("%s (%s): expected residual of at most 1e-10 but received %g", name, oname, res)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package sparse
+
+import (
+	"context"
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+// LSMR solves the (possibly damped) linear least squares problem
+// min ||A*x - b||^2 + Damp^2 * ||x||^2 for x using the LSMR algorithm of Fong and
+// Saunders (2011) where a is an m x n matrix (or operator) and b is of length m.  A
+// may be rectangular (over or under determined) and of any rank.  Only the MulVecTo
+// method of a is used (with trans set to true for products with A^T) so A^T is never
+// formed explicitly and a may be a matrix free operator.  LSMR is analytically
+// equivalent to MINRES applied to the normal equations so, unlike LSQR, the norm of
+// the normal equations residual ||A^T * r|| decreases monotonically allowing the
+// iterations to be terminated earlier.  settings may be nil in which case the default
+// settings are used.  See LSQR for details of the result and errors.
+func LSMR(ctx context.Context, a MulVecToer, n int, b []float64, settings *LeastSquaresSettings) (*LeastSquaresResult, error) {
+	state := newLeastSquaresState(ctx, n, b, settings)
+	x := state.result.X
+	damp := state.settings.Damp
+
+	u, v, alpha, beta := bidiagonalStart(a, n, b)
+	if alpha*beta == 0 {
+		state.zeroSolution()
+		return state.result, nil
+	}
+	h := make([]float64, n)
+	hbar := make([]float64, n)
+	copy(h, v)
+
+	zetabar := alpha * beta
+	alphabar := alpha
+	rho, rhobar, cbar, sbar := 1.0, 1.0, 1.0, 0.0
+
+	// variables for estimating ||r||
+	betadd, betad := beta, 0.0
+	rhodold, tautildeold, thetatilde, zeta, d := 1.0, 0.0, 0.0, 0.0, 0.0
+
+	// variables for estimating ||A|| and cond(A)
+	aNorm2 := alpha * alpha
+	maxrbar, minrbar := 0.0, math.Inf(1)
+
+	for k := 1; ; k++ {
+		if err := state.next(k); err != nil {
+			return state.result, err
+		}
+		alpha, beta = bidiagonalStep(a, u, v, alpha)
+
+		// construct the rotation eliminating the damping parameter
+		chat, shat, alphahat := symOrtho(alphabar, damp)
+
+		// construct and apply the rotation P_k
+		rhoold := rho
+		var c, s float64
+		c, s, rho = symOrtho(alphahat, beta)
+		thetanew := s * alpha
+		alphabar = c * alpha
+
+		// construct and apply the rotation Pbar_k
+		rhobarold, zetaold := rhobar, zeta
+		thetabar := sbar * rho
+		rhotemp := cbar * rho
+		cbar, sbar, rhobar = symOrtho(cbar*rho, thetanew)
+		zeta = cbar * zetabar
+		zetabar = -sbar * zetabar
+
+		// update h, hbar and x
+		floats.AddScaledTo(hbar, h, -thetabar*rho/(rhoold*rhobarold), hbar)
+		floats.AddScaled(x, zeta/(rho*rhobar), hbar)
+		floats.AddScaledTo(h, v, -thetanew/rho, h)
+
+		// estimate ||r||
+		betaacute := chat * betadd
+		betacheck := -shat * betadd
+		betahat := c * betaacute
+		betadd = -s * betaacute
+		thetatildeold := thetatilde
+		ctildeold, stildeold, rhotildeold := symOrtho(rhodold, thetabar)
+		thetatilde = stildeold * rhobar
+		rhodold = ctildeold * rhobar
+		betad = -stildeold*betad + ctildeold*betahat
+		tautildeold = (zetaold - thetatildeold*tautildeold) / rhotildeold
+		taud := (zeta - thetatilde*tautildeold) / rhodold
+		d += betacheck * betacheck
+		rNorm := math.Sqrt(d + (betad-taud)*(betad-taud) + betadd*betadd)
+
+		// estimate ||A|| and cond(A)
+		aNorm2 += beta * beta
+		aNorm := math.Sqrt(aNorm2)
+		aNorm2 += alpha * alpha
+		maxrbar = math.Max(maxrbar, rhobarold)
+		if k > 1 {
+			minrbar = math.Min(minrbar, rhobarold)
+		}
+		aCond := math.Max(maxrbar, rhotemp) / math.Min(minrbar, rhotemp)
+
+		if state.converged(k, rNorm, math.Abs(zetabar), aNorm, aCond, floats.Norm(x, 2)) {
+			return state.result, nil
+		}
+	}
+}
+
+// symOrtho computes a stable plane (Givens) rotation such that
+// [c s; -s c] * [a; b] = [r; 0].
+func symOrtho(a, b float64) (c, s, r float64) {
+	switch {
+	case b == 0:
+		return sign(a), 0, math.Abs(a)
+	case a == 0:
+		return 0, sign(b), math.Abs(b)
+	case math.Abs(b) > math.Abs(a):
+		tau := a / b
+		s = sign(b) / math.Sqrt(1+tau*tau)
+		return s * tau, s, b / s
+	default:
+		tau := b / a
+		c = sign(a) / math.Sqrt(1+tau*tau)
+		return c, c * tau, a / c
+	}
+}
+
+// sign returns the sign of x (1, -1 or 0).
+func sign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
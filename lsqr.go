@@ -0,0 +1,338 @@
+package sparse
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+// LeastSquaresSettings holds the settings for the iterative least squares solvers
+// LSQR and LSMR.  The zero value (or a nil *LeastSquaresSettings) uses the default
+// for each setting.
+type LeastSquaresSettings struct {
+	// Damp is the damping (regularisation) parameter.  If Damp is non-zero, the
+	// solvers compute the ridge regression solution minimising
+	// ||A*x - b||^2 + Damp^2 * ||x||^2.
+	Damp float64
+
+	// ATol and BTol are the relative tolerances for the stopping criteria.  The
+	// solvers stop when either ||r|| <= BTol * ||b|| + ATol * ||A|| * ||x|| (the
+	// system is compatible and r = b - A*x is small) or
+	// ||A^T * r|| <= ATol * ||A|| * ||r|| (x is a least squares solution).  ATol and
+	// BTol should be set to the relative accuracy of the elements of A and b (e.g.
+	// 1e-6 if they are correct to about 6 significant digits).  If zero, a default
+	// of 1e-8 is used.
+	ATol, BTol float64
+
+	// ConLim is the limit on the estimate of the condition number of A.  The solvers
+	// stop if the estimate exceeds ConLim which regularises ill-conditioned systems.
+	// If ConLim is zero, a default of 1e8 is used.  Use a negative value for no limit
+	// (other than 1/epsilon).
+	ConLim float64
+
+	// MaxIterations is the maximum number of iterations performed.  If
+	// MaxIterations is zero, a default of twice the number of columns of A is used.
+	MaxIterations int
+}
+
+// defaults returns a copy of the settings (or the default settings if s is nil)
+// with default values filled in for a matrix with n columns.
+func (s *LeastSquaresSettings) defaults(n int) LeastSquaresSettings {
+	var settings LeastSquaresSettings
+	if s != nil {
+		settings = *s
+	}
+	if settings.ATol == 0 {
+		settings.ATol = 1e-8
+	}
+	if settings.BTol == 0 {
+		settings.BTol = 1e-8
+	}
+	if settings.ConLim == 0 {
+		settings.ConLim = 1e8
+	}
+	if settings.MaxIterations == 0 {
+		settings.MaxIterations = 2 * n
+	}
+	return settings
+}
+
+// StoppingCriterion describes which stopping criterion terminated an iterative least
+// squares solve.
+type StoppingCriterion int
+
+const (
+	// NoCriterion indicates the solve was terminated before any stopping criterion
+	// was satisfied (the maximum number of iterations was reached or the solve was
+	// cancelled).
+	NoCriterion StoppingCriterion = iota
+
+	// ZeroSolution indicates that x = 0 is the exact solution (b = 0 or A^T * b = 0)
+	// so no iterations were performed.
+	ZeroSolution
+
+	// CompatibleSystem indicates that A * x = b is (approximately) satisfied to
+	// within the tolerances ATol and BTol (or machine precision).
+	CompatibleSystem
+
+	// LeastSquaresSolution indicates that x is an approximate least squares solution
+	// to within the tolerance ATol (or machine precision).
+	LeastSquaresSolution
+
+	// ConditionLimit indicates that the estimate of the condition number of A
+	// exceeded ConLim (or 1/epsilon).  The system appears to be ill-conditioned and
+	// x is a regularised solution.
+	ConditionLimit
+)
+
+// String implements the fmt.Stringer interface.
+func (c StoppingCriterion) String() string {
+	switch c {
+	case NoCriterion:
+		return "NoCriterion"
+	case ZeroSolution:
+		return "ZeroSolution"
+	case CompatibleSystem:
+		return "CompatibleSystem"
+	case LeastSquaresSolution:
+		return "LeastSquaresSolution"
+	case ConditionLimit:
+		return "ConditionLimit"
+	}
+	return fmt.Sprintf("StoppingCriterion(%d)", int(c))
+}
+
+// LeastSquaresResult holds the result of an iterative least squares solve.  Where A
+// is damped, the norms and condition number are those of the augmented system
+// [A; Damp*I] * x = [b; 0].
+type LeastSquaresResult struct {
+	// X is the approximate solution.
+	X []float64
+
+	// Status describes the outcome of the solve.  Status is Converged if any of the
+	// stopping criteria were satisfied.
+	Status SolverStatus
+
+	// Stop is the stopping criterion that terminated the solve.
+	Stop StoppingCriterion
+
+	// Iterations is the number of iterations performed.
+	Iterations int
+
+	// ResidualNorm is an estimate of the norm of the (damped) residual
+	// sqrt(||b - A*x||^2 + Damp^2 * ||x||^2).
+	ResidualNorm float64
+
+	// NormalResidualNorm is an estimate of the norm of the residual of the normal
+	// equations ||A^T * (b - A*x) - Damp^2 * x||.
+	NormalResidualNorm float64
+
+	// ANorm is an estimate of the Frobenius norm of A.
+	ANorm float64
+
+	// ACond is an estimate of the condition number of A.
+	ACond float64
+
+	// XNorm is an estimate of the norm of X.
+	XNorm float64
+}
+
+// leastSquaresState holds the state common to the iterative least squares solvers.
+type leastSquaresState struct {
+	ctx      context.Context
+	settings LeastSquaresSettings
+	result   *LeastSquaresResult
+	bNorm    float64
+}
+
+func newLeastSquaresState(ctx context.Context, n int, b []float64, s *LeastSquaresSettings) *leastSquaresState {
+	bNorm := floats.Norm(b, 2)
+	return &leastSquaresState{
+		ctx:      ctx,
+		settings: s.defaults(n),
+		result:   &LeastSquaresResult{X: make([]float64, n), ResidualNorm: bNorm},
+		bNorm:    bNorm,
+	}
+}
+
+// zeroSolution records that x = 0 is the exact solution.
+func (s *leastSquaresState) zeroSolution() {
+	s.result.Status = Converged
+	s.result.Stop = ZeroSolution
+}
+
+// converged records the estimates after an iteration and returns true if any of the
+// stopping criteria (as in the reference implementations of Paige and Saunders,
+// and Fong and Saunders) are satisfied.
+func (s *leastSquaresState) converged(iteration int, rNorm, arNorm, aNorm, aCond, xNorm float64) bool {
+	s.result.Iterations = iteration
+	s.result.ResidualNorm = rNorm
+	s.result.NormalResidualNorm = arNorm
+	s.result.ANorm = aNorm
+	s.result.ACond = aCond
+	s.result.XNorm = xNorm
+
+	test1 := rNorm / s.bNorm
+	test2 := arNorm / (aNorm*rNorm + epsilon)
+	test3 := 1 / (aCond + epsilon)
+	t1 := test1 / (1 + aNorm*xNorm/s.bNorm)
+	rtol := s.settings.BTol + s.settings.ATol*aNorm*xNorm/s.bNorm
+	var ctol float64
+	if s.settings.ConLim > 0 {
+		ctol = 1 / s.settings.ConLim
+	}
+
+	stop := NoCriterion
+	switch {
+	case test1 <= rtol || 1+t1 <= 1:
+		stop = CompatibleSystem
+	case test2 <= s.settings.ATol || 1+test2 <= 1:
+		stop = LeastSquaresSolution
+	case test3 <= ctol || 1+test3 <= 1:
+		stop = ConditionLimit
+	}
+	if stop == NoCriterion {
+		return false
+	}
+	s.result.Status = Converged
+	s.result.Stop = stop
+	return true
+}
+
+// next returns a non-nil error if the solver should stop before performing the
+// specified iteration either because the context has been cancelled or the maximum
+// number of iterations has been reached.
+func (s *leastSquaresState) next(iteration int) error {
+	if err := s.ctx.Err(); err != nil {
+		s.result.Status = Cancelled
+		return err
+	}
+	if iteration > s.settings.MaxIterations {
+		s.result.Status = NotConverged
+		return &NotConvergedError{Iterations: s.result.Iterations, Residual: s.result.ResidualNorm / s.bNorm}
+	}
+	return nil
+}
+
+// bidiagonalStep performs a step of Golub-Kahan bidiagonalisation computing
+// beta * u = A * v - alpha * u and alpha * v = A^T * u - beta * v in place returning
+// the new alpha and beta.
+func bidiagonalStep(a MulVecToer, u, v []float64, alpha float64) (float64, float64) {
+	floats.Scale(-alpha, u)
+	a.MulVecTo(u, false, v)
+	beta := floats.Norm(u, 2)
+	if beta > 0 {
+		floats.Scale(1/beta, u)
+		floats.Scale(-beta, v)
+		a.MulVecTo(v, true, u)
+		alpha = floats.Norm(v, 2)
+		if alpha > 0 {
+			floats.Scale(1/alpha, v)
+		}
+	}
+	return alpha, beta
+}
+
+// bidiagonalStart initialises Golub-Kahan bidiagonalisation with
+// beta * u = b and alpha * v = A^T * u returning the vectors along with alpha and beta.
+func bidiagonalStart(a MulVecToer, n int, b []float64) (u, v []float64, alpha, beta float64) {
+	u = make([]float64, len(b))
+	v = make([]float64, n)
+	copy(u, b)
+	beta = floats.Norm(u, 2)
+	if beta > 0 {
+		floats.Scale(1/beta, u)
+		a.MulVecTo(v, true, u)
+		alpha = floats.Norm(v, 2)
+	}
+	if alpha > 0 {
+		floats.Scale(1/alpha, v)
+	}
+	return u, v, alpha, beta
+}
+
+// LSQR solves the (possibly damped) linear least squares problem
+// min ||A*x - b||^2 + Damp^2 * ||x||^2 for x using the LSQR algorithm of Paige and
+// Saunders (1982) where a is an m x n matrix (or operator) and b is of length m.  A
+// may be rectangular (over or under determined) and of any rank.  Only the MulVecTo
+// method of a is used (with trans set to true for products with A^T) so A^T is never
+// formed explicitly and a may be a matrix free operator.  LSQR is analytically
+// equivalent to CG applied to the normal equations but with better numerical
+// properties.  settings may be nil in which case the default settings are used.
+//
+// If a stopping criterion is satisfied, LSQR returns the result with a Status of
+// Converged and the criterion recorded in Stop.  If the maximum number of iterations
+// is reached, the result is returned along with a *NotConvergedError.  If the context
+// is cancelled, the result is returned along with the error from ctx.
+func LSQR(ctx context.Context, a MulVecToer, n int, b []float64, settings *LeastSquaresSettings) (*LeastSquaresResult, error) {
+	state := newLeastSquaresState(ctx, n, b, settings)
+	x := state.result.X
+	damp := state.settings.Damp
+
+	u, v, alpha, beta := bidiagonalStart(a, n, b)
+	if alpha*beta == 0 {
+		state.zeroSolution()
+		return state.result, nil
+	}
+	w := make([]float64, n)
+	copy(w, v)
+
+	rhobar, phibar := alpha, beta
+	var aNorm, ddNorm, res2, xxNorm, z, sn2 float64
+	cs2 := -1.0
+
+	for k := 1; ; k++ {
+		if err := state.next(k); err != nil {
+			return state.result, err
+		}
+		prevAlpha := alpha
+		alpha, beta = bidiagonalStep(a, u, v, alpha)
+		if beta > 0 {
+			aNorm = math.Sqrt(aNorm*aNorm + prevAlpha*prevAlpha + beta*beta + damp*damp)
+		}
+
+		// eliminate the damping parameter with a plane rotation
+		rhobar1, psi := rhobar, 0.0
+		if damp != 0 {
+			rhobar1 = math.Hypot(rhobar, damp)
+			cs1, sn1 := rhobar/rhobar1, damp/rhobar1
+			psi = sn1 * phibar
+			phibar = cs1 * phibar
+		}
+
+		// eliminate the subdiagonal element beta with a plane rotation
+		rho := math.Hypot(rhobar1, beta)
+		cs, sn := rhobar1/rho, beta/rho
+		theta := sn * alpha
+		rhobar = -cs * alpha
+		phi := cs * phibar
+		phibar = sn * phibar
+		tau := sn * phi
+
+		// update x and w
+		ddNorm += floats.Dot(w, w) / (rho * rho)
+		floats.AddScaled(x, phi/rho, w)
+		floats.AddScaledTo(w, v, -theta/rho, w)
+
+		// estimate the norm of x using the plane rotations applied on the right
+		delta := sn2 * rho
+		gambar := -cs2 * rho
+		rhs := phi - delta*z
+		zbar := rhs / gambar
+		xNorm := math.Sqrt(xxNorm + zbar*zbar)
+		gamma := math.Hypot(gambar, theta)
+		cs2, sn2 = gambar/gamma, theta/gamma
+		z = rhs / gamma
+		xxNorm += z * z
+
+		aCond := aNorm * math.Sqrt(ddNorm)
+		res2 += psi * psi
+		rNorm := math.Sqrt(phibar*phibar + res2)
+		arNorm := alpha * math.Abs(tau)
+		if state.converged(k, rNorm, arNorm, aNorm, aCond, xNorm) {
+			return state.result, nil
+		}
+	}
+}
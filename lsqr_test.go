@@ -0,0 +1,184 @@
+package sparse
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+var leastSquaresSolvers = []struct {
+	name  string
+	solve func(ctx context.Context, a MulVecToer, n int, b []float64, settings *LeastSquaresSettings) (*LeastSquaresResult, error)
+}{
+	{name: "LSQR", solve: LSQR},
+	{name: "LSMR", solve: LSMR},
+}
+
+// denseLeastSquares returns the solution of the damped least squares problem
+// min ||A*x - b||^2 + damp^2 * ||x||^2 (or the minimum norm solution for under
+// determined systems) computed with dense factorisations.
+func denseLeastSquares(a *mat.Dense, b []float64, damp float64) []float64 {
+	m, n := a.Dims()
+	if m < n && damp == 0 {
+		// x = A^T * (A * A^T)^-1 * b
+		var aat mat.Dense
+		aat.Mul(a, a.T())
+		var y, x mat.VecDense
+		if err := y.SolveVec(&aat, mat.NewVecDense(m, b)); err != nil {
+			panic(err)
+		}
+		x.MulVec(a.T(), &y)
+		return x.RawVector().Data
+	}
+	// solve the normal equations (A^T * A + damp^2 * I) * x = A^T * b
+	var ata mat.Dense
+	ata.Mul(a.T(), a)
+	for i := 0; i < n; i++ {
+		ata.Set(i, i, ata.At(i, i)+damp*damp)
+	}
+	var atb, x mat.VecDense
+	atb.MulVec(a.T(), mat.NewVecDense(m, b))
+	if err := x.SolveVec(&ata, &atb); err != nil {
+		panic(err)
+	}
+	return x.RawVector().Data
+}
+
+func TestLeastSquares(t *testing.T) {
+	tests := []struct {
+		m, n    int
+		density float64
+		damp    float64
+	}{
+		{m: 200, n: 50, density: 0.1},
+		{m: 200, n: 50, density: 0.1, damp: 0.5},
+		{m: 500, n: 100, density: 0.02, damp: 0.01},
+		{m: 30, n: 60, density: 0.2},
+		{m: 30, n: 60, density: 0.2, damp: 1},
+	}
+
+	for ti, test := range tests {
+		data := randomData(test.m, test.n, test.density)
+		for i := 0; i < test.m && i < test.n; i++ {
+			data[i*test.n+i] += 1
+		}
+		dense := mat.NewDense(test.m, test.n, data)
+		a := CreateCSR(test.m, test.n, data).(*CSR)
+		b := make([]float64, test.m)
+		for i := range b {
+			b[i] = rand.NormFloat64()
+		}
+		want := denseLeastSquares(dense, b, test.damp)
+
+		for _, solver := range leastSquaresSolvers {
+			desc := fmt.Sprintf("%s Test %d (%d x %d, damp=%v)", solver.name, ti, test.m, test.n, test.damp)
+			settings := &LeastSquaresSettings{Damp: test.damp, ATol: 1e-12, BTol: 1e-12}
+			result, err := solver.solve(context.Background(), a, test.n, b, settings)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", desc, err)
+			}
+			if result.Status != Converged || result.Stop == NoCriterion {
+				t.Errorf("%s: expected convergence but status was %v (%v)", desc, result.Status, result.Stop)
+			}
+			if !floats.EqualApprox(result.X, want, 1e-6) {
+				t.Errorf("%s: expected %v but received %v", desc, want, result.X)
+			}
+
+			// check the residual estimates against the true residuals
+			r := make([]float64, test.m)
+			copy(r, b)
+			floats.Scale(-1, r)
+			a.MulVecTo(r, false, result.X)
+			rNorm := floats.Norm(r, 2)
+			xNorm := floats.Norm(result.X, 2)
+			rNorm2 := rNorm*rNorm + test.damp*test.damp*xNorm*xNorm
+			if !floats.EqualWithinAbsOrRel(result.ResidualNorm*result.ResidualNorm, rNorm2, 1e-6, 1e-6) {
+				t.Errorf("%s: expected residual norm %v but estimated %v", desc, rNorm2, result.ResidualNorm*result.ResidualNorm)
+			}
+			if !floats.EqualWithinAbsOrRel(result.XNorm, xNorm, 1e-6, 1e-6) {
+				t.Errorf("%s: expected solution norm %v but estimated %v", desc, xNorm, result.XNorm)
+			}
+		}
+	}
+}
+
+func TestLeastSquaresStoppingCriteria(t *testing.T) {
+	m, n := 100, 40
+	data := randomData(m, n, 0.1)
+	for i := 0; i < n; i++ {
+		data[i*n+i] += 1
+	}
+	a := CreateCSR(m, n, data).(*CSR)
+
+	// ill-conditioned matrix with the columns scaled over many orders of magnitude
+	scaled := make([]float64, len(data))
+	copy(scaled, data)
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			scaled[i*n+j] *= float64(int(1) << uint(j))
+		}
+	}
+	illConditioned := CreateCSR(m, n, scaled).(*CSR)
+
+	xTrue := make([]float64, n)
+	for i := range xTrue {
+		xTrue[i] = rand.NormFloat64()
+	}
+	compatible := make([]float64, m)
+	a.MulVecTo(compatible, false, xTrue)
+	random := make([]float64, m)
+	for i := range random {
+		random[i] = rand.NormFloat64()
+	}
+
+	tests := []struct {
+		desc     string
+		a        *CSR
+		b        []float64
+		settings *LeastSquaresSettings
+		want     StoppingCriterion
+		err      bool
+	}{
+		{desc: "zero", a: a, b: make([]float64, m), want: ZeroSolution},
+		{desc: "compatible", a: a, b: compatible, want: CompatibleSystem},
+		{desc: "least squares", a: a, b: random, want: LeastSquaresSolution},
+		{desc: "condition limit", a: illConditioned, b: random, settings: &LeastSquaresSettings{ConLim: 1e4}, want: ConditionLimit},
+		{desc: "iteration limit", a: a, b: random, settings: &LeastSquaresSettings{MaxIterations: 2}, want: NoCriterion, err: true},
+	}
+
+	for _, test := range tests {
+		for _, solver := range leastSquaresSolvers {
+			desc := fmt.Sprintf("%s %s", solver.name, test.desc)
+			result, err := solver.solve(context.Background(), test.a, n, test.b, test.settings)
+			if test.err {
+				if _, ok := err.(*NotConvergedError); !ok {
+					t.Errorf("%s: expected *NotConvergedError but received %v", desc, err)
+				}
+				if result.Status != NotConverged || result.Iterations != 2 {
+					t.Errorf("%s: expected NotConverged after 2 iterations but was %v after %d", desc, result.Status, result.Iterations)
+				}
+			} else if err != nil {
+				t.Errorf("%s: unexpected error: %v", desc, err)
+			}
+			if result.Stop != test.want {
+				t.Errorf("%s: expected stopping criterion %v but received %v", desc, test.want, result.Stop)
+			}
+			if test.want == CompatibleSystem && !floats.EqualApprox(result.X, xTrue, 1e-6) {
+				t.Errorf("%s: expected %v but received %v", desc, xTrue, result.X)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, solver := range leastSquaresSolvers {
+		result, err := solver.solve(ctx, a, n, random, nil)
+		if err != context.Canceled || result.Status != Cancelled {
+			t.Errorf("%s: expected cancellation but received %v (%v)", solver.name, err, result.Status)
+		}
+	}
+}
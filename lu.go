@@ -0,0 +1,473 @@
+package sparse
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james-bowman/sparse/blas"
+	"gonum.org/v1/gonum/mat"
+)
+
+// SingularError is the error returned when attempting to factorise a singular matrix.
+// A matrix is structurally singular when its sparsity pattern alone precludes a
+// non-singular matrix (e.g. an empty column or a set of columns whose non-zero
+// elements all fall within a smaller set of rows) and numerically singular when all
+// candidate pivots for a column are exactly zero despite a non-singular pattern.
+type SingularError struct {
+	// Index is the column of the matrix at which the factorisation broke down.
+	Index int
+
+	// Structural is true if the matrix is structurally singular and false if it is
+	// numerically singular.
+	Structural bool
+}
+
+// Error implements the error interface.
+func (e *SingularError) Error() string {
+	if e.Structural {
+		return fmt.Sprintf("sparse: matrix is structurally singular at column %d", e.Index)
+	}
+	return fmt.Sprintf("sparse: matrix is numerically singular at column %d", e.Index)
+}
+
+// Is allows SingularError to be matched against mat.ErrSingular with errors.Is.
+func (e *SingularError) Is(target error) bool {
+	return target == mat.ErrSingular
+}
+
+// LU is a sparse LU factorisation of a square matrix with partial (row) pivoting such
+// that P * A = L * U where P is a permutation matrix, L is unit lower triangular and U
+// is upper triangular.  LU shadows the gonum mat.LU type and is suitable for solving
+// unsymmetric sparse systems of equations.
+type LU struct {
+	n int
+
+	// l and u are stored in compressed sparse column form with row indices relative
+	// to the permuted matrix.  The unit diagonal of l is stored as the first element
+	// of each column and the diagonal of u as the last element of each column.
+	l *CSC
+	u *CSC
+
+	// pinv[i] is the row of the permuted matrix P * A containing row i of A and
+	// perm is its inverse.
+	pinv []int
+	perm []int
+
+	err error
+}
+
+// Dims returns the dimensions of the factorised matrix.
+func (lu *LU) Dims() (r, c int) {
+	return lu.n, lu.n
+}
+
+// Factorize computes the LU factorisation of the square matrix a using a left looking
+// (Gilbert-Peierls) algorithm with partial pivoting.  CSC matrices are factorised
+// directly, other matrix types are converted to CSC first.  Factorize panics if a is
+// not square.  If a is singular, a *SingularError is returned and the factorisation
+// may not be used to solve systems of equations.
+func (lu *LU) Factorize(a mat.Matrix) error {
+	r, c := a.Dims()
+	if r != c {
+		panic(mat.ErrShape)
+	}
+	var csc *CSC
+	switch t := a.(type) {
+	case *CSC:
+		csc = t
+	case TypeConverter:
+		csc = t.ToCSC()
+	default:
+		coo := NewCOO(r, c, nil, nil, nil)
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if v := a.At(i, j); v != 0 {
+					coo.Set(i, j, v)
+				}
+			}
+		}
+		csc = coo.ToCSC()
+	}
+
+	lu.n = r
+	lu.err = lu.factorize(&csc.matrix)
+	return lu.err
+}
+
+// factorize performs the left looking numerical factorisation of the CSC matrix a.
+// The columns of a are stored along the primary axis of a.
+func (lu *LU) factorize(a *blas.SparseMatrix) error {
+	n := lu.n
+	lp := make([]int, n+1)
+	up := make([]int, n+1)
+	li := make([]int, 0, 4*len(a.Ind)+n)
+	lx := make([]float64, 0, 4*len(a.Ind)+n)
+	ui := make([]int, 0, 4*len(a.Ind)+n)
+	ux := make([]float64, 0, 4*len(a.Ind)+n)
+
+	lu.pinv = make([]int, n)
+	for i := range lu.pinv {
+		lu.pinv[i] = -1
+	}
+	pinv := lu.pinv
+
+	x := getFloats(n, true)
+	defer putFloats(x)
+	xi := getInts(n, false)
+	defer putInts(xi)
+	stack := getInts(n, false)
+	defer putInts(stack)
+	pstack := getInts(n, false)
+	defer putInts(pstack)
+	marked := getInts(n, true)
+	defer putInts(marked)
+
+	for k := 0; k < n; k++ {
+		lp[k] = len(li)
+		up[k] = len(ui)
+
+		// compute the non-zero pattern of L \ A(:,k) in topological order
+		top := n
+		for p := a.Indptr[k]; p < a.Indptr[k+1]; p++ {
+			if marked[a.Ind[p]] != k+1 {
+				top = luReach(a.Ind[p], top, k+1, lp, li, pinv, marked, xi, stack, pstack)
+			}
+		}
+
+		// sparse triangular solve x = L \ A(:,k)
+		for _, i := range xi[top:] {
+			x[i] = 0
+		}
+		for p := a.Indptr[k]; p < a.Indptr[k+1]; p++ {
+			x[a.Ind[p]] += a.Data[p]
+		}
+		for _, j := range xi[top:] {
+			col := pinv[j]
+			if col < 0 {
+				continue
+			}
+			// the unit diagonal is stored first in each column of L
+			for p := lp[col] + 1; p < lp[col+1]; p++ {
+				x[li[p]] -= lx[p] * x[j]
+			}
+		}
+
+		// select the largest candidate as pivot and store column k of U
+		ipiv := -1
+		var pivot float64
+		for _, i := range xi[top:] {
+			if pinv[i] < 0 {
+				if ipiv < 0 || math.Abs(x[i]) > math.Abs(pivot) {
+					ipiv = i
+					pivot = x[i]
+				}
+			} else {
+				ui = append(ui, pinv[i])
+				ux = append(ux, x[i])
+			}
+		}
+		if ipiv < 0 {
+			return &SingularError{Index: k, Structural: true}
+		}
+		if pivot == 0 {
+			return &SingularError{Index: k, Structural: structuralRank(a) < n}
+		}
+		ui = append(ui, k)
+		ux = append(ux, pivot)
+		pinv[ipiv] = k
+
+		// store column k of L
+		li = append(li, ipiv)
+		lx = append(lx, 1)
+		for _, i := range xi[top:] {
+			if pinv[i] < 0 {
+				li = append(li, i)
+				lx = append(lx, x[i]/pivot)
+			}
+		}
+	}
+	lp[n] = len(li)
+	up[n] = len(ui)
+
+	// convert row indices of L into rows of the permuted matrix
+	for p, i := range li {
+		li[p] = pinv[i]
+	}
+	lu.perm = make([]int, n)
+	for i, k := range pinv {
+		lu.perm[k] = i
+	}
+
+	lu.l = NewCSC(n, n, lp, li, lx)
+	lu.u = NewCSC(n, n, up, ui, ux)
+	return nil
+}
+
+// structuralRank returns the structural rank of the compressed sparse matrix a
+// i.e. the size of a maximum matching between its primary and secondary axes
+// found using depth first search for augmenting paths.
+func structuralRank(a *blas.SparseMatrix) int {
+	match := make([]int, a.J)
+	for i := range match {
+		match[i] = -1
+	}
+	visited := make([]int, a.J)
+
+	var augment func(k, mark int) bool
+	augment = func(k, mark int) bool {
+		for p := a.Indptr[k]; p < a.Indptr[k+1]; p++ {
+			i := a.Ind[p]
+			if visited[i] == mark {
+				continue
+			}
+			visited[i] = mark
+			if match[i] < 0 || augment(match[i], mark) {
+				match[i] = k
+				return true
+			}
+		}
+		return false
+	}
+
+	var rank int
+	for k := 0; k < a.I; k++ {
+		if augment(k, k+1) {
+			rank++
+		}
+	}
+	return rank
+}
+
+// luReach performs a depth first search of the graph of the partially constructed L
+// factor starting from row j.  Rows are pushed onto xi[:top] in reverse topological
+// order as they are completed and the new top is returned.  Visited rows are flagged
+// in marked with the value mark.
+func luReach(j, top, mark int, lp, li, pinv, marked, xi, stack, pstack []int) int {
+	head := 0
+	stack[0] = j
+	for head >= 0 {
+		j = stack[head]
+		col := pinv[j]
+		if marked[j] != mark {
+			marked[j] = mark
+			if col < 0 {
+				pstack[head] = 0
+			} else {
+				pstack[head] = lp[col] + 1
+			}
+		}
+		done := true
+		end := 0
+		if col >= 0 {
+			end = lp[col+1]
+		}
+		for p := pstack[head]; p < end; p++ {
+			i := li[p]
+			if marked[i] == mark {
+				continue
+			}
+			pstack[head] = p + 1
+			head++
+			stack[head] = i
+			done = false
+			break
+		}
+		if done {
+			head--
+			top--
+			xi[top] = j
+		}
+	}
+	return top
+}
+
+// Det returns the determinant of the factorised matrix.
+func (lu *LU) Det() float64 {
+	det, sign := lu.LogDet()
+	return math.Exp(det) * sign
+}
+
+// LogDet returns the log of the determinant and the sign of the determinant
+// for the factorised matrix.
+func (lu *LU) LogDet() (det float64, sign float64) {
+	if lu.err != nil {
+		return math.Inf(-1), 0
+	}
+	sign = 1.0
+	for k := 0; k < lu.n; k++ {
+		v := lu.u.matrix.Data[lu.u.matrix.Indptr[k+1]-1]
+		if v < 0 {
+			sign *= -1
+		}
+		det += math.Log(math.Abs(v))
+	}
+
+	// the sign of the permutation is determined by the parity of its cycles
+	visited := make([]bool, lu.n)
+	for i := range lu.perm {
+		if visited[i] {
+			continue
+		}
+		var length int
+		for j := i; !visited[j]; j = lu.perm[j] {
+			visited[j] = true
+			length++
+		}
+		if length%2 == 0 {
+			sign *= -1
+		}
+	}
+	return det, sign
+}
+
+// RowPivots returns the row permutation that represents the permutation matrix P
+// from the factorisation such that row i of P * A is row dst[i] of A.  If dst is
+// nil, a new slice is allocated and returned.  If dst is not nil, it must have length
+// equal to the size of the factorised matrix.
+func (lu *LU) RowPivots(dst []int) []int {
+	if dst == nil {
+		dst = make([]int, lu.n)
+	}
+	if len(dst) != lu.n {
+		panic(mat.ErrShape)
+	}
+	copy(dst, lu.perm)
+	return dst
+}
+
+// LTo extracts the unit lower triangular matrix from the factorisation and stores it
+// in dst.  If dst is empty it is resized to the correct size, otherwise LTo panics if
+// dst is not the same size as the factorised matrix.  LTo panics if the factorisation
+// failed (e.g. because the matrix was singular).
+func (lu *LU) LTo(dst *CSR) {
+	lu.checkFactors(dst)
+	dst.matrix = lu.l.ToCSR().matrix
+}
+
+// UTo extracts the upper triangular matrix from the factorisation and stores it
+// in dst.  If dst is empty it is resized to the correct size, otherwise UTo panics if
+// dst is not the same size as the factorised matrix.  UTo panics if the factorisation
+// failed (e.g. because the matrix was singular).
+func (lu *LU) UTo(dst *CSR) {
+	lu.checkFactors(dst)
+	dst.matrix = lu.u.ToCSR().matrix
+}
+
+// checkFactors panics if the receiver does not hold a successful factorisation or if
+// dst is neither empty nor the same size as the factorised matrix.
+func (lu *LU) checkFactors(dst *CSR) {
+	if lu.err != nil || lu.l == nil {
+		panic("sparse: LU factors requested without a successful factorisation")
+	}
+	if r, c := dst.Dims(); !dst.IsZero() && (r != lu.n || c != lu.n) {
+		panic(mat.ErrShape)
+	}
+}
+
+// SolveVecTo solves a system of linear equations using the LU factorisation of a matrix.
+// It computes
+//  A * x = b if trans == false
+//  A^T * x = b if trans == true
+// placing the result in dst.  If dst is empty it is resized to the correct length,
+// otherwise SolveVecTo panics if the length of dst is not the same as the size of the
+// factorised matrix.  If the factorised matrix was singular, the error from the
+// factorisation is returned.
+func (lu *LU) SolveVecTo(dst *mat.VecDense, trans bool, b mat.Vector) error {
+	n := lu.n
+	if b.Len() != n {
+		panic(mat.ErrShape)
+	}
+	if dst.IsEmpty() {
+		dst.ReuseAsVec(n)
+	} else if dst.Len() != n {
+		panic(mat.ErrShape)
+	}
+	if lu.err != nil {
+		return lu.err
+	}
+
+	x := getFloats(n, false)
+	defer putFloats(x)
+
+	l, u := &lu.l.matrix, &lu.u.matrix
+	if !trans {
+		// L * U * x = P * b
+		for k, i := range lu.perm {
+			x[k] = b.AtVec(i)
+		}
+		for j := 0; j < n; j++ {
+			for p := l.Indptr[j] + 1; p < l.Indptr[j+1]; p++ {
+				x[l.Ind[p]] -= l.Data[p] * x[j]
+			}
+		}
+		for j := n - 1; j >= 0; j-- {
+			diag := u.Indptr[j+1] - 1
+			x[j] /= u.Data[diag]
+			for p := u.Indptr[j]; p < diag; p++ {
+				x[u.Ind[p]] -= u.Data[p] * x[j]
+			}
+		}
+		for k, v := range x {
+			dst.SetVec(k, v)
+		}
+		return nil
+	}
+
+	// U^T * L^T * P * x = b
+	for j := 0; j < n; j++ {
+		v := b.AtVec(j)
+		diag := u.Indptr[j+1] - 1
+		for p := u.Indptr[j]; p < diag; p++ {
+			v -= u.Data[p] * x[u.Ind[p]]
+		}
+		x[j] = v / u.Data[diag]
+	}
+	for j := n - 1; j >= 0; j-- {
+		v := x[j]
+		for p := l.Indptr[j] + 1; p < l.Indptr[j+1]; p++ {
+			v -= l.Data[p] * x[l.Ind[p]]
+		}
+		x[j] = v
+	}
+	for k, i := range lu.perm {
+		dst.SetVec(i, x[k])
+	}
+	return nil
+}
+
+// SolveTo solves a system of linear equations using the LU factorisation of a matrix.
+// It computes
+//  A * X = B if trans == false
+//  A^T * X = B if trans == true
+// placing the result in dst.  If dst is empty it is resized to the correct size,
+// otherwise SolveTo panics if dst is not the correct size.  If the factorised matrix
+// was singular, the error from the factorisation is returned.
+func (lu *LU) SolveTo(dst *mat.Dense, trans bool, b mat.Matrix) error {
+	rows, cols := b.Dims()
+	if rows != lu.n {
+		panic(mat.ErrShape)
+	}
+	if dst.IsEmpty() {
+		dst.ReuseAs(lu.n, cols)
+	} else if r, c := dst.Dims(); r != lu.n || c != cols {
+		panic(mat.ErrShape)
+	}
+	if lu.err != nil {
+		return lu.err
+	}
+
+	bv, bHasColView := b.(mat.ColViewer)
+	for c := 0; c < cols; c++ {
+		dstView := dst.ColView(c).(*mat.VecDense)
+		var cv mat.Vector
+		if bHasColView {
+			cv = bv.ColView(c)
+		} else {
+			cv = mat.NewVecDense(rows, mat.Col(nil, c, b))
+		}
+		if err := lu.SolveVecTo(dstView, trans, cv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package sparse
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/floats/scalar"
+	"gonum.org/v1/gonum/mat"
+)
+
+// randomUnsymmetric returns the elements of a random, diagonally dominant and
+// therefore non-singular n * n unsymmetric matrix in row major order.
+func randomUnsymmetric(n int, density float64) []float64 {
+	data := randomData(n, n, density)
+	for i := 0; i < n; i++ {
+		data[i*n+i] = float64(n) * (rand.Float64() + 0.5)
+		if rand.Intn(2) == 0 {
+			data[i*n+i] = -data[i*n+i]
+		}
+	}
+	return data
+}
+
+func TestLUFactorize(t *testing.T) {
+	var tests = []struct {
+		n    int
+		data []float64
+	}{
+		{
+			n: 3,
+			data: []float64{
+				0, 2, 1,
+				3, 0, 0,
+				1, 1, 4,
+			},
+		},
+		{
+			n: 4,
+			data: []float64{
+				1, 0, 0, 2,
+				0, 0, 3, 0,
+				4, 5, 0, 0,
+				0, 6, 0, 7,
+			},
+		},
+		{n: 10, data: randomUnsymmetric(10, 0.3)},
+		{n: 50, data: randomUnsymmetric(50, 0.1)},
+		{n: 50, data: randomData(50, 50, 0.5)},
+	}
+
+	for ti, test := range tests {
+		a := mat.NewDense(test.n, test.n, test.data)
+
+		for name, m := range map[string]mat.Matrix{
+			"CSR":   CreateCSR(test.n, test.n, test.data),
+			"CSC":   CreateCSC(test.n, test.n, test.data),
+			"Dense": a,
+		} {
+			var lu LU
+			if err := lu.Factorize(m); err != nil {
+				t.Errorf("Test %d (%s): unexpected error %v", ti, name, err)
+				continue
+			}
+
+			// check P * A = L * U
+			l := NewCSR(test.n, test.n, nil, nil, nil)
+			u := NewCSR(test.n, test.n, nil, nil, nil)
+			lu.LTo(l)
+			lu.UTo(u)
+			var got mat.Dense
+			got.Mul(l, u)
+			pivots := lu.RowPivots(nil)
+			want := mat.NewDense(test.n, test.n, nil)
+			for i, p := range pivots {
+				want.SetRow(i, a.RawRowView(p))
+			}
+			if !mat.EqualApprox(want, &got, 1e-10) {
+				t.Errorf("Test %d (%s): expected P * A\n%v\nbut received L * U\n%v", ti, name, mat.Formatted(want), mat.Formatted(&got))
+			}
+			for i := 0; i < test.n; i++ {
+				if l.At(i, i) != 1 {
+					t.Errorf("Test %d (%s): expected unit diagonal in L but found %f at %d", ti, name, l.At(i, i), i)
+				}
+				for j := i + 1; j < test.n; j++ {
+					if l.At(i, j) != 0 || u.At(j, i) != 0 {
+						t.Errorf("Test %d (%s): factors not triangular at (%d, %d)", ti, name, i, j)
+					}
+				}
+			}
+
+			if det := mat.Det(a); !scalar.EqualWithinRel(det, lu.Det(), 1e-10) {
+				t.Errorf("Test %d (%s): expected determinant %f but received %f", ti, name, det, lu.Det())
+			}
+			var dlu mat.LU
+			dlu.Factorize(a)
+			wantDet, wantSign := dlu.LogDet()
+			gotDet, gotSign := lu.LogDet()
+			if !scalar.EqualWithinAbsOrRel(wantDet, gotDet, 1e-10, 1e-10) || wantSign != gotSign {
+				t.Errorf("Test %d (%s): expected log determinant (%f, %f) but received (%f, %f)", ti, name, wantDet, wantSign, gotDet, gotSign)
+			}
+		}
+	}
+}
+
+func TestLUSolve(t *testing.T) {
+	for _, n := range []int{1, 5, 20, 100} {
+		data := randomUnsymmetric(n, 0.2)
+		a := mat.NewDense(n, n, data)
+		var lu LU
+		if err := lu.Factorize(CreateCSR(n, n, data)); err != nil {
+			t.Fatalf("n=%d: unexpected error %v", n, err)
+		}
+
+		for _, trans := range []bool{false, true} {
+			var op mat.Matrix = a
+			if trans {
+				op = a.T()
+			}
+
+			want := mat.NewVecDense(n, nil)
+			for i := 0; i < n; i++ {
+				want.SetVec(i, float64(i+1))
+			}
+			var b mat.VecDense
+			b.MulVec(op, want)
+
+			var got mat.VecDense
+			if err := lu.SolveVecTo(&got, trans, &b); err != nil {
+				t.Errorf("n=%d (trans=%t): unexpected error %v", n, trans, err)
+			}
+			if !mat.EqualApprox(want, &got, 1e-10) {
+				t.Errorf("n=%d (trans=%t): expected\n%v\nbut received\n%v", n, trans, mat.Formatted(want.T()), mat.Formatted(got.T()))
+			}
+
+			wantM := mat.NewDense(n, 3, randomData(n, 3, 1))
+			var bm mat.Dense
+			bm.Mul(op, wantM)
+			var gotM mat.Dense
+			if err := lu.SolveTo(&gotM, trans, &bm); err != nil {
+				t.Errorf("n=%d (trans=%t): unexpected error %v", n, trans, err)
+			}
+			if !mat.EqualApprox(wantM, &gotM, 1e-10) {
+				t.Errorf("n=%d (trans=%t): expected\n%v\nbut received\n%v", n, trans, mat.Formatted(wantM), mat.Formatted(&gotM))
+			}
+		}
+	}
+}
+
+func TestLUSingular(t *testing.T) {
+	var tests = []struct {
+		desc       string
+		n          int
+		data       []float64
+		index      int
+		structural bool
+	}{
+		{
+			desc: "empty column",
+			n:    3,
+			data: []float64{
+				1, 0, 2,
+				3, 0, 0,
+				0, 0, 4,
+			},
+			index:      1,
+			structural: true,
+		},
+		{
+			desc: "structurally rank deficient",
+			n:    3,
+			data: []float64{
+				3, 1, 1,
+				1, 0, 0,
+				1, 0, 0,
+			},
+			index:      2,
+			structural: true,
+		},
+		{
+			desc: "numerically singular",
+			n:    3,
+			data: []float64{
+				1, 2, 0,
+				2, 4, 0,
+				0, 0, 1,
+			},
+			index:      1,
+			structural: false,
+		},
+	}
+
+	for _, test := range tests {
+		var lu LU
+		err := lu.Factorize(CreateCSR(test.n, test.n, test.data))
+		if err == nil {
+			t.Errorf("%s: expected error but received nil", test.desc)
+			continue
+		}
+		var serr *SingularError
+		if !errors.As(err, &serr) {
+			t.Errorf("%s: expected *SingularError but received %T", test.desc, err)
+			continue
+		}
+		if serr.Index != test.index || serr.Structural != test.structural {
+			t.Errorf("%s: expected singularity at %d (structural=%t) but received %d (structural=%t)", test.desc, test.index, test.structural, serr.Index, serr.Structural)
+		}
+		if !errors.Is(err, mat.ErrSingular) {
+			t.Errorf("%s: expected error to match mat.ErrSingular", test.desc)
+		}
+
+		var x mat.VecDense
+		if err := lu.SolveVecTo(&x, false, mat.NewVecDense(test.n, nil)); err == nil {
+			t.Errorf("%s: expected error solving with singular factorisation", test.desc)
+		}
+		for i := 0; i < x.Len(); i++ {
+			if math.IsNaN(x.AtVec(i)) {
+				t.Errorf("%s: unexpected NaN in solution", test.desc)
+			}
+		}
+		if lu.Det() != 0 {
+			t.Errorf("%s: expected zero determinant but received %f", test.desc, lu.Det())
+		}
+	}
+}
+
+func TestLUFactors(t *testing.T) {
+	data := []float64{
+		0, 2, 1,
+		4, 1, 0,
+		1, 0, 3,
+	}
+	var lu LU
+	if err := lu.Factorize(CreateCSR(3, 3, data)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// empty destinations are resized
+	var l, u CSR
+	lu.LTo(&l)
+	lu.UTo(&u)
+	var got mat.Dense
+	got.Mul(&l, &u)
+	a := mat.NewDense(3, 3, data)
+	want := mat.NewDense(3, 3, nil)
+	for i, p := range lu.RowPivots(nil) {
+		want.SetRow(i, a.RawRowView(p))
+	}
+	if !mat.EqualApprox(want, &got, 1e-12) {
+		t.Errorf("expected P * A\n%v\nbut received L * U\n%v", mat.Formatted(want), mat.Formatted(&got))
+	}
+
+	panicValue := func(fn func()) (r interface{}) {
+		defer func() {
+			r = recover()
+		}()
+		fn()
+		return nil
+	}
+	if r := panicValue(func() { lu.LTo(NewCSR(2, 2, nil, nil, nil)) }); r != mat.ErrShape {
+		t.Errorf("expected LTo to panic with mat.ErrShape for incorrectly sized destination but received %v", r)
+	}
+
+	// factors of a failed factorisation are not available
+	if err := lu.Factorize(CreateCSR(2, 2, []float64{1, 2, 2, 4})); err == nil {
+		t.Fatalf("expected error factorising singular matrix")
+	}
+	const msg = "sparse: LU factors requested without a successful factorisation"
+	if r := panicValue(func() { lu.LTo(&CSR{}) }); r != msg {
+		t.Errorf("expected LTo to panic after failed factorisation but received %v", r)
+	}
+	if r := panicValue(func() { lu.UTo(&CSR{}) }); r != msg {
+		t.Errorf("expected UTo to panic after failed factorisation but received %v", r)
+	}
+}
@@ -84,6 +84,48 @@ func (s CSCType) Convert(from TypeConverter) mat.Matrix {
 	return from.ToCSC()
 }
 
+// ELLType represents the ELL (ELLPACK) matrix type format
+type ELLType int
+
+// Convert converts the specified TypeConverter to ELL (ELLPACK) format
+func (s ELLType) Convert(from TypeConverter) mat.Matrix {
+	return from.ToCSR().ToELL()
+}
+
+// SELLType represents the SELL-C-σ (Sliced ELLPACK) matrix type format with chunks of
+// Chunk rows sorted by length within windows of Sigma rows
+type SELLType struct {
+	Chunk, Sigma int
+}
+
+// Convert converts the specified TypeConverter to SELL (Sliced ELLPACK) format
+func (s SELLType) Convert(from TypeConverter) mat.Matrix {
+	return from.ToCSR().ToSELL(s.Chunk, s.Sigma)
+}
+
+// SELLFormat returns a value representing SELL-C-σ matrix format with chunks of chunk
+// rows sorted by length within windows of sigma rows.
+func SELLFormat(chunk, sigma int) SELLType {
+	return SELLType{Chunk: chunk, Sigma: sigma}
+}
+
+// BSRType represents the BSR (Block Sparse Row) matrix type format with blocks of R * C elements
+type BSRType struct {
+	R, C int
+}
+
+// Convert converts the specified TypeConverter to BSR (Block Sparse Row) format
+func (s BSRType) Convert(from TypeConverter) mat.Matrix {
+	return from.ToCSR().ToBSR(s.R, s.C)
+}
+
+// BSRFormat returns a value representing BSR matrix format with dense blocks of size r * c.
+// Unlike the other formats, BSR format requires the block size to be specified so is
+// parameterised rather than being a simple enum value.
+func BSRFormat(r, c int) BSRType {
+	return BSRType{R: r, C: c}
+}
+
 const (
 	// DenseFormat is an enum value representing Dense matrix format
 	DenseFormat DenseType = iota
@@ -99,6 +141,9 @@ const (
 
 	// CSCFormat is an enum value representing CSC matrix format
 	CSCFormat CSCType = iota
+
+	// ELLFormat is an enum value representing ELL matrix format
+	ELLFormat ELLType = iota
 )
 
 // Random constructs a new matrix of the specified type e.g. Dense, COO, CSR, etc.
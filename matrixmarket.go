@@ -0,0 +1,250 @@
+package sparse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// matrixMarketHeader is the banner identifying a Matrix Market file.
+const matrixMarketHeader = "%%MatrixMarket"
+
+// maxPrealloc is the maximum number of elements preallocated based upon the sizes
+// declared in the header of a file being read.  Larger matrices are grown as their
+// elements are read so that a corrupt header cannot cause huge allocations.
+const maxPrealloc = 1 << 20
+
+// ReadMatrixMarket reads a matrix in Matrix Market exchange format (as used by the
+// SuiteSparse Matrix Collection) from r and returns it as a COO matrix.  Both the
+// coordinate (sparse) and array (dense, column major) formats are supported with
+// real, double, integer or pattern (coordinate format only) fields.  Pattern matrices
+// are read with a value of 1 for each entry.  Symmetric and skew-symmetric matrices
+// (which store only the lower triangle) are expanded into the full matrix by
+// mirroring each off-diagonal entry.  Duplicate entries in coordinate format files
+// are retained and so are summed when the matrix is converted to other formats (e.g.
+// with ToCSR).  Zero valued entries in array format files are not stored.  Complex
+// and Hermitian matrices are not supported.
+func ReadMatrixMarket(r io.Reader) (*COO, error) {
+	p := &mmParser{scanner: bufio.NewScanner(r)}
+	p.scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	if !p.scanner.Scan() {
+		return nil, p.errorf("missing Matrix Market header")
+	}
+	p.line++
+	header := strings.Fields(strings.ToLower(p.scanner.Text()))
+	if len(header) != 5 || header[0] != strings.ToLower(matrixMarketHeader) || header[1] != "matrix" {
+		return nil, p.errorf("invalid Matrix Market header %q", p.scanner.Text())
+	}
+	format, field, symmetry := header[2], header[3], header[4]
+	if format != "coordinate" && format != "array" {
+		return nil, p.errorf("unsupported format %q", format)
+	}
+	switch field {
+	case "real", "double", "integer":
+	case "pattern":
+		if format == "array" {
+			return nil, p.errorf("pattern field is not valid for array format")
+		}
+	default:
+		return nil, p.errorf("unsupported field %q", field)
+	}
+	if symmetry != "general" && symmetry != "symmetric" && symmetry != "skew-symmetric" {
+		return nil, p.errorf("unsupported symmetry %q", symmetry)
+	}
+
+	size, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	var dims []int
+	if format == "coordinate" {
+		dims, err = p.ints(size, 3)
+	} else {
+		dims, err = p.ints(size, 2)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, cols := dims[0], dims[1]
+	if symmetry != "general" && rows != cols {
+		return nil, p.errorf("%s matrix must be square", symmetry)
+	}
+
+	var entries int
+	switch {
+	case format == "coordinate":
+		entries = dims[2]
+	case rows != 0 && cols > int(maxLen)/rows:
+		return nil, p.errorf("%d x %d matrix is too large", rows, cols)
+	case symmetry == "symmetric":
+		// rows * (rows + 1) / 2 without overflowing
+		entries = rows*cols/2 + (rows+1)/2
+	case symmetry == "skew-symmetric":
+		entries = rows*cols/2 - rows/2
+	default:
+		entries = rows * cols
+	}
+	capacity := min(entries, maxPrealloc)
+	if symmetry != "general" {
+		capacity *= 2
+	}
+	coo := NewCOO(rows, cols, make([]int, 0, capacity), make([]int, 0, capacity), make([]float64, 0, capacity))
+	add := func(i, j int, v float64) error {
+		switch {
+		case symmetry == "general":
+		case i == j && symmetry == "skew-symmetric":
+			return p.errorf("entry (%d, %d) is on the diagonal of a skew-symmetric matrix", i+1, j+1)
+		case i != j && symmetry == "symmetric":
+			coo.append(j, i, v)
+		case i != j:
+			coo.append(j, i, -v)
+		}
+		coo.append(i, j, v)
+		return nil
+	}
+
+	// array format entries are stored in column major order (of the lower triangle
+	// for symmetric matrices)
+	var i, j int
+	if symmetry == "skew-symmetric" {
+		i = 1
+	}
+	for k := 0; k < entries; k++ {
+		fields, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if format == "array" {
+			if len(fields) != 1 {
+				return nil, p.errorf("expected 1 value but found %d", len(fields))
+			}
+			v, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, p.errorf("invalid value %q", fields[0])
+			}
+			if v != 0 {
+				if err := add(i, j, v); err != nil {
+					return nil, err
+				}
+			}
+			if i++; i == rows {
+				j++
+				switch symmetry {
+				case "general":
+					i = 0
+				case "symmetric":
+					i = j
+				case "skew-symmetric":
+					i = j + 1
+				}
+			}
+			continue
+		}
+
+		n := 3
+		if field == "pattern" {
+			n = 2
+		}
+		if len(fields) != n {
+			return nil, p.errorf("expected %d values but found %d", n, len(fields))
+		}
+		index, err := p.ints(fields[:2], 2)
+		if err != nil {
+			return nil, err
+		}
+		if index[0] < 1 || index[0] > rows || index[1] < 1 || index[1] > cols {
+			return nil, p.errorf("entry (%d, %d) is outside the %d x %d matrix", index[0], index[1], rows, cols)
+		}
+		v := 1.0
+		if field != "pattern" {
+			if v, err = strconv.ParseFloat(fields[2], 64); err != nil {
+				return nil, p.errorf("invalid value %q", fields[2])
+			}
+		}
+		if err := add(index[0]-1, index[1]-1, v); err != nil {
+			return nil, err
+		}
+	}
+	return coo, nil
+}
+
+// append appends the element at row i and column j with value v without checking
+// the indices are within the dimensions of the matrix.
+func (c *COO) append(i, j int, v float64) {
+	c.rows = append(c.rows, i)
+	c.cols = append(c.cols, j)
+	c.data = append(c.data, v)
+}
+
+// mmParser reads the lines of a Matrix Market file.
+type mmParser struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// next returns the fields of the next line that is not a comment or blank.
+func (p *mmParser) next() ([]string, error) {
+	for p.scanner.Scan() {
+		p.line++
+		fields := strings.Fields(p.scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "%") {
+			continue
+		}
+		return fields, nil
+	}
+	if err := p.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, p.errorf("unexpected end of data")
+}
+
+// ints parses the n fields as non-negative integers.
+func (p *mmParser) ints(fields []string, n int) ([]int, error) {
+	if len(fields) != n {
+		return nil, p.errorf("expected %d integers but found %d", n, len(fields))
+	}
+	v := make([]int, n)
+	for i, f := range fields {
+		var err error
+		if v[i], err = strconv.Atoi(f); err != nil || v[i] < 0 {
+			return nil, p.errorf("invalid integer %q", f)
+		}
+	}
+	return v, nil
+}
+
+// errorf returns an error describing a problem with the current line.
+func (p *mmParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("sparse: Matrix Market line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// WriteMatrixMarket writes the matrix m to w in Matrix Market exchange format.  The
+// matrix is written in (real, general) coordinate format with an entry for each
+// element visited by DoNonZero so any duplicate elements of a COO matrix (which are
+// summed when read) are retained.  Values are written with the minimum precision
+// necessary to be read back exactly.
+func WriteMatrixMarket(w io.Writer, m Sparser) error {
+	r, c := m.Dims()
+	nnz := 0
+	m.DoNonZero(func(i, j int, v float64) {
+		nnz++
+	})
+
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "%s matrix coordinate real general\n", matrixMarketHeader)
+	fmt.Fprintf(buf, "%d %d %d\n", r, c, nnz)
+	var line []byte
+	m.DoNonZero(func(i, j int, v float64) {
+		line = strconv.AppendInt(line[:0], int64(i+1), 10)
+		line = append(line, ' ')
+		line = strconv.AppendInt(line, int64(j+1), 10)
+		line = append(line, ' ')
+		line = strconv.AppendFloat(line, v, 'g', -1, 64)
+		line = append(line, '\n')
+		buf.Write(line)
+	})
+	return buf.Flush()
+}
@@ -0,0 +1,233 @@
+package sparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestReadMatrixMarket(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+		r, c  int
+		nnz   int
+		data  []float64
+	}{
+		{
+			desc: "coordinate real general",
+			input: `%%MatrixMarket matrix coordinate real general
+% a comment
+
+3 4 5
+1 1 1.5
+2 3 -2e-1
+3 4 7
+1 4 3
+3 1 -1
+`,
+			r: 3, c: 4, nnz: 5,
+			data: []float64{
+				1.5, 0, 0, 3,
+				0, 0, -0.2, 0,
+				-1, 0, 0, 7,
+			},
+		},
+		{
+			desc: "coordinate integer general with duplicates",
+			input: `%%MatrixMarket matrix coordinate integer general
+2 2 4
+1 1 1
+1 1 2
+2 2 3
+2 1 4
+`,
+			r: 2, c: 2, nnz: 4,
+			data: []float64{
+				3, 0,
+				4, 3,
+			},
+		},
+		{
+			desc: "coordinate real symmetric",
+			input: `%%MatrixMarket matrix coordinate real symmetric
+3 3 4
+1 1 4
+2 1 -1
+3 2 -2
+3 3 5
+`,
+			r: 3, c: 3, nnz: 6,
+			data: []float64{
+				4, -1, 0,
+				-1, 0, -2,
+				0, -2, 5,
+			},
+		},
+		{
+			desc: "coordinate pattern skew-symmetric",
+			input: `%%MatrixMarket matrix coordinate pattern skew-symmetric
+3 3 2
+2 1
+3 1
+`,
+			r: 3, c: 3, nnz: 4,
+			data: []float64{
+				0, -1, -1,
+				1, 0, 0,
+				1, 0, 0,
+			},
+		},
+		{
+			desc: "array real general",
+			input: `%%MatrixMarket matrix array real general
+2 3
+1
+0
+2
+3
+0
+4
+`,
+			r: 2, c: 3, nnz: 4,
+			data: []float64{
+				1, 2, 0,
+				0, 3, 4,
+			},
+		},
+		{
+			desc: "array real symmetric",
+			input: `%%MatrixMarket matrix array real symmetric
+3 3
+1
+2
+3
+4
+0
+6
+`,
+			r: 3, c: 3, nnz: 7,
+			data: []float64{
+				1, 2, 3,
+				2, 4, 0,
+				3, 0, 6,
+			},
+		},
+		{
+			desc: "array real skew-symmetric",
+			input: `%%MatrixMarket matrix array real skew-symmetric
+3 3
+1
+2
+3
+`,
+			r: 3, c: 3, nnz: 6,
+			data: []float64{
+				0, -1, -2,
+				1, 0, -3,
+				2, 3, 0,
+			},
+		},
+		{
+			desc: "upper case header",
+			input: `%%MatrixMarket MATRIX Coordinate Real General
+1 1 1
+1 1 2
+`,
+			r: 1, c: 1, nnz: 1,
+			data: []float64{2},
+		},
+	}
+
+	for _, test := range tests {
+		coo, err := ReadMatrixMarket(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.desc, err)
+			continue
+		}
+		if r, c := coo.Dims(); r != test.r || c != test.c {
+			t.Errorf("%s: expected %d x %d matrix but received %d x %d", test.desc, test.r, test.c, r, c)
+			continue
+		}
+		if coo.NNZ() != test.nnz {
+			t.Errorf("%s: expected %d stored elements but received %d", test.desc, test.nnz, coo.NNZ())
+		}
+		e := mat.NewDense(test.r, test.c, test.data)
+		if !mat.Equal(e, coo) {
+			t.Errorf("%s: expected\n%v\nbut received\n%v\n", test.desc, mat.Formatted(e), mat.Formatted(coo))
+		}
+		if csr := coo.ToCSR(); !mat.Equal(e, csr) {
+			t.Errorf("%s: expected CSR\n%v\nbut received\n%v\n", test.desc, mat.Formatted(e), mat.Formatted(csr))
+		}
+	}
+}
+
+func TestReadMatrixMarketErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty":            "",
+		"missing header":   "3 3 1\n1 1 1\n",
+		"complex":          "%%MatrixMarket matrix coordinate complex general\n1 1 1\n1 1 1 0\n",
+		"hermitian":        "%%MatrixMarket matrix coordinate real hermitian\n1 1 1\n1 1 1\n",
+		"array pattern":    "%%MatrixMarket matrix array pattern general\n1 1\n",
+		"non-square":       "%%MatrixMarket matrix coordinate real symmetric\n2 3 1\n1 1 1\n",
+		"missing size":     "%%MatrixMarket matrix coordinate real general\n% only comments\n",
+		"invalid size":     "%%MatrixMarket matrix coordinate real general\n2 x 1\n1 1 1\n",
+		"missing entries":  "%%MatrixMarket matrix coordinate real general\n2 2 3\n1 1 1\n2 2 1\n",
+		"out of range":     "%%MatrixMarket matrix coordinate real general\n2 2 1\n3 1 1\n",
+		"zero index":       "%%MatrixMarket matrix coordinate real general\n2 2 1\n0 1 1\n",
+		"missing value":    "%%MatrixMarket matrix coordinate real general\n2 2 1\n1 1\n",
+		"invalid value":    "%%MatrixMarket matrix coordinate real general\n2 2 1\n1 1 abc\n",
+		"skew diagonal":    "%%MatrixMarket matrix coordinate real skew-symmetric\n2 2 1\n1 1 1\n",
+		"array short":      "%%MatrixMarket matrix array real general\n2 2\n1\n2\n3\n",
+		"array two values": "%%MatrixMarket matrix array real general\n1 2\n1 2\n",
+		"huge nnz":         "%%MatrixMarket matrix coordinate real general\n1 1 9223372036854775807\n1 1 1\n",
+		"huge symmetric":   "%%MatrixMarket matrix coordinate real symmetric\n1 1 5000000000000000000\n1 1 1\n",
+		"large nnz":        "%%MatrixMarket matrix coordinate real general\n100000 100000 2000000000\n1 1 1\n",
+		"array overflow":   "%%MatrixMarket matrix array real general\n4294967296 4294967296\n1\n",
+		"array too large":  "%%MatrixMarket matrix array real symmetric\n9223372036854775807 9223372036854775807\n1\n",
+	}
+
+	for desc, input := range tests {
+		if _, err := ReadMatrixMarket(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected error but received nil", desc)
+		}
+	}
+}
+
+func TestMatrixMarketRoundTrip(t *testing.T) {
+	r, c := 30, 20
+	data := randomData(r, c, 0.1)
+	data[0] = 1.0 / 3.0
+
+	tests := []struct {
+		desc string
+		m    Sparser
+	}{
+		{desc: "CSR", m: CreateCSR(r, c, data).(Sparser)},
+		{desc: "CSC", m: CreateCSC(r, c, data).(Sparser)},
+		{desc: "DOK", m: CreateDOK(r, c, data).(Sparser)},
+		{desc: "COO with duplicates", m: CreateCOOWithDupes(r, c, data).(Sparser)},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteMatrixMarket(&buf, test.m); err != nil {
+			t.Fatalf("%s: unexpected error writing: %v", test.desc, err)
+		}
+		if !strings.HasPrefix(buf.String(), "%%MatrixMarket matrix coordinate real general\n") {
+			t.Errorf("%s: unexpected header in %q", test.desc, buf.String()[:50])
+		}
+		coo, err := ReadMatrixMarket(&buf)
+		if err != nil {
+			t.Fatalf("%s: unexpected error reading: %v", test.desc, err)
+		}
+		if coo.NNZ() != test.m.NNZ() {
+			t.Errorf("%s: expected %d stored elements but received %d", test.desc, test.m.NNZ(), coo.NNZ())
+		}
+		if !mat.Equal(test.m, coo) {
+			t.Errorf("%s: expected\n%v\nbut received\n%v\n", test.desc, mat.Formatted(test.m), mat.Formatted(coo))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package sparse
+
+import (
+	"context"
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+// MINRES solves the system of linear equations A * x = b for x using the Minimum
+// Residual method (Paige and Saunders 1975) where A is a symmetric (but possibly
+// indefinite) matrix (or operator) e.g. a saddle point system.  MINRES minimises
+// the norm of the residual over the Krylov subspace so, unlike CG, does not require
+// A to be positive definite.  If a preconditioner is specified in the settings it
+// must be symmetric positive definite in which case the residual is measured (and
+// the tolerance applied) in the M^-1 norm i.e.
+// ||b - A*x||_M^-1 <= Tolerance * ||b||_M^-1.  settings may be nil in which case the
+// default settings are used.
+//
+// The result is returned along with a nil error if the solver converged to the
+// required tolerance.  Otherwise the result holds the best solution found, its
+// Status records the outcome and the error is a *NotConvergedError if the maximum
+// number of iterations was reached, a *BreakdownError if the preconditioner is not
+// positive definite (or the Lanczos process terminated without converging e.g.
+// because A is singular and the system inconsistent) or the error from ctx if the
+// context was cancelled.
+func MINRES(ctx context.Context, a MulVecToer, b []float64, settings *SolverSettings) (*SolverResult, error) {
+	n := len(b)
+	r1 := make([]float64, n)
+	state := newIterativeState(ctx, a, b, settings, r1)
+	x := state.result.X
+
+	y := make([]float64, n)
+	state.precondition(y, b)
+	bNorm := math.Sqrt(floats.Dot(b, y))
+	state.precondition(y, r1)
+	beta1 := floats.Dot(r1, y)
+	if beta1 < 0 || math.IsNaN(bNorm) {
+		return state.result, state.breakdown(0, "preconditioner is not positive definite (r^T * M^-1 * r < 0)")
+	}
+	beta1 = math.Sqrt(beta1)
+
+	// scaled returns the residual norm (in the M^-1 norm) scaled so that it is
+	// relative to ||b||_M^-1
+	scaled := func(rNorm float64) float64 {
+		if bNorm == 0 {
+			return rNorm
+		}
+		return rNorm * state.bNorm / bNorm
+	}
+	if state.converged(0, scaled(beta1)) {
+		return state.result, nil
+	}
+
+	r2 := make([]float64, n)
+	copy(r2, r1)
+	v := make([]float64, n)
+	w := make([]float64, n)
+	w1 := make([]float64, n)
+	w2 := make([]float64, n)
+
+	var oldb, dbar, epsln float64
+	beta, phibar := beta1, beta1
+	cs, sn := -1.0, 0.0
+
+	for k := 1; ; k++ {
+		if err := state.next(k); err != nil {
+			return state.result, err
+		}
+
+		// Lanczos step: beta_k+1 * v_k+1 = A * v_k - alpha_k * v_k - beta_k * v_k-1
+		// (where v = M^-1 * r)
+		floats.ScaleTo(v, 1/beta, y)
+		zero(y)
+		a.MulVecTo(y, false, v)
+		if k > 1 {
+			floats.AddScaled(y, -beta/oldb, r1)
+		}
+		alpha := floats.Dot(v, y)
+		floats.AddScaled(y, -alpha/beta, r2)
+		r1, r2 = r2, r1
+		copy(r2, y)
+		state.precondition(y, r2)
+		oldb = beta
+		beta = floats.Dot(r2, y)
+		if beta < 0 {
+			return state.result, state.breakdown(k, "preconditioner is not positive definite (r^T * M^-1 * r < 0)")
+		}
+		beta = math.Sqrt(beta)
+
+		// apply the previous rotation and construct the next rotation to eliminate
+		// the subdiagonal element of the tridiagonal matrix
+		oldeps := epsln
+		delta := cs*dbar + sn*alpha
+		gbar := sn*dbar - cs*alpha
+		epsln = sn * beta
+		dbar = -cs * beta
+		gamma := math.Max(math.Hypot(gbar, beta), epsilon)
+		cs, sn = gbar/gamma, beta/gamma
+		phi := cs * phibar
+		phibar = sn * phibar
+
+		// update the search direction w and x
+		w1, w2, w = w2, w, w1
+		floats.AddScaledTo(w, v, -oldeps, w1)
+		floats.AddScaled(w, -delta, w2)
+		floats.Scale(1/gamma, w)
+		floats.AddScaled(x, phi, w)
+
+		if state.converged(k, scaled(phibar)) {
+			return state.result, nil
+		}
+		if beta == 0 {
+			return state.result, state.breakdown(k, "Lanczos process terminated without converging (A may be singular)")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package sparse
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// saddlePoint returns the symmetric indefinite saddle point matrix [A B^T; B 0]
+// where A is the 2D Laplacian of an n x n grid and B is a random m x n^2 matrix.
+func saddlePoint(n, m int) *CSR {
+	a := laplacian2D(n, n)
+	size := n*n + m
+	dok := NewDOK(size, size)
+	a.DoNonZero(func(i, j int, v float64) {
+		dok.Set(i, j, v)
+	})
+	for i := 0; i < m; i++ {
+		// ensure B has full rank
+		dok.Set(n*n+i, i, 1)
+		dok.Set(i, n*n+i, 1)
+		for k := 0; k < 3; k++ {
+			j := rand.Intn(n * n)
+			v := rand.Float64()
+			dok.Set(n*n+i, j, v)
+			dok.Set(j, n*n+i, v)
+		}
+	}
+	return dok.ToCSR()
+}
+
+func TestMINRES(t *testing.T) {
+	shifted := laplacian2D(20, 20)
+	// shift the spectrum of the Laplacian (eigenvalues in (0, 8)) so it is indefinite
+	shifted.DoNonZero(func(i, j int, v float64) {
+		if i == j {
+			shifted.Set(i, j, v-3.3)
+		}
+	})
+
+	tests := []struct {
+		desc string
+		a    *CSR
+	}{
+		{desc: "SPD", a: laplacian2D(20, 20)},
+		{desc: "shifted", a: shifted},
+		{desc: "saddle point", a: saddlePoint(15, 40)},
+	}
+
+	for _, test := range tests {
+		n, _ := test.a.Dims()
+		b := randomData(n, 1, 1)
+
+		result, err := MINRES(context.Background(), test.a, b, &SolverSettings{Tolerance: 1e-10})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.desc, err)
+		}
+		if result.Status != Converged {
+			t.Errorf("%s: expected status Converged but was %v", test.desc, result.Status)
+		}
+		if r := residual(test.a, b, result.X); r > 1e-8 {
+			t.Errorf("%s: expected relative residual <= 1e-8 but was %g", test.desc, r)
+		}
+		if len(result.History) != result.Iterations+1 {
+			t.Errorf("%s: expected %d history entries but received %d", test.desc, result.Iterations+1, len(result.History))
+		}
+		for k := 1; k < len(result.History); k++ {
+			if result.History[k] > result.History[k-1]*(1+1e-12) {
+				t.Errorf("%s: expected monotonically decreasing residuals but %v > %v", test.desc, result.History[k], result.History[k-1])
+				break
+			}
+		}
+
+		// use the absolute values of the diagonal (or 1 for zero diagonal elements)
+		// as a symmetric positive definite preconditioner
+		diag := make([]float64, n)
+		for i := range diag {
+			diag[i] = math.Abs(test.a.At(i, i))
+			if diag[i] == 0 {
+				diag[i] = 1
+			}
+		}
+		settings := &SolverSettings{Tolerance: 1e-10, Preconditioner: diagonalPreconditioner(diag)}
+		result, err = MINRES(context.Background(), test.a, b, settings)
+		if err != nil {
+			t.Fatalf("%s: unexpected error with preconditioner: %v", test.desc, err)
+		}
+		if r := residual(test.a, b, result.X); r > 1e-7 {
+			t.Errorf("%s: expected relative residual <= 1e-7 with preconditioner but was %g", test.desc, r)
+		}
+
+		// restart from the solution
+		result, err = MINRES(context.Background(), test.a, b, &SolverSettings{Tolerance: 1e-8, InitX: result.X})
+		if err != nil || result.Iterations > 1 {
+			t.Errorf("%s: expected convergence from initial guess but took %d iterations: %v", test.desc, result.Iterations, err)
+		}
+	}
+}
+
+func TestMINRESErrors(t *testing.T) {
+	a := saddlePoint(10, 20)
+	n, _ := a.Dims()
+	b := randomData(n, 1, 1)
+
+	negative := make([]float64, n)
+	for i := range negative {
+		negative[i] = -1
+	}
+	result, err := MINRES(context.Background(), a, b, &SolverSettings{Preconditioner: diagonalPreconditioner(negative)})
+	if _, ok := err.(*BreakdownError); !ok || result.Status != Breakdown {
+		t.Errorf("expected *BreakdownError for indefinite preconditioner but received %v (%v)", err, result.Status)
+	}
+
+	result, err = MINRES(context.Background(), a, b, &SolverSettings{MaxIterations: 3})
+	if _, ok := err.(*NotConvergedError); !ok || result.Status != NotConverged || result.Iterations != 3 {
+		t.Errorf("expected *NotConvergedError after 3 iterations but received %v (%v after %d)", err, result.Status, result.Iterations)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result, err = MINRES(ctx, a, b, nil); err != context.Canceled || result.Status != Cancelled {
+		t.Errorf("expected cancellation but received %v (%v)", err, result.Status)
+	}
+}
@@ -0,0 +1,598 @@
+package sparse
+
+import (
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+	_ Ordering = NaturalOrdering{}
+	_ Ordering = AMDOrdering{}
+	_ Ordering = RCMOrdering{}
+	_ Ordering = NestedDissectionOrdering{}
+)
+
+// Ordering is a type that computes a symmetric permutation of a square sparse matrix
+// e.g. to reduce fill-in during factorisation or to reduce the bandwidth of the matrix.
+// Only the sparsity pattern of A + A^T is considered so the numerical values of the
+// matrix are ignored.
+type Ordering interface {
+	// Permutation returns the permutation perm for the square matrix a such that row
+	// (and column) i of the permuted matrix P * A * P^T is row (and column) perm[i] of a.
+	Permutation(a *CSR) []int
+}
+
+// NaturalOrdering is an Ordering that leaves the matrix in its natural order i.e. the
+// identity permutation.
+type NaturalOrdering struct{}
+
+// Permutation returns the identity permutation for a.
+func (NaturalOrdering) Permutation(a *CSR) []int {
+	n := orderingDims(a)
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	return perm
+}
+
+// AMDOrdering is an Ordering using the Approximate Minimum Degree algorithm (Amestoy,
+// Davis & Duff) to compute fill reducing permutations for Cholesky factorisation.
+// At each step the variable with the smallest approximate external degree in the
+// quotient graph is eliminated.
+type AMDOrdering struct{}
+
+// Permutation returns the approximate minimum degree permutation for a.
+func (AMDOrdering) Permutation(a *CSR) []int {
+	n := orderingDims(a)
+	ptr, adj := symmetricGraph(a)
+	return amd(n, ptr, adj)
+}
+
+// RCMOrdering is an Ordering using the Reverse Cuthill-McKee algorithm to compute
+// bandwidth (and profile) reducing permutations.  Each connected component is ordered
+// by breadth first search from a pseudo-peripheral node, visiting neighbours in order
+// of ascending degree, and the resulting order is reversed.
+type RCMOrdering struct{}
+
+// Permutation returns the reverse Cuthill-McKee permutation for a.
+func (RCMOrdering) Permutation(a *CSR) []int {
+	n := orderingDims(a)
+	ptr, adj := symmetricGraph(a)
+	g := newOrderingGraph(n, ptr, adj)
+	perm := make([]int, 0, n)
+
+	nodes := make([]int, n)
+	for i := range nodes {
+		nodes[i] = i
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return g.degree(nodes[i]) < g.degree(nodes[j])
+	})
+
+	visited := make([]bool, n)
+	for _, s := range nodes {
+		if visited[s] {
+			continue
+		}
+		root := g.pseudoPeripheral(s, 0)
+		start := len(perm)
+		perm = append(perm, root)
+		visited[root] = true
+		for q := start; q < len(perm); q++ {
+			v := perm[q]
+			next := len(perm)
+			for _, w := range adj[ptr[v]:ptr[v+1]] {
+				if !visited[w] {
+					visited[w] = true
+					perm = append(perm, w)
+				}
+			}
+			added := perm[next:]
+			sort.SliceStable(added, func(i, j int) bool {
+				return g.degree(added[i]) < g.degree(added[j])
+			})
+		}
+	}
+
+	for i, j := 0, len(perm)-1; i < j; i, j = i+1, j-1 {
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm
+}
+
+// NestedDissectionOrdering is an Ordering using a simple nested dissection algorithm
+// to compute fill reducing permutations.  The graph of the matrix is recursively
+// bisected using vertex separators taken from the middle level of a breadth first
+// level structure rooted at a pseudo-peripheral node.  Separators are ordered after
+// the two parts they separate and parts with no more than LeafSize vertices are
+// ordered using approximate minimum degree.
+type NestedDissectionOrdering struct {
+	// LeafSize is the size of subgraph below which dissection stops.  If LeafSize
+	// is less than or equal to zero a default of 64 is used.
+	LeafSize int
+}
+
+// Permutation returns the nested dissection permutation for a.
+func (o NestedDissectionOrdering) Permutation(a *CSR) []int {
+	n := orderingDims(a)
+	ptr, adj := symmetricGraph(a)
+	g := newOrderingGraph(n, ptr, adj)
+	leaf := o.LeafSize
+	if leaf <= 0 {
+		leaf = 64
+	}
+
+	perm := make([]int, 0, n)
+	loc := make([]int, n)
+	tag := 0
+
+	var dissect func(nodes []int)
+	dissect = func(nodes []int) {
+		if len(nodes) == 0 {
+			return
+		}
+		tag++
+		for _, v := range nodes {
+			g.mask[v] = tag
+		}
+
+		var levels [][]int
+		if len(nodes) > leaf {
+			root := g.pseudoPeripheral(nodes[0], tag)
+			levels = g.levels(root, tag)
+		}
+
+		var reached int
+		for _, l := range levels {
+			reached += len(l)
+		}
+		if reached > 0 && reached < len(nodes) {
+			// disconnected so dissect the component and the remainder separately
+			comp := make([]int, 0, reached)
+			for _, l := range levels {
+				comp = append(comp, l...)
+			}
+			rest := make([]int, 0, len(nodes)-reached)
+			for _, v := range nodes {
+				if g.level[v] < 0 {
+					rest = append(rest, v)
+				}
+			}
+			dissect(comp)
+			dissect(rest)
+			return
+		}
+
+		if len(levels) < 3 {
+			// small (or too densely connected to separate) so order with minimum degree
+			for i, v := range nodes {
+				loc[v] = i
+			}
+			sptr := make([]int, len(nodes)+1)
+			var sadj []int
+			for i, v := range nodes {
+				for _, w := range adj[ptr[v]:ptr[v+1]] {
+					if g.mask[w] == tag {
+						sadj = append(sadj, loc[w])
+					}
+				}
+				sptr[i+1] = len(sadj)
+			}
+			for _, i := range amd(len(nodes), sptr, sadj) {
+				perm = append(perm, nodes[i])
+			}
+			return
+		}
+
+		// split at the level containing the median vertex
+		mid, count := 1, len(levels[0])
+		for mid < len(levels)-2 && count+len(levels[mid]) <= len(nodes)/2 {
+			count += len(levels[mid])
+			mid++
+		}
+
+		var part1, part2, sep []int
+		for _, l := range levels[:mid] {
+			part1 = append(part1, l...)
+		}
+		for _, l := range levels[mid+1:] {
+			part2 = append(part2, l...)
+		}
+		// separator vertices not adjacent to the second part may join the first
+		for _, v := range levels[mid] {
+			adjacent := false
+			for _, w := range adj[ptr[v]:ptr[v+1]] {
+				if g.mask[w] == tag && g.level[w] == mid+1 {
+					adjacent = true
+					break
+				}
+			}
+			if adjacent {
+				sep = append(sep, v)
+			} else {
+				part1 = append(part1, v)
+			}
+		}
+
+		dissect(part1)
+		dissect(part2)
+		perm = append(perm, sep...)
+	}
+
+	nodes := make([]int, n)
+	for i := range nodes {
+		nodes[i] = i
+	}
+	dissect(nodes)
+	return perm
+}
+
+// orderingDims returns the dimension of the square matrix a, panicking if a is
+// not square.
+func orderingDims(a *CSR) int {
+	r, c := a.Dims()
+	if r != c {
+		panic(mat.ErrShape)
+	}
+	return r
+}
+
+// symmetricGraph returns the adjacency structure of the graph of A + A^T excluding
+// self loops.  The neighbours of vertex i are adj[ptr[i]:ptr[i+1]].
+func symmetricGraph(a *CSR) (ptr []int, adj []int) {
+	n := orderingDims(a)
+	m := &a.matrix
+
+	// count the entries of each row of A + A^T (with possible duplicates)
+	count := make([]int, n)
+	for i := 0; i < n; i++ {
+		for _, j := range m.Ind[m.Indptr[i]:m.Indptr[i+1]] {
+			if i != j {
+				count[i]++
+				count[j]++
+			}
+		}
+	}
+	ptr = make([]int, n+1)
+	for i, c := range count {
+		ptr[i+1] = ptr[i] + c
+	}
+	next := make([]int, n)
+	copy(next, ptr[:n])
+	adj = make([]int, ptr[n])
+	for i := 0; i < n; i++ {
+		for _, j := range m.Ind[m.Indptr[i]:m.Indptr[i+1]] {
+			if i != j {
+				adj[next[i]] = j
+				next[i]++
+				adj[next[j]] = i
+				next[j]++
+			}
+		}
+	}
+
+	// remove duplicate entries in place
+	mark := make([]int, n)
+	for i := range mark {
+		mark[i] = -1
+	}
+	var nz int
+	for i := 0; i < n; i++ {
+		start := nz
+		for _, j := range adj[ptr[i]:ptr[i+1]] {
+			if mark[j] != i {
+				mark[j] = i
+				adj[nz] = j
+				nz++
+			}
+		}
+		ptr[i] = start
+	}
+	ptr[n] = nz
+	return ptr, adj[:nz]
+}
+
+// orderingGraph supports breadth first searches over subgraphs of a graph where
+// the vertices of the subgraph are identified by a mask value.
+type orderingGraph struct {
+	ptr, adj []int
+	mask     []int
+	level    []int
+	reached  []int
+}
+
+func newOrderingGraph(n int, ptr, adj []int) *orderingGraph {
+	g := &orderingGraph{
+		ptr:   ptr,
+		adj:   adj,
+		mask:  make([]int, n),
+		level: make([]int, n),
+	}
+	for v := range g.level {
+		g.level[v] = -1
+	}
+	return g
+}
+
+func (g *orderingGraph) degree(v int) int {
+	return g.ptr[v+1] - g.ptr[v]
+}
+
+// levels returns the level structure of a breadth first search from root over the
+// subgraph of vertices with mask value tag.  The level of each vertex reached is
+// recorded in g.level, vertices of the subgraph not reached have level -1.
+func (g *orderingGraph) levels(root int, tag int) [][]int {
+	for _, v := range g.reached {
+		g.level[v] = -1
+	}
+	g.reached = append(g.reached[:0], root)
+	g.level[root] = 0
+
+	var levels [][]int
+	for start := 0; start < len(g.reached); {
+		end := len(g.reached)
+		levels = append(levels, g.reached[start:end:end])
+		for _, v := range g.reached[start:end] {
+			for _, w := range g.adj[g.ptr[v]:g.ptr[v+1]] {
+				if g.mask[w] == tag && g.level[w] < 0 {
+					g.level[w] = len(levels)
+					g.reached = append(g.reached, w)
+				}
+			}
+		}
+		start = end
+	}
+	return levels
+}
+
+// pseudoPeripheral returns a pseudo-peripheral vertex of the connected component
+// of the subgraph (of vertices with mask value tag) containing start using the
+// algorithm of George and Liu.
+func (g *orderingGraph) pseudoPeripheral(start int, tag int) int {
+	root := start
+	levels := g.levels(root, tag)
+	for {
+		last := levels[len(levels)-1]
+		candidate := last[0]
+		for _, v := range last[1:] {
+			if g.degree(v) < g.degree(candidate) {
+				candidate = v
+			}
+		}
+		next := g.levels(candidate, tag)
+		if len(next) <= len(levels) {
+			return root
+		}
+		root, levels = candidate, next
+	}
+}
+
+// amd returns the approximate minimum degree ordering of the graph with n vertices
+// and adjacency structure ptr, adj.  The elimination is simulated using a quotient
+// graph in which eliminated vertices become elements representing the cliques
+// formed in the filled graph.
+func amd(n int, ptr, adj []int) []int {
+	const (
+		variable = iota
+		element
+		absorbed
+	)
+
+	vars := make([][]int, n)    // adjacent variables of each variable
+	elems := make([][]int, n)   // adjacent elements of each variable
+	members := make([][]int, n) // variables adjacent to each element
+	status := make([]int, n)
+	deg := make([]int, n)
+
+	// degree lists
+	head := make([]int, n+1)
+	next := make([]int, n)
+	prev := make([]int, n)
+	for d := range head {
+		head[d] = -1
+	}
+	insert := func(i, d int) {
+		prev[i] = -1
+		next[i] = head[d]
+		if head[d] >= 0 {
+			prev[head[d]] = i
+		}
+		head[d] = i
+	}
+	remove := func(i int) {
+		if prev[i] >= 0 {
+			next[prev[i]] = next[i]
+		} else {
+			head[deg[i]] = next[i]
+		}
+		if next[i] >= 0 {
+			prev[next[i]] = prev[i]
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		vars[i] = append([]int(nil), adj[ptr[i]:ptr[i+1]]...)
+		deg[i] = len(vars[i])
+		insert(i, deg[i])
+	}
+
+	mark := make([]int, n)
+	for i := range mark {
+		mark[i] = -1
+	}
+	w := make([]int, n)
+	wmark := make([]int, n)
+	for i := range wmark {
+		wmark[i] = -1
+	}
+
+	perm := make([]int, 0, n)
+	mindeg := 0
+	for k := 0; k < n; k++ {
+		for head[mindeg] < 0 {
+			mindeg++
+		}
+		p := head[mindeg]
+		remove(p)
+		perm = append(perm, p)
+
+		// form the new element Lp from the variables and elements adjacent to p
+		mark[p] = k
+		var lp []int
+		for _, j := range vars[p] {
+			if status[j] == variable && mark[j] != k {
+				mark[j] = k
+				lp = append(lp, j)
+			}
+		}
+		for _, e := range elems[p] {
+			if status[e] != element {
+				continue
+			}
+			for _, j := range members[e] {
+				if status[j] == variable && mark[j] != k {
+					mark[j] = k
+					lp = append(lp, j)
+				}
+			}
+			status[e] = absorbed
+			members[e] = nil
+		}
+		status[p] = element
+		members[p] = lp
+		vars[p], elems[p] = nil, nil
+
+		// compute |Le \ Lp| for each element e adjacent to a variable in Lp
+		for _, i := range lp {
+			for _, e := range elems[i] {
+				if status[e] != element {
+					continue
+				}
+				if wmark[e] != k {
+					wmark[e] = k
+					w[e] = len(members[e])
+				}
+				w[e]--
+			}
+		}
+
+		// update the variables in Lp with their approximate external degree
+		for _, i := range lp {
+			remove(i)
+
+			nv := vars[i][:0]
+			for _, j := range vars[i] {
+				if status[j] == variable && mark[j] != k {
+					nv = append(nv, j)
+				}
+			}
+			vars[i] = nv
+
+			var external int
+			ne := elems[i][:0]
+			for _, e := range elems[i] {
+				if status[e] != element {
+					continue
+				}
+				if w[e] == 0 {
+					// Le is a subset of Lp so e is absorbed into p
+					status[e] = absorbed
+					members[e] = nil
+					continue
+				}
+				ne = append(ne, e)
+				external += w[e]
+			}
+			elems[i] = append(ne, p)
+
+			d := len(vars[i]) + len(lp) - 1 + external
+			if bound := deg[i] + len(lp) - 1; bound < d {
+				d = bound
+			}
+			if bound := n - k - 2; bound < d {
+				d = bound
+			}
+			if d < 0 {
+				d = 0
+			}
+			deg[i] = d
+			insert(i, d)
+			if d < mindeg {
+				mindeg = d
+			}
+		}
+	}
+	return perm
+}
+
+// etree returns the elimination tree of the Cholesky factor of the matrix with the
+// symmetric graph ptr, adj permuted by perm (nil for natural order) along with the
+// inverse permutation.  The parent of each (permuted) vertex is returned in parent
+// with roots having a parent of -1.
+func etree(n int, ptr, adj []int, perm []int) (parent []int, pinv []int) {
+	pinv = make([]int, n)
+	for i := range pinv {
+		pinv[i] = i
+	}
+	if perm != nil {
+		for i, p := range perm {
+			pinv[p] = i
+		}
+	}
+	parent = make([]int, n)
+	ancestor := make([]int, n)
+	for i := 0; i < n; i++ {
+		parent[i] = -1
+		ancestor[i] = -1
+		v := i
+		if perm != nil {
+			v = perm[i]
+		}
+		for _, w := range adj[ptr[v]:ptr[v+1]] {
+			for r := pinv[w]; r != -1 && r < i; {
+				next := ancestor[r]
+				ancestor[r] = i
+				if next == -1 {
+					parent[r] = i
+				}
+				r = next
+			}
+		}
+	}
+	return parent, pinv
+}
+
+// CholeskyNNZ returns the number of non-zero elements (including the diagonal) in
+// the Cholesky factor L of the permuted matrix P * A * P^T where perm is the permutation
+// returned by an Ordering (or nil for the natural order).  Only the sparsity pattern
+// of A + A^T is considered and numerical cancellation is ignored so CholeskyNNZ may be
+// used to estimate the fill-in resulting from an ordering before factorising.
+func CholeskyNNZ(a *CSR, perm []int) int {
+	n := orderingDims(a)
+	if perm != nil && len(perm) != n {
+		panic(mat.ErrShape)
+	}
+	ptr, adj := symmetricGraph(a)
+	parent, pinv := etree(n, ptr, adj, perm)
+
+	mark := make([]int, n)
+	nnz := n
+	for i := 0; i < n; i++ {
+		mark[i] = i
+		v := i
+		if perm != nil {
+			v = perm[i]
+		}
+		// the pattern of row i of L is the union of the paths in the elimination
+		// tree from each k < i adjacent to i up to i
+		for _, w := range adj[ptr[v]:ptr[v+1]] {
+			for k := pinv[w]; k < i && mark[k] != i; k = parent[k] {
+				mark[k] = i
+				nnz++
+			}
+		}
+	}
+	return nnz
+}
@@ -0,0 +1,243 @@
+package sparse
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/floats/scalar"
+	"gonum.org/v1/gonum/mat"
+)
+
+// laplacian2D returns the 5 point finite difference Laplacian for an nx * ny grid.
+func laplacian2D(nx, ny int) *CSR {
+	n := nx * ny
+	dok := NewDOK(n, n)
+	for x := 0; x < nx; x++ {
+		for y := 0; y < ny; y++ {
+			i := x*ny + y
+			dok.Set(i, i, 4)
+			if x > 0 {
+				dok.Set(i, i-ny, -1)
+			}
+			if x < nx-1 {
+				dok.Set(i, i+ny, -1)
+			}
+			if y > 0 {
+				dok.Set(i, i-1, -1)
+			}
+			if y < ny-1 {
+				dok.Set(i, i+1, -1)
+			}
+		}
+	}
+	return dok.ToCSR()
+}
+
+// laplacian3D returns the 7 point finite difference Laplacian for an nx * ny * nz grid.
+func laplacian3D(nx, ny, nz int) *CSR {
+	n := nx * ny * nz
+	dok := NewDOK(n, n)
+	for x := 0; x < nx; x++ {
+		for y := 0; y < ny; y++ {
+			for z := 0; z < nz; z++ {
+				i := (x*ny+y)*nz + z
+				dok.Set(i, i, 6)
+				if x > 0 {
+					dok.Set(i, i-ny*nz, -1)
+				}
+				if x < nx-1 {
+					dok.Set(i, i+ny*nz, -1)
+				}
+				if y > 0 {
+					dok.Set(i, i-nz, -1)
+				}
+				if y < ny-1 {
+					dok.Set(i, i+nz, -1)
+				}
+				if z > 0 {
+					dok.Set(i, i-1, -1)
+				}
+				if z < nz-1 {
+					dok.Set(i, i+1, -1)
+				}
+			}
+		}
+	}
+	return dok.ToCSR()
+}
+
+// bandwidth returns the maximum distance of a non-zero element from the diagonal
+// of the permuted matrix P * A * P^T.
+func bandwidth(a *CSR, perm []int) int {
+	pinv := make([]int, len(perm))
+	for i, p := range perm {
+		pinv[p] = i
+	}
+	var bw int
+	a.DoNonZero(func(i, j int, v float64) {
+		d := pinv[i] - pinv[j]
+		if d < 0 {
+			d = -d
+		}
+		if d > bw {
+			bw = d
+		}
+	})
+	return bw
+}
+
+func TestOrderingPermutation(t *testing.T) {
+	disconnected := NewDOK(7, 7)
+	for _, e := range [][2]int{{0, 3}, {3, 5}, {1, 6}} {
+		disconnected.Set(e[0], e[1], 1)
+		disconnected.Set(e[1], e[0], 1)
+	}
+	for i := 0; i < 7; i++ {
+		disconnected.Set(i, i, 4)
+	}
+
+	matrices := map[string]*CSR{
+		"grid":         laplacian2D(12, 9),
+		"random":       CreateCSR(40, 40, randomData(40, 40, 0.1)).(*CSR),
+		"disconnected": disconnected.ToCSR(),
+		"diagonal":     CreateCSR(3, 3, []float64{1, 0, 0, 0, 1, 0, 0, 0, 1}).(*CSR),
+		"empty":        NewCSR(0, 0, []int{0}, nil, nil),
+	}
+	orderings := map[string]Ordering{
+		"natural":  NaturalOrdering{},
+		"AMD":      AMDOrdering{},
+		"RCM":      RCMOrdering{},
+		"ND":       NestedDissectionOrdering{},
+		"ND leaf4": NestedDissectionOrdering{LeafSize: 4},
+	}
+
+	for mname, a := range matrices {
+		n, _ := a.Dims()
+		for oname, o := range orderings {
+			perm := o.Permutation(a)
+			if len(perm) != n {
+				t.Errorf("%s (%s): expected permutation of length %d but received %d", mname, oname, n, len(perm))
+				continue
+			}
+			seen := make([]bool, n)
+			for _, p := range perm {
+				if p < 0 || p >= n || seen[p] {
+					t.Errorf("%s (%s): invalid permutation %v", mname, oname, perm)
+					break
+				}
+				seen[p] = true
+			}
+		}
+	}
+}
+
+func TestOrderingFill(t *testing.T) {
+	a := laplacian2D(30, 30)
+	natural := CholeskyNNZ(a, nil)
+	if nnz := CholeskyNNZ(a, NaturalOrdering{}.Permutation(a)); nnz != natural {
+		t.Errorf("expected natural ordering fill of %d but received %d", natural, nnz)
+	}
+
+	for name, o := range map[string]Ordering{
+		"AMD": AMDOrdering{},
+		"ND":  NestedDissectionOrdering{},
+	} {
+		nnz := CholeskyNNZ(a, o.Permutation(a))
+		if nnz >= natural*3/4 {
+			t.Errorf("%s: expected fill to reduce significantly from %d but received %d", name, natural, nnz)
+		}
+	}
+
+	// shuffle the grid and check RCM recovers a narrow band
+	perm := rand.Perm(900)
+	pinv := make([]int, 900)
+	for i, p := range perm {
+		pinv[p] = i
+	}
+	shuffled := symPermute(a, perm, pinv)
+	if bw := bandwidth(shuffled, RCMOrdering{}.Permutation(shuffled)); bw > 40 {
+		t.Errorf("RCM: expected bandwidth of at most 40 but received %d", bw)
+	}
+}
+
+// symPermute returns the permuted matrix P * A * P^T where row (and column) i of
+// the result is row (and column) perm[i] of a and pinv is the inverse of perm.
+// Column indices within each row of the result are sorted.
+func symPermute(a *CSR, perm, pinv []int) *CSR {
+	r, c := a.Dims()
+	indptr := make([]int, r+1)
+	ind := make([]int, a.NNZ())
+	data := make([]float64, a.NNZ())
+	for i, p := range perm {
+		start, end := a.matrix.Indptr[p], a.matrix.Indptr[p+1]
+		nz := indptr[i]
+		for k := start; k < end; k++ {
+			ind[nz] = pinv[a.matrix.Ind[k]]
+			data[nz] = a.matrix.Data[k]
+			nz++
+		}
+		sortSparse(ind[indptr[i]:nz], data[indptr[i]:nz])
+		indptr[i+1] = nz
+	}
+	return NewCSR(r, c, indptr, ind, data)
+}
+
+func TestCholeskyNNZ(t *testing.T) {
+	a := laplacian2D(7, 8)
+	for name, o := range map[string]Ordering{
+		"natural": NaturalOrdering{},
+		"AMD":     AMDOrdering{},
+		"RCM":     RCMOrdering{},
+		"ND":      NestedDissectionOrdering{LeafSize: 8},
+	} {
+		perm := o.Permutation(a)
+		var chol Cholesky
+		chol.Factorize(a, WithOrdering(o))
+
+		if got, want := CholeskyNNZ(a, perm), chol.FactorNNZ(); got != want {
+			t.Errorf("%s: expected %d non-zeros in L but received %d", name, want, got)
+		}
+		if got := chol.Permutation(); fmt.Sprint(got) != fmt.Sprint(perm) {
+			t.Errorf("%s: expected permutation %v but received %v", name, perm, got)
+		}
+	}
+}
+
+func TestCholeskyOrdering(t *testing.T) {
+	a := laplacian2D(6, 5)
+	n, _ := a.Dims()
+	dense := a.ToDense()
+
+	want := mat.NewVecDense(n, nil)
+	for i := 0; i < n; i++ {
+		want.SetVec(i, float64(i+1))
+	}
+	var b mat.VecDense
+	b.MulVec(dense, want)
+
+	for name, o := range map[string]Ordering{
+		"AMD": AMDOrdering{},
+		"RCM": RCMOrdering{},
+		"ND":  NestedDissectionOrdering{LeafSize: 4},
+	} {
+		var chol Cholesky
+		chol.Factorize(a, WithOrdering(o))
+
+		if !mat.EqualApprox(dense, &chol, 1e-12) {
+			t.Errorf("%s: expected factorised matrix\n%v\nbut received\n%v", name, mat.Formatted(dense), mat.Formatted(&chol))
+		}
+
+		got := mat.NewVecDense(n, nil)
+		if err := chol.SolveVecTo(got, &b); err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+		if !mat.EqualApprox(want, got, 1e-10) {
+			t.Errorf("%s: expected solution\n%v\nbut received\n%v", name, mat.Formatted(want.T()), mat.Formatted(got.T()))
+		}
+
+		if det, _ := mat.LogDet(dense); !scalar.EqualWithinAbsOrRel(det, chol.LogDet(), 1e-10, 1e-10) {
+			t.Errorf("%s: expected log determinant %f but received %f", name, det, chol.LogDet())
+		}
+	}
+}
@@ -31,29 +31,37 @@ var (
 
 // MarshalBinary binary serialises the receiver into a []byte and returns the result.
 //
-// DIA is little-endian encoded as follows:
+// DIA matrices storing only the main diagonal are little-endian encoded as follows:
 //   0 -  7  number of rows    (int64)
 //   8 - 15  number of columns (int64)
 // 	16 - 23  number of non zero elements (along the diagonal) (int64)
 //  24 - ..  diagonal matrix data elements (float64)
+//
+// DIA matrices storing diagonals with other offsets are little-endian encoded as follows:
+//   0 -  7  number of rows    (int64)
+//   8 - 15  number of columns (int64)
+// 	16 - 23  negated number of diagonals (int64)
+//  24 - ..  offset and number of elements for each diagonal (int64 pairs)
+//   .. - ..  matrix data elements for each diagonal in turn (float64)
 func (m DIA) MarshalBinary() ([]byte, error) {
-	bufLen := 3*int64(sizeInt64) + int64(len(m.data))*int64(sizeFloat64)
+	header := m.marshalHeader()
+	bufLen := int64(len(header))*int64(sizeInt64) + int64(m.NNZ())*int64(sizeFloat64)
 	if bufLen <= 0 {
 		return nil, errors.New("sparse: buffer for data is too big")
 	}
 
 	p := 0
 	buf := make([]byte, bufLen)
-	binary.LittleEndian.PutUint64(buf[p:p+sizeInt64], uint64(m.m))
-	p += sizeInt64
-	binary.LittleEndian.PutUint64(buf[p:p+sizeInt64], uint64(m.n))
-	p += sizeInt64
-	binary.LittleEndian.PutUint64(buf[p:p+sizeInt64], uint64(m.NNZ()))
-	p += sizeInt64
+	for _, v := range header {
+		binary.LittleEndian.PutUint64(buf[p:p+sizeInt64], uint64(v))
+		p += sizeInt64
+	}
 
-	for i := 0; i < m.NNZ(); i++ {
-		binary.LittleEndian.PutUint64(buf[p:p+sizeFloat64], math.Float64bits(m.data[i]))
-		p += sizeFloat64
+	for _, diagonal := range m.data {
+		for _, v := range diagonal {
+			binary.LittleEndian.PutUint64(buf[p:p+sizeFloat64], math.Float64bits(v))
+			p += sizeFloat64
+		}
 	}
 
 	return buf, nil
@@ -66,37 +74,76 @@ func (m DIA) MarshalBinary() ([]byte, error) {
 func (m DIA) MarshalBinaryTo(w io.Writer) (int, error) {
 	var n int
 	var buf [8]byte
-	binary.LittleEndian.PutUint64(buf[:], uint64(m.m))
-	nn, err := w.Write(buf[:])
-	n += nn
-	if err != nil {
-		return n, err
-	}
-	binary.LittleEndian.PutUint64(buf[:], uint64(m.n))
-	nn, err = w.Write(buf[:])
-	n += nn
-	if err != nil {
-		return n, err
-	}
-	binary.LittleEndian.PutUint64(buf[:], uint64(m.NNZ()))
-	nn, err = w.Write(buf[:])
-	n += nn
-	if err != nil {
-		return n, err
-	}
-
-	for i := 0; i < m.NNZ(); i++ {
-		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(m.data[i]))
-		nn, err = w.Write(buf[:])
+	for _, v := range m.marshalHeader() {
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		nn, err := w.Write(buf[:])
 		n += nn
 		if err != nil {
 			return n, err
 		}
 	}
 
+	for _, diagonal := range m.data {
+		for _, v := range diagonal {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+			nn, err := w.Write(buf[:])
+			n += nn
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+
 	return n, nil
 }
 
+// marshalHeader returns the header fields of the serialised layout for the
+// receiver.  Matrices storing only the main diagonal use the original layout
+// so that they remain readable by earlier versions.
+func (m DIA) marshalHeader() []int64 {
+	if len(m.offsets) == 0 || (len(m.offsets) == 1 && m.offsets[0] == 0) {
+		return []int64{int64(m.m), int64(m.n), int64(m.NNZ())}
+	}
+	header := make([]int64, 3, 3+2*len(m.offsets))
+	header[0], header[1], header[2] = int64(m.m), int64(m.n), -int64(len(m.offsets))
+	for i, k := range m.offsets {
+		header = append(header, int64(k), int64(len(m.data[i])))
+	}
+	return header
+}
+
+// unmarshalOffsets validates the serialised offsets and diagonal lengths and,
+// if valid, initialises the receiver with storage for the diagonals.  The total
+// number of diagonal elements is returned.
+func (m *DIA) unmarshalOffsets(r, c int64, offsets, lens []int64) (int64, error) {
+	if r < 0 || c < 0 || r > maxLen || c > maxLen {
+		return 0, errors.New("sparse: dimensions/data size mismatch")
+	}
+	m.m = int(r)
+	m.n = int(c)
+	m.offsets = make([]int, len(offsets))
+	m.data = make([][]float64, len(offsets))
+
+	var nnz int64
+	for i, k := range offsets {
+		if (i > 0 && k <= offsets[i-1]) || k <= -r || k >= c {
+			return 0, errors.New("sparse: invalid diagonal offset")
+		}
+		m.offsets[i] = int(k)
+		if lens[i] < 0 || lens[i] > int64(m.diagonalLen(int(k))) {
+			return 0, errors.New("sparse: dimensions/data size mismatch")
+		}
+		nnz += lens[i]
+	}
+	if nnz > maxLen {
+		return 0, errors.New("sparse: data is too big")
+	}
+	for i := range m.data {
+		m.data[i] = make([]float64, lens[i])
+	}
+	return nnz, nil
+}
+
 // UnmarshalBinary binary deserialises the []byte into the receiver.
 // It panics if the receiver is a non-zero DIA matrix.
 //
@@ -121,23 +168,49 @@ func (m *DIA) UnmarshalBinary(data []byte) error {
 	nnz := int64(binary.LittleEndian.Uint64(data[p : p+sizeInt64]))
 	p += sizeInt64
 
-	if int(nnz) < 0 || nnz > maxLen {
-		return errors.New("sparse: data is too big")
-	}
-	if r < 0 || c < 0 || r < nnz || c < nnz {
-		return errors.New("sparse: dimensions/data size mismatch")
+	if nnz >= 0 {
+		if int(nnz) < 0 || nnz > maxLen {
+			return errors.New("sparse: data is too big")
+		}
+		if r < 0 || c < 0 || r < nnz || c < nnz {
+			return errors.New("sparse: dimensions/data size mismatch")
+		}
+		if _, err := m.unmarshalOffsets(r, c, []int64{0}, []int64{nnz}); err != nil {
+			return err
+		}
+	} else {
+		if r < 0 || c < 0 || r > maxLen || c > maxLen {
+			return errors.New("sparse: dimensions/data size mismatch")
+		}
+		if nnz == math.MinInt64 {
+			return errors.New("sparse: invalid number of diagonals")
+		}
+		ndiag := -nnz
+		if ndiag > int64(len(data)-p)/int64(2*sizeInt64) {
+			return errors.New("sparse: data is missing required attributes")
+		}
+		offsets := make([]int64, ndiag)
+		lens := make([]int64, ndiag)
+		for i := range offsets {
+			offsets[i] = int64(binary.LittleEndian.Uint64(data[p : p+sizeInt64]))
+			p += sizeInt64
+			lens[i] = int64(binary.LittleEndian.Uint64(data[p : p+sizeInt64]))
+			p += sizeInt64
+		}
+		var err error
+		if nnz, err = m.unmarshalOffsets(r, c, offsets, lens); err != nil {
+			return err
+		}
 	}
-	if len(data) != int(nnz)*sizeFloat64+3*sizeInt64 {
+	if int64(len(data)-p) != nnz*int64(sizeFloat64) {
 		return errors.New("sparse: data/buffer size mismatch")
 	}
 
-	m.m = int(r)
-	m.n = int(c)
-	m.data = make([]float64, nnz)
-
-	for i := range m.data {
-		m.data[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[p : p+sizeFloat64]))
-		p += sizeFloat64
+	for _, diagonal := range m.data {
+		for i := range diagonal {
+			diagonal[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[p : p+sizeFloat64]))
+			p += sizeFloat64
+		}
 	}
 
 	return nil
@@ -179,24 +252,61 @@ func (m *DIA) UnmarshalBinaryFrom(r io.Reader) (int, error) {
 	}
 	nnz := int64(binary.LittleEndian.Uint64(buf[:]))
 
-	if int(nnz) < 0 || nnz > maxLen {
-		return n, errors.New("sparse: data is too big")
-	}
-	if row < 0 || col < 0 || row < nnz || col < nnz {
-		return n, errors.New("sparse: dimensions/data size mismatch")
+	if nnz >= 0 {
+		if int(nnz) < 0 || nnz > maxLen {
+			return n, errors.New("sparse: data is too big")
+		}
+		if row < 0 || col < 0 || row < nnz || col < nnz {
+			return n, errors.New("sparse: dimensions/data size mismatch")
+		}
+		if _, err = m.unmarshalOffsets(row, col, []int64{0}, []int64{nnz}); err != nil {
+			return n, err
+		}
+	} else {
+		if row < 0 || col < 0 || row > maxLen || col > maxLen {
+			return n, errors.New("sparse: dimensions/data size mismatch")
+		}
+		if nnz == math.MinInt64 {
+			return n, errors.New("sparse: invalid number of diagonals")
+		}
+		// a row x col matrix has at most row+col-1 diagonals (written so as
+		// not to overflow)
+		ndiag := -nnz
+		if ndiag-row >= col {
+			return n, errors.New("sparse: dimensions/data size mismatch")
+		}
+		// the offsets are appended as they are read rather than allocated
+		// up front so corrupt input cannot trigger huge allocations
+		var offsets, lens []int64
+		for i := int64(0); i < ndiag; i++ {
+			nn, err = readUntilFull(r, buf[:])
+			n += nn
+			if err != nil {
+				return n, err
+			}
+			offsets = append(offsets, int64(binary.LittleEndian.Uint64(buf[:])))
+
+			nn, err = readUntilFull(r, buf[:])
+			n += nn
+			if err != nil {
+				return n, err
+			}
+			lens = append(lens, int64(binary.LittleEndian.Uint64(buf[:])))
+		}
+		if _, err = m.unmarshalOffsets(row, col, offsets, lens); err != nil {
+			return n, err
+		}
 	}
 
-	m.m = int(row)
-	m.n = int(col)
-	m.data = make([]float64, nnz)
-
-	for i := range m.data {
-		nn, err = readUntilFull(r, buf[:])
-		n += nn
-		if err != nil {
-			return n, err
+	for _, diagonal := range m.data {
+		for i := range diagonal {
+			nn, err = readUntilFull(r, buf[:])
+			n += nn
+			if err != nil {
+				return n, err
+			}
+			diagonal[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))
 		}
-		m.data[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))
 	}
 
 	return n, nil
@@ -2,6 +2,8 @@ package sparse
 
 import (
 	"bytes"
+	"encoding/binary"
+	"math"
 	"testing"
 
 	"gonum.org/v1/gonum/mat"
@@ -116,6 +118,88 @@ func TestDIAUnmarshalFrom(t *testing.T) {
 	}
 }
 
+func TestDIAOffsetsMarshalRoundTrip(t *testing.T) {
+	want := NewDIAOffsets(3, 4, []int{1, -1, 0}, [][]float64{{2, 3, 4}, {5, 6}, {1, 7, 8}})
+	raw := []byte("\x03\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\xFD\xFF\xFF\xFF\xFF\xFF\xFF\xFF" +
+		"\xFF\xFF\xFF\xFF\xFF\xFF\xFF\xFF\x02\x00\x00\x00\x00\x00\x00\x00" +
+		"\x00\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00" +
+		"\x01\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00" +
+		"\x00\x00\x00\x00\x00\x00\x14\x40\x00\x00\x00\x00\x00\x00\x18\x40" +
+		"\x00\x00\x00\x00\x00\x00\xF0\x3F\x00\x00\x00\x00\x00\x00\x1C\x40\x00\x00\x00\x00\x00\x00\x20\x40" +
+		"\x00\x00\x00\x00\x00\x00\x00\x40\x00\x00\x00\x00\x00\x00\x08\x40\x00\x00\x00\x00\x00\x00\x10\x40")
+
+	buf, err := want.MarshalBinary()
+	if err != nil {
+		t.Fatalf("error encoding: %v\n", err)
+	}
+	if !bytes.Equal(buf, raw) {
+		t.Errorf("error encoding: bytes mismatch.\n got=%q\nwant=%q\n", string(buf), string(raw))
+	}
+
+	w := new(bytes.Buffer)
+	n, err := want.MarshalBinaryTo(w)
+	if err != nil {
+		t.Fatalf("error encoding: %v\n", err)
+	}
+	if n != len(raw) || !bytes.Equal(w.Bytes(), raw) {
+		t.Errorf("error encoding: bytes mismatch.\n got=%q\nwant=%q\n", w.String(), string(raw))
+	}
+
+	var v DIA
+	if err := v.UnmarshalBinary(raw); err != nil {
+		t.Fatalf("error decoding: %v\n", err)
+	}
+	if !mat.Equal(&v, want) {
+		t.Errorf("error decoding: values differ.\n got=%v\nwant=%v\n", mat.Formatted(&v), mat.Formatted(want))
+	}
+
+	var u DIA
+	n, err = u.UnmarshalBinaryFrom(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("error decoding: %v\n", err)
+	}
+	if n != len(raw) {
+		t.Errorf("error decoding: lengths differ.\n got=%d\nwant=%d\n", n, len(raw))
+	}
+	if !mat.Equal(&u, want) {
+		t.Errorf("error decoding: values differ.\n got=%v\nwant=%v\n", mat.Formatted(&u), mat.Formatted(want))
+	}
+
+	if err := v.UnmarshalBinary(raw[:len(raw)-1]); err == nil {
+		t.Errorf("expected error decoding truncated data")
+	}
+}
+
+func TestDIAUnmarshalMalformed(t *testing.T) {
+	header := func(fields ...int64) []byte {
+		buf := make([]byte, 8*len(fields))
+		for i, v := range fields {
+			binary.LittleEndian.PutUint64(buf[8*i:], uint64(v))
+		}
+		return buf
+	}
+
+	tests := map[string][]byte{
+		"min int diagonals":          header(3, 3, math.MinInt64),
+		"negative rows":              header(-1, 3, -1, 0, 1, 0),
+		"negative columns":           header(3, -1, -1, 0, 1, 0),
+		"too many diagonals":         header(2, 2, -4, -1, 1, 0, 2, 1, 1, 2, 0),
+		"huge dimensions, truncated": header(math.MaxInt64, math.MaxInt64, -math.MaxInt64, 0, 1),
+		"truncated offsets":          header(3, 3, -2, 0, 1),
+	}
+
+	for desc, raw := range tests {
+		var v DIA
+		if err := v.UnmarshalBinary(raw); err == nil {
+			t.Errorf("%s: expected error from UnmarshalBinary but received nil", desc)
+		}
+		var u DIA
+		if _, err := u.UnmarshalBinaryFrom(bytes.NewReader(raw)); err == nil {
+			t.Errorf("%s: expected error from UnmarshalBinaryFrom but received nil", desc)
+		}
+	}
+}
+
 var (
 	compressedCSR = []struct {
 		want *CSR
@@ -0,0 +1,565 @@
+package sparse
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// QR is a sparse QR factorisation of an m * n matrix A where m >= n such that
+// A * P = Q * R where P is a column permutation matrix, Q is an m * m orthonormal
+// matrix and R is an m * n upper trapezoidal matrix.  The factorisation is computed
+// row by row using Givens rotations (George-Heath) with the columns ordered by
+// ascending number of non-zero elements to limit fill in R.  Q is not formed
+// explicitly but is stored implicitly as the sequence of rotations applied.
+// QR shadows the gonum mat.QR type and is suitable for solving sparse least
+// squares problems.
+type QR struct {
+	m, n int
+
+	// r holds the n * n upper triangular factor in CSR format with columns
+	// in permuted order.  Rows of r for which no pivot was found are empty.
+	r *CSR
+
+	// perm[j] is the column of A stored as column j of A * P.
+	perm []int
+
+	// rows is the order in which rows of A were processed, rot holds the
+	// rotations applied to each processed row (indexed by rotPtr) and slot
+	// holds the position of each processed row within Q^T * A.
+	rows   []int
+	rotPtr []int
+	rot    []givens
+	slot   []int
+
+	cond float64
+}
+
+// givens is a plane rotation applied between row k of R and the row being
+// processed.
+type givens struct {
+	k    int
+	c, s float64
+}
+
+// apply applies the rotation to the pair (rk, x) returning the rotated values.
+func (g givens) apply(rk, x float64) (float64, float64) {
+	return g.c*rk + g.s*x, -g.s*rk + g.c*x
+}
+
+// undo applies the inverse of the rotation to the pair (rk, x) returning the
+// resulting values.
+func (g givens) undo(rk, x float64) (float64, float64) {
+	return g.c*rk - g.s*x, g.s*rk + g.c*x
+}
+
+// Factorize computes the QR factorisation of the m * n matrix a where m >= n.
+// The QR factorisation always exists even if a is rank deficient.  CSR matrices
+// are factorised directly, other matrix types are converted to CSR first.
+// Factorize panics if m < n.
+func (qr *QR) Factorize(a mat.Matrix) {
+	m, n := a.Dims()
+	if m < n {
+		panic(mat.ErrShape)
+	}
+	var csr *CSR
+	switch t := a.(type) {
+	case *CSR:
+		csr = t
+	case TypeConverter:
+		csr = t.ToCSR()
+	default:
+		coo := NewCOO(m, n, nil, nil, nil)
+		for i := 0; i < m; i++ {
+			for j := 0; j < n; j++ {
+				if v := a.At(i, j); v != 0 {
+					coo.Set(i, j, v)
+				}
+			}
+		}
+		csr = coo.ToCSR()
+	}
+	qr.m, qr.n = m, n
+
+	// order columns by ascending count
+	counts := make([]int, n)
+	for _, j := range csr.matrix.Ind {
+		counts[j]++
+	}
+	qr.perm = make([]int, n)
+	for j := range qr.perm {
+		qr.perm[j] = j
+	}
+	sort.SliceStable(qr.perm, func(i, j int) bool {
+		return counts[qr.perm[i]] < counts[qr.perm[j]]
+	})
+	pos := make([]int, n)
+	for j, p := range qr.perm {
+		pos[p] = j
+	}
+
+	// order rows by their leading column in the permuted matrix
+	leading := make([]int, m)
+	for i := 0; i < m; i++ {
+		leading[i] = n
+		for k := csr.matrix.Indptr[i]; k < csr.matrix.Indptr[i+1]; k++ {
+			if csr.matrix.Data[k] != 0 && pos[csr.matrix.Ind[k]] < leading[i] {
+				leading[i] = pos[csr.matrix.Ind[k]]
+			}
+		}
+	}
+	qr.rows = make([]int, m)
+	for i := range qr.rows {
+		qr.rows[i] = i
+	}
+	sort.SliceStable(qr.rows, func(i, j int) bool {
+		return leading[qr.rows[i]] < leading[qr.rows[j]]
+	})
+
+	rInd := make([][]int, n)
+	rVal := make([][]float64, n)
+	qr.rotPtr = make([]int, m+1)
+	qr.rot = qr.rot[:0]
+	qr.slot = make([]int, m)
+	filled := make([]bool, n)
+
+	var xInd, tInd []int
+	var xVal, tVal []float64
+	for ri, i := range qr.rows {
+		xInd, xVal = xInd[:0], xVal[:0]
+		for k := csr.matrix.Indptr[i]; k < csr.matrix.Indptr[i+1]; k++ {
+			if csr.matrix.Data[k] != 0 {
+				xInd = append(xInd, pos[csr.matrix.Ind[k]])
+				xVal = append(xVal, csr.matrix.Data[k])
+			}
+		}
+		sortSparse(xInd, xVal)
+
+		qr.slot[ri] = -1
+		for len(xInd) > 0 {
+			k := xInd[0]
+			if !filled[k] {
+				rInd[k] = append([]int(nil), xInd...)
+				rVal[k] = append([]float64(nil), xVal...)
+				filled[k] = true
+				qr.slot[ri] = k
+				break
+			}
+			r := math.Hypot(rVal[k][0], xVal[0])
+			g := givens{k: k, c: rVal[k][0] / r, s: xVal[0] / r}
+			qr.rot = append(qr.rot, g)
+
+			// merge the rotated rows, dropping the annihilated leading element of x
+			tInd, tVal = tInd[:0], tVal[:0]
+			var nInd []int
+			var nVal []float64
+			p, q := 0, 0
+			for p < len(rInd[k]) || q < len(xInd) {
+				var j int
+				var rv, xv float64
+				switch {
+				case q >= len(xInd) || (p < len(rInd[k]) && rInd[k][p] < xInd[q]):
+					j, rv = rInd[k][p], rVal[k][p]
+					p++
+				case p >= len(rInd[k]) || xInd[q] < rInd[k][p]:
+					j, xv = xInd[q], xVal[q]
+					q++
+				default:
+					j, rv, xv = xInd[q], rVal[k][p], xVal[q]
+					p++
+					q++
+				}
+				rv, xv = g.apply(rv, xv)
+				if j == k {
+					nInd = append(nInd, j)
+					nVal = append(nVal, r)
+					continue
+				}
+				if rv != 0 {
+					nInd = append(nInd, j)
+					nVal = append(nVal, rv)
+				}
+				if xv != 0 {
+					tInd = append(tInd, j)
+					tVal = append(tVal, xv)
+				}
+			}
+			rInd[k], rVal[k] = nInd, nVal
+			xInd, tInd = tInd, xInd
+			xVal, tVal = tVal, xVal
+		}
+		qr.rotPtr[ri+1] = len(qr.rot)
+	}
+
+	// rows annihilated completely occupy the empty rows of R followed by rows n to m
+	free := make([]int, 0, m)
+	for k := 0; k < n; k++ {
+		if !filled[k] {
+			free = append(free, k)
+		}
+	}
+	for k := n; k < m; k++ {
+		free = append(free, k)
+	}
+	for ri := range qr.slot {
+		if qr.slot[ri] < 0 {
+			qr.slot[ri] = free[0]
+			free = free[1:]
+		}
+	}
+
+	indptr := make([]int, n+1)
+	var ind []int
+	var data []float64
+	for k := 0; k < n; k++ {
+		ind = append(ind, rInd[k]...)
+		data = append(data, rVal[k]...)
+		indptr[k+1] = len(ind)
+	}
+	qr.r = NewCSR(n, n, indptr, ind, data)
+	qr.updateCond()
+}
+
+// sortSparse sorts the sparse vector elements into ascending index order.
+func sortSparse(ind []int, data []float64) {
+	sort.Sort(sparseElements{ind: ind, data: data})
+}
+
+type sparseElements struct {
+	ind  []int
+	data []float64
+}
+
+func (s sparseElements) Len() int           { return len(s.ind) }
+func (s sparseElements) Less(i, j int) bool { return s.ind[i] < s.ind[j] }
+func (s sparseElements) Swap(i, j int) {
+	s.ind[i], s.ind[j] = s.ind[j], s.ind[i]
+	s.data[i], s.data[j] = s.data[j], s.data[i]
+}
+
+// singular returns true if R has a zero diagonal element.
+func (qr *QR) singular() bool {
+	for k := 0; k < qr.n; k++ {
+		if qr.r.matrix.Indptr[k] == qr.r.matrix.Indptr[k+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// updateCond estimates the condition number of R (and so A) in the infinity
+// norm, consistent with mat.QR.
+func (qr *QR) updateCond() {
+	if qr.singular() {
+		qr.cond = math.Inf(1)
+		return
+	}
+	var rNorm float64
+	for k := 0; k < qr.n; k++ {
+		var sum float64
+		for _, v := range qr.r.matrix.Data[qr.r.matrix.Indptr[k]:qr.r.matrix.Indptr[k+1]] {
+			sum += math.Abs(v)
+		}
+		rNorm = math.Max(rNorm, sum)
+	}
+	// ||R^-1||_inf = ||R^-T||_1
+	qr.cond = rNorm * invNorm1Est(qr.n, func(x []float64, trans bool) {
+		qr.rSolve(x, !trans)
+	})
+}
+
+// rSolve solves R * x = b (or R^T * x = b if trans is true) in place.
+func (qr *QR) rSolve(x []float64, trans bool) {
+	r := &qr.r.matrix
+	if !trans {
+		for k := qr.n - 1; k >= 0; k-- {
+			v := x[k]
+			for p := r.Indptr[k] + 1; p < r.Indptr[k+1]; p++ {
+				v -= r.Data[p] * x[r.Ind[p]]
+			}
+			x[k] = v / r.Data[r.Indptr[k]]
+		}
+		return
+	}
+	for k := 0; k < qr.n; k++ {
+		x[k] /= r.Data[r.Indptr[k]]
+		for p := r.Indptr[k] + 1; p < r.Indptr[k+1]; p++ {
+			x[r.Ind[p]] -= r.Data[p] * x[k]
+		}
+	}
+}
+
+// invNorm1Est estimates the 1-norm of the inverse of an n * n matrix using
+// Hager's method as refined by Higham.  solve must overwrite x with the solution
+// of A * y = x (or A^T * y = x if trans is true).
+func invNorm1Est(n int, solve func(x []float64, trans bool)) float64 {
+	if n == 0 {
+		return 0
+	}
+	x := make([]float64, n)
+	y := make([]float64, n)
+	for i := range x {
+		x[i] = 1 / float64(n)
+	}
+	var est float64
+	prev := -1
+	for iter := 0; iter < 5; iter++ {
+		copy(y, x)
+		solve(y, false)
+		newEst := 0.0
+		for _, v := range y {
+			newEst += math.Abs(v)
+		}
+		if iter > 0 && newEst <= est {
+			break
+		}
+		est = newEst
+
+		for i, v := range y {
+			y[i] = 1
+			if v < 0 {
+				y[i] = -1
+			}
+		}
+		solve(y, true)
+		j := 0
+		var zx float64
+		for i, v := range y {
+			zx += v * x[i]
+			if math.Abs(v) > math.Abs(y[j]) {
+				j = i
+			}
+		}
+		if math.Abs(y[j]) <= zx || j == prev {
+			break
+		}
+		prev = j
+		for i := range x {
+			x[i] = 0
+		}
+		x[j] = 1
+	}
+
+	// alternative estimate guarding against pathological cases
+	for i := range x {
+		x[i] = 1 + float64(i)/math.Max(1, float64(n-1))
+		if i%2 == 1 {
+			x[i] = -x[i]
+		}
+	}
+	solve(x, false)
+	var alt float64
+	for _, v := range x {
+		alt += math.Abs(v)
+	}
+	return math.Max(est, 2*alt/(3*float64(n)))
+}
+
+// Cond returns the condition number for the factorised matrix.  The condition
+// number is estimated from R and is infinite if the matrix is structurally rank
+// deficient.
+func (qr *QR) Cond() float64 {
+	return qr.cond
+}
+
+// ColumnPivots returns the column permutation that represents the permutation
+// matrix P from the factorisation such that column j of A * P is column dst[j]
+// of A.  If dst is nil, a new slice is allocated and returned.  If dst is not nil,
+// it must have length equal to the number of columns of the factorised matrix.
+func (qr *QR) ColumnPivots(dst []int) []int {
+	if dst == nil {
+		dst = make([]int, qr.n)
+	}
+	if len(dst) != qr.n {
+		panic(mat.ErrShape)
+	}
+	copy(dst, qr.perm)
+	return dst
+}
+
+// RTo extracts the n * n upper triangular matrix R from the factorisation and
+// stores it in dst.  The columns of R correspond to the columns of A * P.  RTo
+// panics if dst is not n * n.
+func (qr *QR) RTo(dst *CSR) {
+	r, c := dst.Dims()
+	if r != qr.n || c != qr.n {
+		panic(mat.ErrShape)
+	}
+	indptr := make([]int, qr.n+1)
+	ind := make([]int, len(qr.r.matrix.Ind))
+	data := make([]float64, len(qr.r.matrix.Data))
+	copy(indptr, qr.r.matrix.Indptr)
+	copy(ind, qr.r.matrix.Ind)
+	copy(data, qr.r.matrix.Data)
+	dst.matrix.Indptr, dst.matrix.Ind, dst.matrix.Data = indptr, ind, data
+}
+
+// QTo extracts the m * m orthonormal matrix Q from the factorisation and stores it
+// in dst.  If dst is empty, QTo will resize dst to be m * m.  When dst is non-empty,
+// QTo will panic if dst is not m * m.  As Q is generally dense, QMulVecTo should be
+// preferred for applying Q to vectors.
+func (qr *QR) QTo(dst *mat.Dense) {
+	if dst.IsEmpty() {
+		dst.ReuseAs(qr.m, qr.m)
+	} else if r, c := dst.Dims(); r != qr.m || c != qr.m {
+		panic(mat.ErrShape)
+	}
+	x := make([]float64, qr.m)
+	for j := 0; j < qr.m; j++ {
+		for i := range x {
+			x[i] = 0
+		}
+		x[j] = 1
+		qr.qMul(x, false)
+		dst.SetCol(j, x)
+	}
+}
+
+// QMulVecTo computes Q * b (or Q^T * b if trans is true) using the implicit
+// representation of Q and stores the result in dst.  If dst is empty, it is
+// resized to length m.  QMulVecTo panics if b or a non-empty dst are not of
+// length m.
+func (qr *QR) QMulVecTo(dst *mat.VecDense, trans bool, b mat.Vector) {
+	if b.Len() != qr.m {
+		panic(mat.ErrShape)
+	}
+	if dst.IsEmpty() {
+		dst.ReuseAsVec(qr.m)
+	} else if dst.Len() != qr.m {
+		panic(mat.ErrShape)
+	}
+	x := make([]float64, qr.m)
+	for i := range x {
+		x[i] = b.AtVec(i)
+	}
+	qr.qMul(x, trans)
+	for i, v := range x {
+		dst.SetVec(i, v)
+	}
+}
+
+// qMul overwrites x with Q * x (or Q^T * x if trans is true).
+func (qr *QR) qMul(x []float64, trans bool) {
+	y := getFloats(qr.m, true)
+	defer putFloats(y)
+
+	if trans {
+		for ri, i := range qr.rows {
+			t := x[i]
+			for _, g := range qr.rot[qr.rotPtr[ri]:qr.rotPtr[ri+1]] {
+				y[g.k], t = g.apply(y[g.k], t)
+			}
+			y[qr.slot[ri]] = t
+		}
+		copy(x, y)
+		return
+	}
+
+	copy(y, x)
+	for ri := len(qr.rows) - 1; ri >= 0; ri-- {
+		t := y[qr.slot[ri]]
+		y[qr.slot[ri]] = 0
+		rots := qr.rot[qr.rotPtr[ri]:qr.rotPtr[ri+1]]
+		for r := len(rots) - 1; r >= 0; r-- {
+			g := rots[r]
+			y[g.k], t = g.undo(y[g.k], t)
+		}
+		x[qr.rows[ri]] = t
+	}
+}
+
+// SolveVecTo finds a minimum-norm solution to a system of linear equations,
+//  A * x = b.
+// See QR.SolveTo for the full documentation.
+func (qr *QR) SolveVecTo(dst *mat.VecDense, trans bool, b mat.Vector) error {
+	r, c := qr.m, qr.n
+	if trans {
+		r, c = c, r
+	}
+	if b.Len() != r {
+		panic(mat.ErrShape)
+	}
+	if dst.IsEmpty() {
+		dst.ReuseAsVec(c)
+	} else if dst.Len() != c {
+		panic(mat.ErrShape)
+	}
+	if qr.singular() {
+		return mat.Condition(math.Inf(1))
+	}
+
+	if !trans {
+		// x = P * R^-1 * (Q^T * b)[:n]
+		x := make([]float64, qr.m)
+		for i := range x {
+			x[i] = b.AtVec(i)
+		}
+		qr.qMul(x, true)
+		qr.rSolve(x[:qr.n], false)
+		for j, p := range qr.perm {
+			dst.SetVec(p, x[j])
+		}
+	} else {
+		// x = Q * [R^-T * P^T * b; 0]
+		x := make([]float64, qr.m)
+		for j, p := range qr.perm {
+			x[j] = b.AtVec(p)
+		}
+		qr.rSolve(x[:qr.n], true)
+		qr.qMul(x, false)
+		for i, v := range x {
+			dst.SetVec(i, v)
+		}
+	}
+
+	if qr.cond > mat.ConditionTolerance {
+		return mat.Condition(qr.cond)
+	}
+	return nil
+}
+
+// SolveTo finds a minimum-norm solution to a system of linear equations defined
+// by the matrices A and b, where A is an m * n matrix represented in its QR factorised
+// form.  If A is singular or near-singular a Condition error is returned.
+// See the documentation for mat.Condition for more information.
+//
+// The minimization problem solved depends on the input parameters.
+//  If trans == false, find X such that ||A*X - B||_2 is minimized.
+//  If trans == true, find the minimum norm solution of A^T * X = B.
+// The solution matrix, X, is stored in place into dst.  If dst is empty it is
+// resized to the correct size, otherwise SolveTo panics if dst is not the
+// correct size.
+func (qr *QR) SolveTo(dst *mat.Dense, trans bool, b mat.Matrix) error {
+	r, c := qr.m, qr.n
+	if trans {
+		r, c = c, r
+	}
+	br, bc := b.Dims()
+	if br != r {
+		panic(mat.ErrShape)
+	}
+	if dst.IsEmpty() {
+		dst.ReuseAs(c, bc)
+	} else if dr, dc := dst.Dims(); dr != c || dc != bc {
+		panic(mat.ErrShape)
+	}
+
+	var err error
+	bv, bHasColView := b.(mat.ColViewer)
+	for j := 0; j < bc; j++ {
+		dstView := dst.ColView(j).(*mat.VecDense)
+		var cv mat.Vector
+		if bHasColView {
+			cv = bv.ColView(j)
+		} else {
+			cv = mat.NewVecDense(br, mat.Col(nil, j, b))
+		}
+		if err = qr.SolveVecTo(dstView, trans, cv); err != nil {
+			if _, ok := err.(mat.Condition); !ok || qr.singular() {
+				return err
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package sparse
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestQRFactorize(t *testing.T) {
+	var tests = []struct {
+		m, n int
+		data []float64
+	}{
+		{
+			m: 3, n: 3,
+			data: []float64{
+				0, 2, 1,
+				3, 0, 0,
+				1, 1, 4,
+			},
+		},
+		{
+			m: 5, n: 3,
+			data: []float64{
+				1, 0, 0,
+				0, 2, 0,
+				1, 0, 3,
+				0, 4, 0,
+				5, 0, 6,
+			},
+		},
+		{m: 30, n: 10, data: randomData(30, 10, 0.4)},
+		{m: 80, n: 40, data: randomUnsymmetric(80, 0.05)[:80*40]},
+	}
+
+	for ti, test := range tests {
+		a := mat.NewDense(test.m, test.n, test.data)
+
+		for name, m := range map[string]mat.Matrix{
+			"CSR":   CreateCSR(test.m, test.n, test.data),
+			"CSC":   CreateCSC(test.m, test.n, test.data),
+			"Dense": a,
+		} {
+			var qr QR
+			qr.Factorize(m)
+
+			// check A * P = Q * R
+			r := NewCSR(test.n, test.n, nil, nil, nil)
+			qr.RTo(r)
+			var rr mat.Dense
+			rr.Augment(r.ToDense().T(), mat.NewDense(test.n, test.m-test.n+1, nil))
+			rFull := rr.Slice(0, test.n, 0, test.m).T()
+
+			var q mat.Dense
+			qr.QTo(&q)
+			var got mat.Dense
+			got.Mul(&q, rFull)
+
+			pivots := qr.ColumnPivots(nil)
+			want := mat.NewDense(test.m, test.n, nil)
+			for j, p := range pivots {
+				want.SetCol(j, mat.Col(nil, p, a))
+			}
+			if !mat.EqualApprox(want, &got, 1e-10) {
+				t.Errorf("Test %d (%s): expected A * P\n%v\nbut received Q * R\n%v", ti, name, mat.Formatted(want), mat.Formatted(&got))
+			}
+
+			var qtq mat.Dense
+			qtq.Mul(q.T(), &q)
+			eye := mat.NewDiagDense(test.m, nil)
+			for i := 0; i < test.m; i++ {
+				eye.SetDiag(i, 1)
+			}
+			if !mat.EqualApprox(eye, &qtq, 1e-10) {
+				t.Errorf("Test %d (%s): Q is not orthonormal", ti, name)
+			}
+			for i := 0; i < test.n; i++ {
+				for j := 0; j < i; j++ {
+					if r.At(i, j) != 0 {
+						t.Errorf("Test %d (%s): R not upper triangular at (%d, %d)", ti, name, i, j)
+					}
+				}
+			}
+
+			x := mat.NewVecDense(test.m, randomData(test.m, 1, 1))
+			var qx, qtx, wantQx mat.VecDense
+			qr.QMulVecTo(&qx, false, x)
+			qr.QMulVecTo(&qtx, true, x)
+			wantQx.MulVec(&q, x)
+			if !mat.EqualApprox(&wantQx, &qx, 1e-10) {
+				t.Errorf("Test %d (%s): Q * x mismatch", ti, name)
+			}
+			wantQx.MulVec(q.T(), x)
+			if !mat.EqualApprox(&wantQx, &qtx, 1e-10) {
+				t.Errorf("Test %d (%s): Q^T * x mismatch", ti, name)
+			}
+		}
+	}
+}
+
+func TestQRSolve(t *testing.T) {
+	for _, size := range []struct{ m, n int }{
+		{m: 4, n: 4},
+		{m: 10, n: 4},
+		{m: 60, n: 25},
+	} {
+		data := randomData(size.m, size.n, 0.3)
+		for i := 0; i < size.n; i++ {
+			data[i*size.n+i] = 1 + float64(i)
+		}
+		a := mat.NewDense(size.m, size.n, data)
+		var dqr mat.QR
+		dqr.Factorize(a)
+		var qr QR
+		qr.Factorize(CreateCSR(size.m, size.n, data))
+
+		r := NewCSR(size.n, size.n, nil, nil, nil)
+		qr.RTo(r)
+		if cond := mat.Cond(r.ToDense(), math.Inf(1)); math.Abs(cond-qr.Cond()) > 0.5*cond {
+			t.Errorf("%v: expected condition number of approximately %f but received %f", size, cond, qr.Cond())
+		}
+
+		for _, trans := range []bool{false, true} {
+			r := size.m
+			if trans {
+				r = size.n
+			}
+			b := mat.NewDense(r, 2, randomData(r, 2, 1))
+
+			var want, got mat.Dense
+			if err := dqr.SolveTo(&want, trans, b); err != nil {
+				t.Fatalf("%v (trans=%t): unexpected error from mat.QR %v", size, trans, err)
+			}
+			if err := qr.SolveTo(&got, trans, b); err != nil {
+				t.Errorf("%v (trans=%t): unexpected error %v", size, trans, err)
+			}
+			if !mat.EqualApprox(&want, &got, 1e-10) {
+				t.Errorf("%v (trans=%t): expected\n%v\nbut received\n%v", size, trans, mat.Formatted(&want), mat.Formatted(&got))
+			}
+
+			var wantV, gotV mat.VecDense
+			dqr.SolveVecTo(&wantV, trans, b.ColView(1))
+			if err := qr.SolveVecTo(&gotV, trans, b.ColView(1)); err != nil {
+				t.Errorf("%v (trans=%t): unexpected error %v", size, trans, err)
+			}
+			if !mat.EqualApprox(&wantV, &gotV, 1e-10) {
+				t.Errorf("%v (trans=%t): expected\n%v\nbut received\n%v", size, trans, mat.Formatted(&wantV), mat.Formatted(&gotV))
+			}
+		}
+	}
+}
+
+func TestQRRankDeficient(t *testing.T) {
+	data := []float64{
+		1, 2, 0,
+		2, 4, 0,
+		0, 0, 1,
+		3, 6, 0,
+	}
+	var qr QR
+	qr.Factorize(CreateCSR(4, 3, data))
+	if qr.Cond() < mat.ConditionTolerance {
+		t.Errorf("expected condition number above %g but received %f", mat.ConditionTolerance, qr.Cond())
+	}
+	var x mat.VecDense
+	err := qr.SolveVecTo(&x, false, mat.NewVecDense(4, []float64{1, 2, 3, 4}))
+	if _, ok := err.(mat.Condition); !ok {
+		t.Errorf("expected mat.Condition error but received %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package sparse
+
+import (
+	"context"
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+	_ Smoother       = (*Jacobi)(nil)
+	_ Smoother       = (*SOR)(nil)
+	_ Smoother       = (*SSOR)(nil)
+	_ Preconditioner = (*Jacobi)(nil)
+	_ Preconditioner = (*SOR)(nil)
+	_ Preconditioner = (*SSOR)(nil)
+)
+
+// Smoother is a stationary iterative method that may be used to smooth (damp the high
+// frequency components of) the error of an approximate solution e.g. within a
+// multigrid cycle.
+type Smoother interface {
+	// Smooth performs the specified number of iterations (sweeps) of the method to
+	// improve the approximate solution x of A * x = b in place.
+	Smooth(x, b []float64, iterations int)
+}
+
+// diagonal returns the main diagonal of the square matrix a, returning a
+// *SingularError if any diagonal element is zero (or not stored).
+func diagonal(a *CSR) ([]float64, error) {
+	r, c := a.Dims()
+	if r != c {
+		panic(mat.ErrShape)
+	}
+	m := &a.matrix
+	diag := make([]float64, r)
+	stored := make([]bool, r)
+	for i := 0; i < r; i++ {
+		for p := m.Indptr[i]; p < m.Indptr[i+1]; p++ {
+			if m.Ind[p] == i {
+				diag[i] += m.Data[p]
+				stored[i] = true
+			}
+		}
+		if diag[i] == 0 {
+			return nil, &SingularError{Index: i, Structural: !stored[i]}
+		}
+	}
+	return diag, nil
+}
+
+// stationary solves A * x = b by repeatedly applying sweep (a single iteration of a
+// stationary method) until the residual satisfies the settings.
+func stationary(ctx context.Context, a *CSR, b []float64, settings *SolverSettings, sweep func(x, b []float64)) (*SolverResult, error) {
+	r := make([]float64, len(b))
+	state := newIterativeState(ctx, a, b, settings, r)
+	x := state.result.X
+	if state.converged(0, floats.Norm(r, 2)) {
+		return state.result, nil
+	}
+	for k := 1; ; k++ {
+		if err := state.next(k); err != nil {
+			return state.result, err
+		}
+		sweep(x, b)
+		copy(r, b)
+		state.mulVec(r, -1, x, false)
+		rNorm := floats.Norm(r, 2)
+		if state.converged(k, rNorm) {
+			return state.result, nil
+		}
+		if math.IsNaN(rNorm) || math.IsInf(rNorm, 0) {
+			return state.result, state.breakdown(k, "iteration diverged")
+		}
+	}
+}
+
+// Jacobi is the (weighted) Jacobi method for solving A * x = b where each iteration
+// computes x = x + omega * D^-1 * (b - A * x) with D the main diagonal of A.  Jacobi
+// may be used as a standalone solver, as a Smoother or as a Preconditioner (where it
+// is also known as diagonal scaling) in which case it applies omega * D^-1.
+type Jacobi struct {
+	a       *CSR
+	invDiag *DIA
+	omega   float64
+}
+
+// NewJacobi creates a new Jacobi method for the square matrix a with relaxation
+// (damping) weight omega.  omega of 1 is the standard Jacobi method and values less
+// than 1 (e.g. 2/3) are typically used when smoothing.  NewJacobi panics if omega
+// is not positive and returns a *SingularError if a has a zero diagonal element.
+func NewJacobi(a *CSR, omega float64) (*Jacobi, error) {
+	if !(omega > 0) {
+		panic("sparse: relaxation weight must be positive")
+	}
+	diag, err := diagonal(a)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range diag {
+		diag[i] = 1 / v
+	}
+	n := len(diag)
+	return &Jacobi{a: a, invDiag: NewDIA(n, n, diag), omega: omega}, nil
+}
+
+// InverseDiagonal returns the inverse of the main diagonal of A (D^-1) as a DIA
+// matrix.  The returned matrix shares storage with the receiver.
+func (j *Jacobi) InverseDiagonal() *DIA {
+	return j.invDiag
+}
+
+// Apply computes dst = omega * D^-1 * src.
+func (j *Jacobi) Apply(dst, src []float64) {
+	zero(dst)
+	j.invDiag.MulVecTo(dst, false, src)
+	if j.omega != 1 {
+		floats.Scale(j.omega, dst)
+	}
+}
+
+// Smooth performs the specified number of Jacobi iterations updating x in place.
+func (j *Jacobi) Smooth(x, b []float64, iterations int) {
+	n := len(x)
+	r := getFloats(n, false)
+	defer putFloats(r)
+	z := getFloats(n, false)
+	defer putFloats(z)
+	for k := 0; k < iterations; k++ {
+		copy(r, b)
+		floats.Scale(-1, r)
+		j.a.MulVecTo(r, false, x)
+		j.Apply(z, r)
+		floats.Sub(x, z)
+	}
+}
+
+// Solve solves the system of linear equations A * x = b for x using the Jacobi
+// method.  The Preconditioner in settings (which may be nil) is ignored.  See CG
+// for details of the result and errors.  Additionally, a *BreakdownError is
+// returned if the iteration diverges.
+func (j *Jacobi) Solve(ctx context.Context, b []float64, settings *SolverSettings) (*SolverResult, error) {
+	return stationary(ctx, j.a, b, settings, func(x, b []float64) { j.Smooth(x, b, 1) })
+}
+
+// relaxation holds the state common to successive over relaxation methods.
+type relaxation struct {
+	a     *CSR
+	diag  []float64
+	omega float64
+}
+
+func newRelaxation(a *CSR, omega float64) (relaxation, error) {
+	if !(omega > 0 && omega < 2) {
+		panic("sparse: relaxation weight must be in the range (0, 2)")
+	}
+	diag, err := diagonal(a)
+	return relaxation{a: a, diag: diag, omega: omega}, err
+}
+
+// relax updates x[i] in place for the equation of row i.
+func (s *relaxation) relax(i int, x, b []float64) {
+	m := &s.a.matrix
+	sigma := b[i]
+	for p := m.Indptr[i]; p < m.Indptr[i+1]; p++ {
+		if j := m.Ind[p]; j != i {
+			sigma -= m.Data[p] * x[j]
+		}
+	}
+	x[i] += s.omega * (sigma/s.diag[i] - x[i])
+}
+
+// forward performs a forward sweep (in ascending row order) updating x in place.
+func (s *relaxation) forward(x, b []float64) {
+	for i := range x {
+		s.relax(i, x, b)
+	}
+}
+
+// backward performs a backward sweep (in descending row order) updating x in place.
+func (s *relaxation) backward(x, b []float64) {
+	for i := len(x) - 1; i >= 0; i-- {
+		s.relax(i, x, b)
+	}
+}
+
+// SOR is the Successive Over Relaxation method for solving A * x = b where each
+// iteration performs a forward sweep through the rows of A, updating each element
+// of x in turn using the latest values of the other elements.  With a relaxation
+// weight omega of 1, SOR is the Gauss-Seidel method.  SOR may be used as a standalone
+// solver, as a Smoother or as a Preconditioner in which case it applies
+// (D/omega + L)^-1 where D and L are the diagonal and strictly lower triangle of A.
+// As the preconditioner is not symmetric, SOR should not be used with CG.
+type SOR struct {
+	relaxation
+}
+
+// NewSOR creates a new SOR method for the square matrix a with relaxation weight
+// omega.  NewSOR panics if omega is not in the range (0, 2) and returns a
+// *SingularError if a has a zero diagonal element.
+func NewSOR(a *CSR, omega float64) (*SOR, error) {
+	r, err := newRelaxation(a, omega)
+	if err != nil {
+		return nil, err
+	}
+	return &SOR{relaxation: r}, nil
+}
+
+// NewGaussSeidel creates a new Gauss-Seidel method (SOR with a relaxation weight of
+// 1) for the square matrix a.  NewGaussSeidel returns a *SingularError if a has a
+// zero diagonal element.
+func NewGaussSeidel(a *CSR) (*SOR, error) {
+	return NewSOR(a, 1)
+}
+
+// Apply computes dst = (D/omega + L)^-1 * src i.e. performs a single forward sweep
+// from a zero initial guess.
+func (s *SOR) Apply(dst, src []float64) {
+	zero(dst)
+	s.forward(dst, src)
+}
+
+// Smooth performs the specified number of SOR iterations updating x in place.
+func (s *SOR) Smooth(x, b []float64, iterations int) {
+	for k := 0; k < iterations; k++ {
+		s.forward(x, b)
+	}
+}
+
+// Solve solves the system of linear equations A * x = b for x using the SOR method.
+// The Preconditioner in settings (which may be nil) is ignored.  See CG for details
+// of the result and errors.  Additionally, a *BreakdownError is returned if the
+// iteration diverges.
+func (s *SOR) Solve(ctx context.Context, b []float64, settings *SolverSettings) (*SolverResult, error) {
+	return stationary(ctx, s.a, b, settings, s.forward)
+}
+
+// SSOR is the Symmetric Successive Over Relaxation method for solving A * x = b
+// where each iteration performs a forward sweep followed by a backward sweep through
+// the rows of A.  With a relaxation weight omega of 1, SSOR is the symmetric
+// Gauss-Seidel method.  SSOR may be used as a standalone solver, as a Smoother or as
+// a Preconditioner.  For symmetric positive definite A, the SSOR preconditioner is
+// also symmetric positive definite so is suitable for use with CG.
+type SSOR struct {
+	relaxation
+}
+
+// NewSSOR creates a new SSOR method for the square matrix a with relaxation weight
+// omega.  NewSSOR panics if omega is not in the range (0, 2) and returns a
+// *SingularError if a has a zero diagonal element.
+func NewSSOR(a *CSR, omega float64) (*SSOR, error) {
+	r, err := newRelaxation(a, omega)
+	if err != nil {
+		return nil, err
+	}
+	return &SSOR{relaxation: r}, nil
+}
+
+// Apply applies the SSOR preconditioner i.e. performs a single forward and backward
+// sweep from a zero initial guess.
+func (s *SSOR) Apply(dst, src []float64) {
+	zero(dst)
+	s.sweep(dst, src)
+}
+
+// Smooth performs the specified number of SSOR iterations updating x in place.
+func (s *SSOR) Smooth(x, b []float64, iterations int) {
+	for k := 0; k < iterations; k++ {
+		s.sweep(x, b)
+	}
+}
+
+// sweep performs a forward followed by a backward sweep updating x in place.
+func (s *SSOR) sweep(x, b []float64) {
+	s.forward(x, b)
+	s.backward(x, b)
+}
+
+// Solve solves the system of linear equations A * x = b for x using the SSOR method.
+// The Preconditioner in settings (which may be nil) is ignored.  See CG for details
+// of the result and errors.  Additionally, a *BreakdownError is returned if the
+// iteration diverges.
+func (s *SSOR) Solve(ctx context.Context, b []float64, settings *SolverSettings) (*SolverResult, error) {
+	return stationary(ctx, s.a, b, settings, s.sweep)
+}
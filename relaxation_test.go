@@ -0,0 +1,146 @@
+package sparse
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+type stationarySolver interface {
+	Smoother
+	Preconditioner
+	Solve(ctx context.Context, b []float64, settings *SolverSettings) (*SolverResult, error)
+}
+
+func TestStationarySolvers(t *testing.T) {
+	nx := 10
+	a := laplacian2D(nx, nx)
+	n, _ := a.Dims()
+	b := randomData(n, 1, 1)
+
+	// optimal SOR relaxation weight for the 2D Laplacian
+	optimal := 2 / (1 + math.Sin(math.Pi/float64(nx+1)))
+
+	jacobi, err := NewJacobi(a, 1)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	gs, err := NewGaussSeidel(a)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sor, err := NewSOR(a, optimal)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ssor, err := NewSSOR(a, 1.5)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		solver stationarySolver
+	}{
+		{name: "Jacobi", solver: jacobi},
+		{name: "Gauss-Seidel", solver: gs},
+		{name: "SOR", solver: sor},
+		{name: "SSOR", solver: ssor},
+	}
+
+	iterations := make(map[string]int)
+	for _, test := range tests {
+		result, err := test.solver.Solve(context.Background(), b, &SolverSettings{Tolerance: 1e-8, MaxIterations: 5000})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if result.Status != Converged {
+			t.Errorf("%s: expected status Converged but was %v", test.name, result.Status)
+		}
+		if res := residual(a, b, result.X); res > 1e-8 {
+			t.Errorf("%s: expected relative residual <= 1e-8 but was %g", test.name, res)
+		}
+		iterations[test.name] = result.Iterations
+
+		// smoothing should reduce the error of a high frequency initial guess
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = result.X[i] + float64(1-2*(i%2))
+		}
+		before := floats.Distance(x, result.X, 2)
+		test.solver.Smooth(x, b, 3)
+		if after := floats.Distance(x, result.X, 2); after >= 0.5*before {
+			t.Errorf("%s: expected smoothing to reduce high frequency error but reduced from %g to %g", test.name, before, after)
+		}
+
+		// as a preconditioner
+		plain, _ := GMRES(context.Background(), a, b, 20, nil)
+		preconditioned, err := GMRES(context.Background(), a, b, 20, &SolverSettings{Preconditioner: test.solver})
+		if err != nil {
+			t.Errorf("%s: unexpected error from preconditioned GMRES %v", test.name, err)
+		}
+		if test.name != "Jacobi" && preconditioned.Iterations >= plain.Iterations {
+			t.Errorf("%s: expected preconditioning to reduce iterations but took %d (vs %d)", test.name, preconditioned.Iterations, plain.Iterations)
+		}
+	}
+	if !(iterations["SOR"] < iterations["Gauss-Seidel"] && iterations["Gauss-Seidel"] < iterations["Jacobi"]) {
+		t.Errorf("expected SOR to converge faster than Gauss-Seidel faster than Jacobi but took %v", iterations)
+	}
+
+	// SSOR is a symmetric preconditioner so may be used with CG
+	plain, _ := CG(context.Background(), a, b, nil)
+	preconditioned, err := PCG(context.Background(), a, b, ssor, nil)
+	if err != nil {
+		t.Errorf("unexpected error from PCG with SSOR %v", err)
+	}
+	if preconditioned.Iterations >= plain.Iterations {
+		t.Errorf("expected SSOR to reduce CG iterations but took %d (vs %d)", preconditioned.Iterations, plain.Iterations)
+	}
+
+	for i, v := range jacobi.InverseDiagonal().Diagonal() {
+		if v != 0.25 {
+			t.Errorf("expected inverse diagonal element %d to be 0.25 but was %v", i, v)
+		}
+	}
+}
+
+func TestStationarySolverErrors(t *testing.T) {
+	missing := CreateCSR(2, 2, []float64{
+		1, 2,
+		3, 0,
+	}).(*CSR)
+	_, err := NewJacobi(missing, 1)
+	var singular *SingularError
+	if !errors.As(err, &singular) || singular.Index != 1 || !singular.Structural {
+		t.Errorf("expected structurally singular error at index 1 but received %v", err)
+	}
+	_, err = NewSOR(missing, 1)
+	if !errors.As(err, &singular) || singular.Index != 1 {
+		t.Errorf("expected singular error at index 1 but received %v", err)
+	}
+
+	// Jacobi diverges for matrices that are not diagonally dominant
+	a := CreateCSR(2, 2, []float64{
+		1, 2,
+		2, 1,
+	}).(*CSR)
+	jacobi, err := NewJacobi(a, 1)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	result, err := jacobi.Solve(context.Background(), []float64{1, 2}, &SolverSettings{MaxIterations: 5000})
+	var breakdown *BreakdownError
+	if !errors.As(err, &breakdown) || result.Status != Breakdown {
+		t.Errorf("expected *BreakdownError and status Breakdown but received %v and %v", err, result.Status)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic for relaxation weight outside (0, 2)")
+		}
+	}()
+	NewSOR(a, 2)
+}
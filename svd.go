@@ -0,0 +1,327 @@
+package sparse
+
+import (
+	"context"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// SVDSettings holds the settings for TruncatedSVD.  The zero value (or a nil
+// *SVDSettings) uses the default for each setting.
+type SVDSettings struct {
+	// Tolerance is the relative tolerance for convergence of the singular triplets
+	// i.e. a triplet (sigma, u, v) is converged when ||A^T*u - sigma*v|| <=
+	// Tolerance * ||A|| (where ||A|| is estimated by the largest computed singular
+	// value).  If Tolerance is zero, a default of 1e-10 is used.
+	Tolerance float64
+
+	// MaxIterations is the maximum number of restarts.  If MaxIterations is zero, a
+	// default of 300 is used.
+	MaxIterations int
+
+	// SubspaceSize is the size of the Krylov subspaces (the number of left and
+	// right basis vectors held in memory) between restarts.  It must be greater than
+	// the number of singular values requested.  If SubspaceSize is zero, a default
+	// of max(2k+1, 20) (limited to the smallest dimension of the matrix) is used.
+	SubspaceSize int
+
+	// InitVec is the starting (right) vector of length c for an r x c matrix.  If
+	// InitVec is nil, a random starting vector (from a fixed seed so results are
+	// reproducible) is used.
+	InitVec []float64
+}
+
+// defaults returns a copy of the settings (or the default settings if s is nil)
+// with default values filled in for k singular values of an r x c matrix.
+func (s *SVDSettings) defaults(r, c, k int) SVDSettings {
+	var settings SVDSettings
+	if s != nil {
+		settings = *s
+	}
+	n := min(r, c)
+	if settings.Tolerance == 0 {
+		settings.Tolerance = 1e-10
+	}
+	if settings.MaxIterations == 0 {
+		settings.MaxIterations = 300
+	}
+	if settings.SubspaceSize == 0 {
+		settings.SubspaceSize = 2*k + 1
+		if settings.SubspaceSize < 20 {
+			settings.SubspaceSize = 20
+		}
+		if settings.SubspaceSize > n {
+			settings.SubspaceSize = n
+		}
+	}
+	if settings.SubspaceSize <= k || settings.SubspaceSize > n {
+		panic("sparse: subspace size must be greater than k and no greater than min(r, c)")
+	}
+	if settings.InitVec != nil && len(settings.InitVec) != c {
+		panic(mat.ErrShape)
+	}
+	return settings
+}
+
+// TruncatedSVD computes the k largest singular values (and corresponding singular
+// vectors) of the r x c sparse matrix a using Golub-Kahan-Lanczos bidiagonalisation
+// with thick restarts (the augmented implicitly restarted Lanczos bidiagonalisation
+// method of Baglama and Reichel) and full reorthogonalisation.  The truncated
+// decomposition A ~= U * Sigma * V^T is returned where U is an r x k dense matrix of
+// (orthonormal) left singular vectors, Sigma is a k x k diagonal matrix of the
+// singular values in descending order and V^T is a k x c dense matrix of (orthonormal)
+// right singular vectors.  a is only accessed through multiplication by A and A^T (see
+// MulMatVec) so A^T is never formed explicitly.  settings may be nil in which case the
+// default settings are used.  TruncatedSVD panics if k is not in the range
+// [1, min(r, c)).
+//
+// If the singular triplets do not converge within the maximum number of restarts (or
+// the context is cancelled), the current approximations are returned along with a
+// *NotConvergedError (or the error from ctx).
+func TruncatedSVD(ctx context.Context, a BlasCompatibleSparser, k int, settings *SVDSettings) (u *mat.Dense, sigma *mat.DiagDense, vt *mat.Dense, err error) {
+	r, c := a.Dims()
+	if k < 1 || k >= min(r, c) {
+		panic("sparse: number of singular values must be in the range [1, min(r, c))")
+	}
+	s := settings.defaults(r, c, k)
+	m := s.SubspaceSize
+	left := newKrylovBasis(r, m, nil)
+	right := newKrylovBasis(c, m, s.InitVec)
+
+	b := mat.NewDense(m, m, nil)
+	h := make([]float64, m)
+	w := mat.NewVecDense(r, nil)
+	f := mat.NewVecDense(c, nil)
+	var svd mat.SVD
+	var p, q mat.Dense
+	var values []float64
+	var scale float64
+	l := 0
+
+	for iter := 1; ; iter++ {
+		if err := ctx.Err(); err != nil {
+			u, sigma, vt = singularTriplets(left, right, values, &p, &q, k, m)
+			return u, sigma, vt, err
+		}
+
+		// extend the bidiagonalisation A * V_m = U_m * B_m and
+		// A^T * U_m = V_m * B_m^T + beta * v_m * e_m^T
+		var beta float64
+		for j := l; j < m; j++ {
+			w.Zero()
+			MulMatVec(false, 1, a, mat.NewVecDense(c, right.v[j]), w)
+			for i := range h[:j] {
+				h[i] = 0
+			}
+			alpha := left.orthogonalize(w.RawVector().Data, j, h[:j])
+			if alpha <= scale*epsilon*float64(r) {
+				left.random(j)
+				floats.Scale(1/floats.Norm(left.v[j], 2), left.v[j])
+				alpha = 0
+			} else {
+				floats.ScaleTo(left.v[j], 1/alpha, w.RawVector().Data)
+			}
+			for i := 0; i < j; i++ {
+				b.Set(i, j, h[i])
+			}
+			b.Set(j, j, alpha)
+
+			f.Zero()
+			MulMatVec(true, 1, a, mat.NewVecDense(r, left.v[j]), f)
+			beta = right.extend(j, f.RawVector().Data, h[:j+1], scale)
+		}
+
+		if ok := svd.Factorize(b, mat.SVDFull); !ok {
+			panic("sparse: singular value decomposition of bidiagonal matrix failed")
+		}
+		svd.UTo(&p)
+		svd.VTo(&q)
+		values = svd.Values(values)
+		scale = values[0]
+
+		// check convergence of the largest Ritz triplets (A * v = sigma * u holds
+		// exactly so only the residual of A^T * u needs checking)
+		converged := 0
+		residual := 0.0
+		for i := 0; i < k; i++ {
+			res := math.Abs(beta * p.At(m-1, i))
+			residual = math.Max(residual, res/scale)
+			if res <= s.Tolerance*scale {
+				converged++
+			}
+		}
+		if converged == k {
+			u, sigma, vt = singularTriplets(left, right, values, &p, &q, k, m)
+			return u, sigma, vt, nil
+		}
+		if iter == s.MaxIterations {
+			u, sigma, vt = singularTriplets(left, right, values, &p, &q, k, m)
+			return u, sigma, vt, &NotConvergedError{Iterations: iter, Residual: residual}
+		}
+
+		// thick restart keeping the largest Ritz vectors (plus some extra to
+		// accelerate convergence) with B becoming diagonal plus a column coupling
+		// the kept vectors to the residual vector
+		l = k + converged
+		if extra := (m - k) / 2; l < k+extra {
+			l = k + extra
+		}
+		if l > m-1 {
+			l = m - 1
+		}
+		keep := make([]int, l)
+		for i := range keep {
+			keep[i] = i
+		}
+		left.combine(&p, keep, m)
+		right.combine(&q, keep, m)
+		b.Zero()
+		for i := 0; i < l; i++ {
+			b.Set(i, i, values[i])
+		}
+	}
+}
+
+// singularTriplets returns the k largest Ritz triplets from the current
+// decomposition (or nil if no decomposition has been computed).
+func singularTriplets(left, right *krylovBasis, values []float64, p, q *mat.Dense, k, m int) (u *mat.Dense, sigma *mat.DiagDense, vt *mat.Dense) {
+	if p.IsEmpty() {
+		return nil, nil, nil
+	}
+	keep := make([]int, k)
+	for i := range keep {
+		keep[i] = i
+	}
+	u = left.vectors(p, keep, m)
+	v := right.vectors(q, keep, m)
+	return u, mat.NewDiagDense(k, append([]float64(nil), values[:k]...)), mat.DenseCopyOf(v.T())
+}
+
+// RandomizedSVDSettings holds the settings for RandomizedSVD.  The zero value (or a
+// nil *RandomizedSVDSettings) uses the default for each setting.
+type RandomizedSVDSettings struct {
+	// Oversampling is the number of additional random samples taken (beyond the
+	// number of singular values requested) to improve accuracy.  If Oversampling is
+	// zero, a default of 10 is used.  Use a negative value for no oversampling.
+	Oversampling int
+
+	// PowerIterations is the number of power (subspace) iterations performed to
+	// sharpen the decay of the singular values which greatly improves accuracy
+	// when the singular values decay slowly (as is typical for document-term
+	// matrices).  If PowerIterations is zero, a default of 2 is used.  Use a
+	// negative value for no power iterations.
+	PowerIterations int
+
+	// Source is the source of random numbers for the random test matrix.  If Source
+	// is nil, a fixed seed is used so results are reproducible.
+	Source rand.Source
+}
+
+// defaults returns a copy of the settings (or the default settings if s is nil)
+// with default values filled in.
+func (s *RandomizedSVDSettings) defaults() RandomizedSVDSettings {
+	var settings RandomizedSVDSettings
+	if s != nil {
+		settings = *s
+	}
+	switch {
+	case settings.Oversampling == 0:
+		settings.Oversampling = 10
+	case settings.Oversampling < 0:
+		settings.Oversampling = 0
+	}
+	switch {
+	case settings.PowerIterations == 0:
+		settings.PowerIterations = 2
+	case settings.PowerIterations < 0:
+		settings.PowerIterations = 0
+	}
+	if settings.Source == nil {
+		settings.Source = rand.NewSource(1)
+	}
+	return settings
+}
+
+// RandomizedSVD computes an approximation of the k largest singular values (and
+// corresponding singular vectors) of the r x c sparse matrix a using the randomized
+// range finder with power iterations of Halko, Martinsson and Tropp (2011).  The
+// range of A is sampled by multiplying A with a random Gaussian test matrix, an
+// orthonormal basis Q for the sample is computed and the (small) matrix Q^T * A is
+// decomposed by dense SVD.  The truncated decomposition A ~= U * Sigma * V^T is
+// returned as for TruncatedSVD.  a is only accessed through multiplication by A and
+// A^T (see MulMatMat) so A^T is never formed explicitly.  settings may be nil in which
+// case the default settings are used.  RandomizedSVD panics if k is not in the range
+// [1, min(r, c)].
+//
+// RandomizedSVD performs a fixed amount of work (2 * (PowerIterations + 1) passes
+// over A) so is typically much faster than TruncatedSVD but less accurate unless
+// the singular values decay quickly.  If the context is cancelled, nil matrices are
+// returned along with the error from ctx.
+func RandomizedSVD(ctx context.Context, a BlasCompatibleSparser, k int, settings *RandomizedSVDSettings) (u *mat.Dense, sigma *mat.DiagDense, vt *mat.Dense, err error) {
+	r, c := a.Dims()
+	if k < 1 || k > min(r, c) {
+		panic("sparse: number of singular values must be in the range [1, min(r, c)]")
+	}
+	s := settings.defaults()
+	rnd := rand.New(s.Source)
+	l := min(k+s.Oversampling, min(r, c))
+
+	omega := mat.NewDense(c, l, nil)
+	raw := omega.RawMatrix()
+	for i := range raw.Data {
+		raw.Data[i] = rnd.NormFloat64()
+	}
+	q := orthonormalColumns(MulMatMat(false, 1, a, omega, nil), rnd)
+	for i := 0; i < s.PowerIterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, nil, err
+		}
+		z := orthonormalColumns(MulMatMat(true, 1, a, q, nil), rnd)
+		q = orthonormalColumns(MulMatMat(false, 1, a, z, nil), rnd)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// B = Q^T * A is computed as B^T = A^T * Q and then B^T = W * Sigma * Z^T so
+	// A ~= Q * B = (Q * Z) * Sigma * W^T
+	bt := MulMatMat(true, 1, a, q, nil)
+	var svd mat.SVD
+	if ok := svd.Factorize(bt, mat.SVDThin); !ok {
+		panic("sparse: singular value decomposition of projected matrix failed")
+	}
+	var wm, zm mat.Dense
+	svd.UTo(&wm)
+	svd.VTo(&zm)
+	values := svd.Values(nil)
+
+	u = mat.NewDense(r, k, nil)
+	u.Mul(q, zm.Slice(0, l, 0, k))
+	vt = mat.DenseCopyOf(wm.Slice(0, c, 0, k).T())
+	return u, mat.NewDiagDense(k, values[:k]), vt, nil
+}
+
+// orthonormalColumns returns a matrix whose columns are an orthonormal basis for the
+// columns of y (computed by Gram-Schmidt with reorthogonalisation).  If the columns
+// of y are linearly dependent, random vectors are used to complete the basis.
+func orthonormalColumns(y *mat.Dense, rnd *rand.Rand) *mat.Dense {
+	n, l := y.Dims()
+	basis := &krylovBasis{v: make([][]float64, l), rnd: rnd}
+	h := make([]float64, l)
+	for j := range basis.v {
+		basis.v[j] = mat.Col(nil, j, y)
+		norm := floats.Norm(basis.v[j], 2)
+		if basis.orthogonalize(basis.v[j], j, h[:j]) <= norm*epsilon*float64(n) {
+			basis.random(j)
+		}
+		floats.Scale(1/floats.Norm(basis.v[j], 2), basis.v[j])
+	}
+	q := mat.NewDense(n, l, nil)
+	for j, v := range basis.v {
+		q.SetCol(j, v)
+	}
+	return q
+}
@@ -0,0 +1,150 @@
+package sparse
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+func checkSVD(t *testing.T, desc string, a mat.Matrix, u *mat.Dense, sigma *mat.DiagDense, vt *mat.Dense, tol float64) {
+	r, c := a.Dims()
+	k := sigma.Diag()
+	if ur, uc := u.Dims(); ur != r || uc != k {
+		t.Fatalf("%s: expected U to be %d x %d but was %d x %d", desc, r, k, ur, uc)
+	}
+	if vr, vc := vt.Dims(); vr != k || vc != c {
+		t.Fatalf("%s: expected V^T to be %d x %d but was %d x %d", desc, k, c, vr, vc)
+	}
+	var utu, vvt mat.Dense
+	utu.Mul(u.T(), u)
+	vvt.Mul(vt, vt.T())
+	if !mat.EqualApprox(&utu, eye(k), 1e-8) {
+		t.Errorf("%s: expected orthonormal left singular vectors", desc)
+	}
+	if !mat.EqualApprox(&vvt, eye(k), 1e-8) {
+		t.Errorf("%s: expected orthonormal right singular vectors", desc)
+	}
+	for i := 0; i < k; i++ {
+		s := sigma.At(i, i)
+		if i > 0 && s > sigma.At(i-1, i-1) {
+			t.Errorf("%s: expected singular values in descending order but received %v", desc, mat.Formatted(sigma))
+		}
+		var av, atu mat.VecDense
+		av.MulVec(a, vt.RowView(i))
+		av.AddScaledVec(&av, -s, u.ColView(i))
+		atu.MulVec(a.T(), u.ColView(i))
+		atu.AddScaledVec(&atu, -s, vt.RowView(i))
+		if res := math.Max(mat.Norm(&av, 2), mat.Norm(&atu, 2)); res > tol*sigma.At(0, 0) {
+			t.Errorf("%s: singular triplet %d (%v) has residual %g", desc, i, s, res)
+		}
+	}
+}
+
+func denseSingularValues(a mat.Matrix, k int) []float64 {
+	var svd mat.SVD
+	if ok := svd.Factorize(a, mat.SVDNone); !ok {
+		panic("SVD failed")
+	}
+	return svd.Values(nil)[:k]
+}
+
+func TestTruncatedSVD(t *testing.T) {
+	tests := []struct {
+		r, c    int
+		density float64
+		k       int
+		csc     bool
+	}{
+		{r: 80, c: 50, density: 0.1, k: 5},
+		{r: 50, c: 80, density: 0.1, k: 5, csc: true},
+		{r: 200, c: 150, density: 0.03, k: 10},
+		{r: 30, c: 25, density: 0.3, k: 20, csc: true},
+		{r: 300, c: 100, density: 0.02, k: 1},
+	}
+
+	for ti, test := range tests {
+		dense := mat.NewDense(test.r, test.c, randomData(test.r, test.c, test.density))
+		var a BlasCompatibleSparser = CreateCSR(test.r, test.c, dense.RawMatrix().Data).(*CSR)
+		if test.csc {
+			a = a.(*CSR).ToCSC()
+		}
+		desc := fmt.Sprintf("Test %d (%d x %d, k=%d)", ti, test.r, test.c, test.k)
+
+		u, sigma, vt, err := TruncatedSVD(context.Background(), a, test.k, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", desc, err)
+		}
+		checkSVD(t, desc, dense, u, sigma, vt, 1e-8)
+		want := denseSingularValues(dense, test.k)
+		for i, s := range want {
+			if !floats.EqualWithinAbsOrRel(sigma.At(i, i), s, 1e-8, 1e-8) {
+				t.Errorf("%s: expected singular value %d to be %v but received %v", desc, i, s, sigma.At(i, i))
+			}
+		}
+	}
+}
+
+func TestTruncatedSVDNotConverged(t *testing.T) {
+	dense := mat.NewDense(100, 80, randomData(100, 80, 0.1))
+	a := CreateCSR(100, 80, dense.RawMatrix().Data).(*CSR)
+
+	u, sigma, vt, err := TruncatedSVD(context.Background(), a, 5, &SVDSettings{MaxIterations: 1, SubspaceSize: 8})
+	if _, ok := err.(*NotConvergedError); !ok {
+		t.Fatalf("expected *NotConvergedError but received %v", err)
+	}
+	if u == nil || sigma == nil || vt == nil {
+		t.Errorf("expected current approximations to be returned")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, _, err := TruncatedSVD(ctx, a, 5, nil); err != context.Canceled {
+		t.Errorf("expected context.Canceled but received %v", err)
+	}
+}
+
+func TestRandomizedSVD(t *testing.T) {
+	tests := []struct {
+		r, c    int
+		density float64
+		k       int
+		decay   float64
+		csc     bool
+	}{
+		{r: 200, c: 120, density: 0.05, k: 5, decay: 0.8},
+		{r: 120, c: 200, density: 0.05, k: 5, decay: 0.8, csc: true},
+		{r: 60, c: 40, density: 0.2, k: 40, decay: 1},
+	}
+
+	for ti, test := range tests {
+		// scale the columns so the singular values decay
+		data := randomData(test.r, test.c, test.density)
+		for i := 0; i < test.r; i++ {
+			for j := 0; j < test.c; j++ {
+				data[i*test.c+j] *= math.Pow(test.decay, float64(j))
+			}
+		}
+		dense := mat.NewDense(test.r, test.c, data)
+		var a BlasCompatibleSparser = CreateCSR(test.r, test.c, data).(*CSR)
+		if test.csc {
+			a = a.(*CSR).ToCSC()
+		}
+		desc := fmt.Sprintf("Test %d (%d x %d, k=%d)", ti, test.r, test.c, test.k)
+
+		u, sigma, vt, err := RandomizedSVD(context.Background(), a, test.k, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", desc, err)
+		}
+		checkSVD(t, desc, dense, u, sigma, vt, 1e-4)
+		want := denseSingularValues(dense, test.k)
+		for i, s := range want {
+			if !floats.EqualWithinRel(sigma.At(i, i), s, 1e-6) {
+				t.Errorf("%s: expected singular value %d to be %v but received %v", desc, i, s, sigma.At(i, i))
+			}
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package sparse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// SVMLightOptions holds the options for reading and writing files in SVMLight (also
+// known as LibSVM) format.  The zero value (or a nil *SVMLightOptions) uses the
+// default for each option.
+type SVMLightOptions struct {
+	// ZeroBased specifies that feature indices in the file start at 0 rather than 1
+	// (the default, as used by SVMLight and LibSVM).
+	ZeroBased bool
+
+	// Features is the number of features (columns of the matrix).  When reading, if
+	// Features is zero, the number of features is determined from the largest
+	// feature index in the file otherwise it is an error for a feature index to
+	// exceed the number of features.
+	Features int
+}
+
+// SVMLightRow is a single row (example) of an SVMLight format file.
+type SVMLightRow struct {
+	// Label is the target value (class label or regression target).
+	Label float64
+
+	// QID is the query ID (used for ranking problems) or 0 if the row has no qid
+	// field.
+	QID int
+
+	// Ind and Data are the (zero based) feature indices in ascending order and the
+	// corresponding feature values.
+	Ind  []int
+	Data []float64
+}
+
+// ScanSVMLight reads the SVMLight (LibSVM) format data from r a row at a time
+// calling fn for each row in turn so that files larger than memory may be processed.
+// Each line is of the form
+//   <label> [qid:<qid>] <index>:<value> <index>:<value> ... [# comment]
+// Blank lines and comments are ignored.  Feature indices are converted to zero based
+// indices (according to opts which may be nil) and sorted in ascending order.  The
+// Ind and Data slices of the row passed to fn are reused for subsequent rows so must
+// be copied if retained.  If fn returns an error, scanning stops and the error is
+// returned.
+func ScanSVMLight(r io.Reader, opts *SVMLightOptions, fn func(row SVMLightRow) error) error {
+	var o SVMLightOptions
+	if opts != nil {
+		o = *opts
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<30)
+	var row SVMLightRow
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := parseSVMLightRow(&row, fields, &o); err != nil {
+			return fmt.Errorf("sparse: SVMLight line %d: %v", line, err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseSVMLightRow parses the fields of a line into row (reusing its slices).
+func parseSVMLightRow(row *SVMLightRow, fields []string, opts *SVMLightOptions) error {
+	var err error
+	if row.Label, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return fmt.Errorf("invalid label %q", fields[0])
+	}
+	fields = fields[1:]
+	row.QID = 0
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "qid:") {
+		if row.QID, err = strconv.Atoi(fields[0][4:]); err != nil {
+			return fmt.Errorf("invalid qid %q", fields[0])
+		}
+		fields = fields[1:]
+	}
+
+	row.Ind, row.Data = row.Ind[:0], row.Data[:0]
+	sorted := true
+	for _, field := range fields {
+		sep := strings.IndexByte(field, ':')
+		if sep < 0 {
+			return fmt.Errorf("invalid feature %q", field)
+		}
+		index, err := strconv.Atoi(field[:sep])
+		if err != nil {
+			return fmt.Errorf("invalid feature index %q", field)
+		}
+		if !opts.ZeroBased {
+			index--
+		}
+		if index < 0 || (opts.Features > 0 && index >= opts.Features) {
+			return fmt.Errorf("feature index %q out of range", field)
+		}
+		v, err := strconv.ParseFloat(field[sep+1:], 64)
+		if err != nil {
+			return fmt.Errorf("invalid feature value %q", field)
+		}
+		if n := len(row.Ind); n > 0 && index <= row.Ind[n-1] {
+			sorted = false
+		}
+		row.Ind = append(row.Ind, index)
+		row.Data = append(row.Data, v)
+	}
+	if !sorted {
+		sortSparse(row.Ind, row.Data)
+		for k := 1; k < len(row.Ind); k++ {
+			if row.Ind[k] == row.Ind[k-1] {
+				return fmt.Errorf("duplicate feature index %d", row.Ind[k])
+			}
+		}
+	}
+	return nil
+}
+
+// ReadSVMLight reads SVMLight (LibSVM) format data from r returning the features as
+// a CSR matrix (with a row for each example) along with the labels and query IDs (or
+// nil if none of the rows have a qid field).  The number of columns of the matrix is
+// the number of features specified in opts (which may be nil) or, if not specified,
+// the largest feature index.  See ScanSVMLight for details of the format.
+func ReadSVMLight(r io.Reader, opts *SVMLightOptions) (x *CSR, labels []float64, qids []int, err error) {
+	indptr := []int{0}
+	var ind []int
+	var data []float64
+	hasQID := false
+	cols := 0
+	if opts != nil {
+		cols = opts.Features
+	}
+	err = ScanSVMLight(r, opts, func(row SVMLightRow) error {
+		ind = append(ind, row.Ind...)
+		data = append(data, row.Data...)
+		indptr = append(indptr, len(ind))
+		labels = append(labels, row.Label)
+		qids = append(qids, row.QID)
+		hasQID = hasQID || row.QID != 0
+		if n := len(row.Ind); n > 0 && row.Ind[n-1] >= cols {
+			cols = row.Ind[n-1] + 1
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !hasQID {
+		qids = nil
+	}
+	return NewCSR(len(labels), cols, indptr, ind, data), labels, qids, nil
+}
+
+// SVMLightWriter writes SVMLight (LibSVM) format data a row at a time.
+type SVMLightWriter struct {
+	w    *bufio.Writer
+	opts SVMLightOptions
+	line []byte
+}
+
+// NewSVMLightWriter creates a new SVMLightWriter writing to w with the specified
+// options (which may be nil).  Flush must be called after the last row has been
+// written.
+func NewSVMLightWriter(w io.Writer, opts *SVMLightOptions) *SVMLightWriter {
+	writer := &SVMLightWriter{w: bufio.NewWriter(w)}
+	if opts != nil {
+		writer.opts = *opts
+	}
+	return writer
+}
+
+// WriteRow writes the row (example) to the underlying writer.  The qid field is
+// written if the QID of the row is not 0.  The feature indices of the row must be
+// zero based and in ascending order.
+func (w *SVMLightWriter) WriteRow(row SVMLightRow) error {
+	if len(row.Ind) != len(row.Data) {
+		panic(mat.ErrShape)
+	}
+	offset := 1
+	if w.opts.ZeroBased {
+		offset = 0
+	}
+	line := strconv.AppendFloat(w.line[:0], row.Label, 'g', -1, 64)
+	if row.QID != 0 {
+		line = append(line, " qid:"...)
+		line = strconv.AppendInt(line, int64(row.QID), 10)
+	}
+	for k, index := range row.Ind {
+		line = append(line, ' ')
+		line = strconv.AppendInt(line, int64(index+offset), 10)
+		line = append(line, ':')
+		line = strconv.AppendFloat(line, row.Data[k], 'g', -1, 64)
+	}
+	line = append(line, '\n')
+	w.line = line
+	_, err := w.w.Write(line)
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *SVMLightWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// WriteSVMLight writes the rows of the feature matrix x along with the corresponding
+// labels and query IDs (which may be nil) to w in SVMLight (LibSVM) format with the
+// specified options (which may be nil).  Only the stored elements of x are written.
+// WriteSVMLight panics if the lengths of labels or qids (if not nil) do not match
+// the number of rows of x.
+func WriteSVMLight(w io.Writer, x *CSR, labels []float64, qids []int, opts *SVMLightOptions) error {
+	r, _ := x.Dims()
+	if len(labels) != r || (qids != nil && len(qids) != r) {
+		panic(mat.ErrShape)
+	}
+	writer := NewSVMLightWriter(w, opts)
+	m := x.RawMatrix()
+	ind := make([]int, 0)
+	data := make([]float64, 0)
+	for i := 0; i < r; i++ {
+		row := SVMLightRow{Label: labels[i]}
+		if qids != nil {
+			row.QID = qids[i]
+		}
+		row.Ind = append(ind[:0], m.Ind[m.Indptr[i]:m.Indptr[i+1]]...)
+		row.Data = append(data[:0], m.Data[m.Indptr[i]:m.Indptr[i+1]]...)
+		sortSparse(row.Ind, row.Data)
+		ind, data = row.Ind, row.Data
+		if err := writer.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
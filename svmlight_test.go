@@ -0,0 +1,205 @@
+package sparse
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestReadSVMLight(t *testing.T) {
+	tests := []struct {
+		desc   string
+		input  string
+		opts   *SVMLightOptions
+		r, c   int
+		data   []float64
+		labels []float64
+		qids   []int
+	}{
+		{
+			desc: "one based",
+			input: `# a comment
+1 1:0.5 3:2
+-1 2:1.5e2 4:-1 # trailing comment
+
++1 4:3 1:1
+0
+`,
+			r: 4, c: 4,
+			data: []float64{
+				0.5, 0, 2, 0,
+				0, 150, 0, -1,
+				1, 0, 0, 3,
+				0, 0, 0, 0,
+			},
+			labels: []float64{1, -1, 1, 0},
+		},
+		{
+			desc: "zero based with qid",
+			input: `3 qid:1 0:1 2:2
+2 qid:1 1:3
+1.5 qid:2 0:4
+`,
+			opts: &SVMLightOptions{ZeroBased: true},
+			r:    3, c: 3,
+			data: []float64{
+				1, 0, 2,
+				0, 3, 0,
+				4, 0, 0,
+			},
+			labels: []float64{3, 2, 1.5},
+			qids:   []int{1, 1, 2},
+		},
+		{
+			desc:  "features",
+			input: "1 1:1\n2 2:2\n",
+			opts:  &SVMLightOptions{Features: 5},
+			r:     2, c: 5,
+			data: []float64{
+				1, 0, 0, 0, 0,
+				0, 2, 0, 0, 0,
+			},
+			labels: []float64{1, 2},
+		},
+	}
+
+	for _, test := range tests {
+		x, labels, qids, err := ReadSVMLight(strings.NewReader(test.input), test.opts)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.desc, err)
+			continue
+		}
+		if r, c := x.Dims(); r != test.r || c != test.c {
+			t.Errorf("%s: expected %d x %d matrix but received %d x %d", test.desc, test.r, test.c, r, c)
+			continue
+		}
+		e := mat.NewDense(test.r, test.c, test.data)
+		if !mat.Equal(e, x) {
+			t.Errorf("%s: expected\n%v\nbut received\n%v\n", test.desc, mat.Formatted(e), mat.Formatted(x))
+		}
+		if !floats.Equal(labels, test.labels) {
+			t.Errorf("%s: expected labels %v but received %v", test.desc, test.labels, labels)
+		}
+		if len(qids) != len(test.qids) {
+			t.Errorf("%s: expected qids %v but received %v", test.desc, test.qids, qids)
+		}
+		for i := range qids {
+			if qids[i] != test.qids[i] {
+				t.Errorf("%s: expected qids %v but received %v", test.desc, test.qids, qids)
+				break
+			}
+		}
+	}
+}
+
+func TestReadSVMLightErrors(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+		opts  *SVMLightOptions
+	}{
+		{desc: "invalid label", input: "a 1:1\n"},
+		{desc: "invalid qid", input: "1 qid:x 1:1\n"},
+		{desc: "missing separator", input: "1 1\n"},
+		{desc: "invalid index", input: "1 a:1\n"},
+		{desc: "invalid value", input: "1 1:a\n"},
+		{desc: "zero index when one based", input: "1 0:1\n"},
+		{desc: "negative index", input: "1 -1:1\n", opts: &SVMLightOptions{ZeroBased: true}},
+		{desc: "index exceeds features", input: "1 4:1\n", opts: &SVMLightOptions{Features: 3}},
+		{desc: "duplicate index", input: "1 2:1 1:1 2:3\n"},
+	}
+	for _, test := range tests {
+		if _, _, _, err := ReadSVMLight(strings.NewReader(test.input), test.opts); err == nil {
+			t.Errorf("%s: expected error but received nil", test.desc)
+		}
+	}
+}
+
+func TestScanSVMLight(t *testing.T) {
+	input := "1 1:1\n2 2:2\n3 3:3\n4 4:4\n"
+	stop := errors.New("stop")
+
+	var rows []SVMLightRow
+	err := ScanSVMLight(strings.NewReader(input), nil, func(row SVMLightRow) error {
+		rows = append(rows, SVMLightRow{
+			Label: row.Label,
+			Ind:   append([]int(nil), row.Ind...),
+			Data:  append([]float64(nil), row.Data...),
+		})
+		if len(rows) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("expected error from callback but received %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected scanning to stop after 2 rows but received %d", len(rows))
+	}
+	for i, row := range rows {
+		if row.Label != float64(i+1) || len(row.Ind) != 1 || row.Ind[0] != i || row.Data[0] != float64(i+1) {
+			t.Errorf("row %d: unexpected row %+v", i, row)
+		}
+	}
+}
+
+func TestSVMLightRoundTrip(t *testing.T) {
+	r, c := 40, 25
+	data := randomData(r, c, 0.2)
+	data[0] = 1.0 / 3.0
+	x := CreateCSR(r, c, data).(*CSR)
+	labels := make([]float64, r)
+	qids := make([]int, r)
+	for i := range labels {
+		labels[i] = float64(i%3) - 1
+		qids[i] = i/10 + 1
+	}
+
+	tests := []struct {
+		desc string
+		qids []int
+		opts *SVMLightOptions
+	}{
+		{desc: "one based"},
+		{desc: "zero based", opts: &SVMLightOptions{ZeroBased: true, Features: c}},
+		{desc: "qid", qids: qids},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteSVMLight(&buf, x, labels, test.qids, test.opts); err != nil {
+			t.Fatalf("%s: unexpected error writing: %v", test.desc, err)
+		}
+		if strings.Contains(buf.String(), "qid:") != (test.qids != nil) {
+			t.Errorf("%s: unexpected qid fields in output", test.desc)
+		}
+		opts := test.opts
+		if opts == nil {
+			opts = &SVMLightOptions{Features: c}
+		}
+		read, readLabels, readQIDs, err := ReadSVMLight(&buf, opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error reading: %v", test.desc, err)
+		}
+		if !mat.Equal(x, read) {
+			t.Errorf("%s: expected\n%v\nbut received\n%v\n", test.desc, mat.Formatted(x), mat.Formatted(read))
+		}
+		if !floats.Equal(labels, readLabels) {
+			t.Errorf("%s: expected labels %v but received %v", test.desc, labels, readLabels)
+		}
+		if len(readQIDs) != len(test.qids) {
+			t.Errorf("%s: expected qids %v but received %v", test.desc, test.qids, readQIDs)
+		}
+		for i := range readQIDs {
+			if readQIDs[i] != test.qids[i] {
+				t.Errorf("%s: expected qids %v but received %v", test.desc, test.qids, readQIDs)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sparse
+
+import (
+	"github.com/james-bowman/sparse/blas"
+	"gonum.org/v1/gonum/mat"
+)
+
+// triangular returns the compressed representation of the triangular matrix t along
+// with whether the compressed representation is lower triangular and whether it must
+// be transposed to solve the system T * x = b (or T^T * x = b if trans is true).
+// CSC matrices are stored as their transpose so are solved using the opposite
+// triangle and transpose.  triangular panics with mat.ErrTriangle if t is not
+// triangular and returns a *SingularError if any diagonal element is zero.
+func triangular(t mat.Matrix, trans bool) (m *blas.SparseMatrix, lower bool, transT bool, err error) {
+	r, c := t.Dims()
+	if r != c {
+		panic(mat.ErrShape)
+	}
+	switch t := t.(type) {
+	case *CSR:
+		m, transT = &t.matrix, trans
+	case *CSC:
+		m, transT = &t.matrix, !trans
+	case TypeConverter:
+		m, transT = &t.ToCSR().matrix, trans
+	default:
+		panic("sparse: unsupported matrix type for triangular solve")
+	}
+
+	isLower, isUpper := true, true
+	diag := make([]bool, m.I)
+	for i := 0; i < m.I; i++ {
+		for k := m.Indptr[i]; k < m.Indptr[i+1]; k++ {
+			j := m.Ind[k]
+			switch {
+			case j < i:
+				isUpper = false
+			case j > i:
+				isLower = false
+			case m.Data[k] != 0:
+				diag[i] = true
+			}
+		}
+	}
+	if !isLower && !isUpper {
+		panic(mat.ErrTriangle)
+	}
+	for i, ok := range diag {
+		if !ok {
+			return m, isLower, transT, &SingularError{Index: i}
+		}
+	}
+	return m, isLower, transT, nil
+}
+
+// SolveTriVec solves the triangular system of linear equations T * x = b (or
+// T^T * x = b if trans is true) placing the result in dst.  t must be either upper
+// or lower triangular and is typically a CSR or CSC matrix (other sparse types are
+// converted to CSR).  If dst is empty it is resized to the correct length, otherwise
+// SolveTriVec panics if the length of dst is not the same as the size of t.
+// SolveTriVec panics with mat.ErrTriangle if t is not triangular.  If t is singular
+// (has a zero diagonal element), a *SingularError is returned.
+func SolveTriVec(dst *mat.VecDense, t mat.Matrix, trans bool, b mat.Vector) error {
+	n, _ := t.Dims()
+	if b.Len() != n {
+		panic(mat.ErrShape)
+	}
+	if dst.IsEmpty() {
+		dst.ReuseAsVec(n)
+	} else if dst.Len() != n {
+		panic(mat.ErrShape)
+	}
+	m, lower, transT, err := triangular(t, trans)
+	if err != nil {
+		return err
+	}
+
+	if dst != b {
+		dst.CopyVec(b)
+	}
+	raw := dst.RawVector()
+	blas.Dussv(transT, lower, 1, m, raw.Data, raw.Inc)
+	return nil
+}
+
+// SolveTri solves the triangular system of linear equations T * X = B (or
+// T^T * X = B if trans is true) placing the result in dst.  t must be either upper
+// or lower triangular and is typically a CSR or CSC matrix (other sparse types are
+// converted to CSR).  If dst is empty it is resized to the correct size, otherwise
+// SolveTri panics if dst is not the correct size.  SolveTri panics with
+// mat.ErrTriangle if t is not triangular.  If t is singular (has a zero diagonal
+// element), a *SingularError is returned.
+func SolveTri(dst *mat.Dense, t mat.Matrix, trans bool, b mat.Matrix) error {
+	n, _ := t.Dims()
+	rows, cols := b.Dims()
+	if rows != n {
+		panic(mat.ErrShape)
+	}
+	if dst.IsEmpty() {
+		dst.ReuseAs(n, cols)
+	} else if r, c := dst.Dims(); r != n || c != cols {
+		panic(mat.ErrShape)
+	}
+	m, lower, transT, err := triangular(t, trans)
+	if err != nil {
+		return err
+	}
+
+	if dst != b {
+		dst.Copy(b)
+	}
+	raw := dst.RawMatrix()
+	blas.Dussm(transT, lower, cols, 1, m, raw.Data, raw.Stride)
+	return nil
+}
@@ -0,0 +1,105 @@
+package sparse
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func randomTriangular(n int, density float64, kind mat.TriKind) []float64 {
+	data := randomData(n, n, density)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if (kind == mat.Upper && j < i) || (kind == mat.Lower && j > i) {
+				data[i*n+j] = 0
+			}
+		}
+		data[i*n+i] = 1 + float64(i%3)
+	}
+	return data
+}
+
+func TestSolveTri(t *testing.T) {
+	n := 30
+	for _, kind := range []mat.TriKind{mat.Upper, mat.Lower} {
+		data := randomTriangular(n, 0.2, kind)
+		tri := mat.NewTriDense(n, kind, data)
+
+		for _, trans := range []bool{false, true} {
+			var tt mat.Matrix = tri
+			if trans {
+				tt = tri.T()
+			}
+			b := mat.NewDense(n, 3, randomData(n, 3, 1))
+
+			for name, m := range map[string]mat.Matrix{
+				"CSR": CreateCSR(n, n, data),
+				"CSC": CreateCSC(n, n, data),
+				"COO": CreateCOO(n, n, data),
+			} {
+				desc := fmt.Sprintf("%s (upper=%t, trans=%t)", name, kind == mat.Upper, trans)
+
+				var want, got mat.VecDense
+				if err := want.SolveVec(tt, b.ColView(1)); err != nil {
+					t.Fatalf("%s: unexpected error from mat.VecDense.SolveVec %v", desc, err)
+				}
+				if err := SolveTriVec(&got, m, trans, b.ColView(1)); err != nil {
+					t.Errorf("%s: unexpected error %v", desc, err)
+				}
+				if !mat.EqualApprox(&want, &got, 1e-10) {
+					t.Errorf("%s: expected\n%v\nbut received\n%v", desc, mat.Formatted(want.T()), mat.Formatted(got.T()))
+				}
+
+				var wantM, gotM mat.Dense
+				if err := wantM.Solve(tt, b); err != nil {
+					t.Fatalf("%s: unexpected error from mat.Dense.Solve %v", desc, err)
+				}
+				if err := SolveTri(&gotM, m, trans, b); err != nil {
+					t.Errorf("%s: unexpected error %v", desc, err)
+				}
+				if !mat.EqualApprox(&wantM, &gotM, 1e-10) {
+					t.Errorf("%s: expected\n%v\nbut received\n%v", desc, mat.Formatted(&wantM), mat.Formatted(&gotM))
+				}
+
+				// solving in place
+				inPlace := mat.VecDenseCopyOf(b.ColView(1))
+				if err := SolveTriVec(inPlace, m, trans, inPlace); err != nil {
+					t.Errorf("%s: unexpected error %v", desc, err)
+				}
+				if !mat.EqualApprox(&want, inPlace, 1e-10) {
+					t.Errorf("%s: incorrect solution solving in place", desc)
+				}
+			}
+		}
+	}
+}
+
+func TestSolveTriErrors(t *testing.T) {
+	singular := CreateCSR(3, 3, []float64{
+		1, 0, 0,
+		2, 0, 0,
+		3, 4, 5,
+	})
+	var x mat.VecDense
+	err := SolveTriVec(&x, singular, false, mat.NewVecDense(3, []float64{1, 2, 3}))
+	if !errors.Is(err, mat.ErrSingular) {
+		t.Errorf("expected error matching mat.ErrSingular but received %v", err)
+	}
+	if e, ok := err.(*SingularError); !ok || e.Index != 1 {
+		t.Errorf("expected singular error at index 1 but received %v", err)
+	}
+
+	full := CreateCSR(2, 2, []float64{
+		1, 2,
+		3, 4,
+	})
+	defer func() {
+		if r := recover(); r != mat.ErrTriangle {
+			t.Errorf("expected panic with mat.ErrTriangle but received %v", r)
+		}
+	}()
+	var y mat.VecDense
+	SolveTriVec(&y, full, false, mat.NewVecDense(2, []float64{1, 2}))
+}